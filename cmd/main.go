@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/markraiter/simple-blog/config"
@@ -13,6 +15,10 @@ import (
 	"github.com/markraiter/simple-blog/internal/app/api/middleware"
 	"github.com/markraiter/simple-blog/internal/app/service"
 	"github.com/markraiter/simple-blog/internal/app/storage/postgres"
+	"github.com/markraiter/simple-blog/internal/lib/hasher"
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
+	"github.com/markraiter/simple-blog/internal/lib/oidc"
 	"github.com/markraiter/simple-blog/internal/model"
 )
 
@@ -40,16 +46,71 @@ func main() {
 	validate.RegisterValidation("upper", model.ValidateContainsUpper, false)     // nolint:errcheck
 	validate.RegisterValidation("lower", model.ValidateContainsLower, false)     // nolint:errcheck
 	validate.RegisterValidation("special", model.ValidateContainsSpecial, false) // nolint:errcheck
+	validate.RegisterValidation("weblink", model.ValidateWebLink, false)         // nolint:errcheck
+
+	if !model.DeletionPolicy(cfg.Account.DeletionPolicy).Valid() {
+		panic("unknown account deletion policy: " + cfg.Account.DeletionPolicy)
+	}
+
+	if cfg.Scheduler.PublishInterval <= 0 {
+		panic("publish interval must be positive: " + cfg.Scheduler.PublishInterval.String())
+	}
+
+	if cfg.Scheduler.CleanupInterval <= 0 {
+		panic("cleanup interval must be positive: " + cfg.Scheduler.CleanupInterval.String())
+	}
 
 	log.Info("starting application...")
 	log.Info("port: " + cfg.Server.Port)
 
 	db := postgres.New(cfg.Postgres)
 
+	keys, err := jwt.NewKeySet(cfg.Auth)
+	if err != nil {
+		panic("error loading signing keys: " + err.Error())
+	}
+
+	passwordHasher, err := hasher.New(cfg.Auth)
+	if err != nil {
+		panic("error creating password hasher: " + err.Error())
+	}
+
+	revocations := service.NewRevocationService(db, cfg.Auth.RevocationCacheTTL)
+
+	mail, err := mailer.New(cfg.Mailer, log)
+	if err != nil {
+		panic("error creating mailer: " + err.Error())
+	}
+
+	loginGuard := service.NewLoginGuard(cfg.Lockout, log)
+
+	scheduler := service.NewScheduler(db, cfg.Scheduler, log)
+
+	cleaner := service.NewCleaner(db, cfg.Scheduler, log)
+
+	var oidcClient service.OIDCClient
+	if cfg.OIDC.Issuer != "" {
+		client, err := oidc.New(ctx, cfg.OIDC, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			panic("error creating oidc client: " + err.Error())
+		}
+
+		oidcClient = client
+	}
+
 	service := service.New(
 		db,
 		db,
 		db,
+		db,
+		db,
+		revocations,
+		mail,
+		loginGuard,
+		keys,
+		oidcClient,
+		passwordHasher,
+		log,
 	)
 
 	handler := handler.New(
@@ -58,11 +119,13 @@ func main() {
 		&service.AuthService,
 		&service.PostService,
 		&service.CommentService,
+		&service.UserService,
+		&service.SearchService,
 	)
 
 	server := api.New(log)
 
-	router := handler.Router(ctx, *cfg, log)
+	router := handler.Router(ctx, *cfg, log, keys, revocations)
 
 	handlerWithMiddlewareLogger := middleware.LoggerMiddleware(log)(router)
 
@@ -72,6 +135,23 @@ func main() {
 		}
 	}()
 
+	schedulerCtx, stopScheduler := context.WithCancel(ctx)
+	schedulerDone := make(chan struct{})
+
+	go func() {
+		defer close(schedulerDone)
+
+		scheduler.Run(schedulerCtx)
+	}()
+
+	cleanerDone := make(chan struct{})
+
+	go func() {
+		defer close(cleanerDone)
+
+		cleaner.Run(schedulerCtx)
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 
@@ -79,6 +159,10 @@ func main() {
 
 	log.Info("shutting down application...")
 
+	stopScheduler()
+	<-schedulerDone
+	<-cleanerDone
+
 	if err := server.Shutdown(ctx); err != nil {
 		log.Error("error occured while shutting down the server: " + err.Error())
 	}
@@ -12,6 +12,11 @@ type Config struct {
 	Server
 	Postgres
 	Auth
+	Lockout
+	Mailer
+	OIDC
+	Account
+	Scheduler
 }
 
 type Postgres struct {
@@ -32,9 +37,73 @@ type Server struct {
 }
 
 type Auth struct {
-	SigningKey string        `env:"SIGNING_KEY" env-required:"true"`
-	AccessTTL  time.Duration `env:"ACCESS_TTL" env-default:"1h"`
-	RefreshTTL time.Duration `env:"REFRESH_TTL" env-default:"720h"`
+	SigningKey         string        `env:"SIGNING_KEY"`
+	SigningKeys        []string      `env:"SIGNING_KEYS" env-separator:","`
+	SigningKeyID       string        `env:"SIGNING_KEY_ID"`
+	AccessTTL          time.Duration `env:"ACCESS_TTL" env-default:"1h"`
+	RefreshTTL         time.Duration `env:"REFRESH_TTL" env-default:"720h"`
+	RevocationCacheTTL time.Duration `env:"REVOCATION_CACHE_TTL" env-default:"30s"`
+	PasswordResetTTL   time.Duration `env:"PASSWORD_RESET_TTL" env-default:"1h"`
+	EmailVerifyTTL     time.Duration `env:"EMAIL_VERIFY_TTL" env-default:"48h"`
+	BaseURL            string        `env:"BASE_URL" env-default:"http://localhost:8080"`
+	TwoFactorIssuer    string        `env:"TWO_FACTOR_ISSUER" env-default:"simple-blog"`
+	TwoFactorTTL       time.Duration `env:"TWO_FACTOR_TTL" env-default:"5m"`
+	OIDCStateTTL       time.Duration `env:"OIDC_STATE_TTL" env-default:"10m"`
+	PasswordHasher     string        `env:"PASSWORD_HASHER" env-default:"argon2id"`
+	BcryptCost         int           `env:"BCRYPT_COST" env-default:"10"`
+	Argon2Time         uint32        `env:"ARGON2_TIME" env-default:"2"`
+	Argon2Memory       uint32        `env:"ARGON2_MEMORY" env-default:"19456"`
+	Argon2Threads      uint8         `env:"ARGON2_THREADS" env-default:"1"`
+}
+
+// Lockout configures the protection of login against password guessing.
+//
+// After Max*Attempts failures the login is locked for BaseDelay, every
+// further failure doubles the delay up to MaxDelay. Failures are forgotten
+// after Window without new ones.
+type Lockout struct {
+	MaxAccountAttempts int           `env:"LOCKOUT_MAX_ACCOUNT_ATTEMPTS" env-default:"5"`
+	MaxIPAttempts      int           `env:"LOCKOUT_MAX_IP_ATTEMPTS" env-default:"20"`
+	BaseDelay          time.Duration `env:"LOCKOUT_BASE_DELAY" env-default:"30s"`
+	MaxDelay           time.Duration `env:"LOCKOUT_MAX_DELAY" env-default:"1h"`
+	Window             time.Duration `env:"LOCKOUT_WINDOW" env-default:"1h"`
+}
+
+type Mailer struct {
+	Driver   string `env:"MAILER_DRIVER" env-default:"log"`
+	From     string `env:"MAILER_FROM" env-default:"no-reply@localhost"`
+	Dir      string `env:"MAILER_DIR"`
+	Host     string `env:"SMTP_HOST" env-default:"localhost"`
+	Port     string `env:"SMTP_PORT" env-default:"587"`
+	Username string `env:"SMTP_USERNAME"`
+	Password string `env:"SMTP_PASSWORD"`
+}
+
+// OIDC configures login through an external OpenID Connect provider.
+//
+// The login is disabled while Issuer is empty.
+type OIDC struct {
+	Issuer       string   `env:"OIDC_ISSUER"`
+	ClientID     string   `env:"OIDC_CLIENT_ID"`
+	ClientSecret string   `env:"OIDC_CLIENT_SECRET"`
+	RedirectURL  string   `env:"OIDC_REDIRECT_URL" env-default:"http://localhost:8080/api/auth/oidc/callback"`
+	Scopes       []string `env:"OIDC_SCOPES" env-separator:"," env-default:"openid,email,profile"`
+}
+
+// Account configures what happens to the content of deleted accounts.
+//
+// With DeletionPolicy "anonymize" posts and comments are kept and shown
+// as written by a deleted user, with "delete" they are removed together
+// with the account.
+type Account struct {
+	DeletionPolicy string `env:"ACCOUNT_DELETION_POLICY" env-default:"anonymize"`
+}
+
+// Scheduler configures the background jobs: how often scheduled posts
+// that are due are published and how often expired tokens are removed.
+type Scheduler struct {
+	PublishInterval time.Duration `env:"PUBLISH_INTERVAL" env-default:"1m"`
+	CleanupInterval time.Duration `env:"CLEANUP_INTERVAL" env-default:"1h"`
 }
 
 func MustLoad() *Config {
@@ -5,20 +5,49 @@ import (
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/markraiter/simple-blog/config"
 	_ "github.com/markraiter/simple-blog/docs"
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
 	"github.com/markraiter/simple-blog/internal/app/service"
 	"github.com/markraiter/simple-blog/internal/lib/sl"
 	"github.com/markraiter/simple-blog/internal/model"
 )
 
 type Auth interface {
-	RegisterUser(ctx context.Context, user *model.UserRequest) (int, error)
-	Login(ctx context.Context, cfg config.Auth, email, password string) (string, error)
+	RegisterUser(ctx context.Context, cfg config.Auth, user *model.UserRequest) (int, error)
+	Login(ctx context.Context, cfg config.Auth, email, password string, client model.ClientInfo) (*model.LoginResponse, error)
+	LoginTwoFactor(ctx context.Context, cfg config.Auth, challengeToken, code string, client model.ClientInfo) (*model.TokenPair, error)
+	Refresh(ctx context.Context, cfg config.Auth, refreshToken string, client model.ClientInfo) (*model.TokenPair, error)
+	Logout(ctx context.Context, userID int, sessionID, jti string, expiresAt time.Time, refreshToken string) error
+	LogoutAll(ctx context.Context, userID int) error
+	SetUserRole(ctx context.Context, userID int, role model.Role) error
+	ForgotPassword(ctx context.Context, cfg config.Auth, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, cfg config.Auth, userID int) error
+	EnrollTwoFactor(ctx context.Context, cfg config.Auth, userID int) (*model.TwoFactorEnrollment, error)
+	ConfirmTwoFactor(ctx context.Context, userID int, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, userID int, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error)
+	CreatePersonalToken(ctx context.Context, userID int, req *model.PersonalTokenRequest) (*model.PersonalTokenCreated, error)
+	PersonalTokens(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error)
+	RevokePersonalToken(ctx context.Context, userID, id int) error
+	AuthenticatePersonalToken(ctx context.Context, token string) (*model.User, *model.PersonalAccessToken, error)
+	StartOIDCLogin(cfg config.Auth) (string, string, error)
+	FinishOIDCLogin(ctx context.Context, cfg config.Auth, stateToken, state, code string, client model.ClientInfo) (*model.LoginResponse, error)
+	Sessions(ctx context.Context, userID int, currentSessionID string) ([]*model.Session, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	ChangePassword(ctx context.Context, userID int, sessionID, ip string, req *model.ChangePasswordRequest) error
+	ChangeEmail(ctx context.Context, cfg config.Auth, userID int, ip string, req *model.ChangeEmailRequest) error
+	ConfirmEmailChange(ctx context.Context, userID int, sessionID, token string) error
+	DeleteAccount(ctx context.Context, policy model.DeletionPolicy, userID int, ip, password string) error
 }
 
 type AuthHandler struct {
@@ -28,7 +57,7 @@ type AuthHandler struct {
 }
 
 // @Summary Register user
-// @Description Register user
+// @Description Register user. A link to verify the email address is sent to the user, posts and comments can be published only after that.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -37,7 +66,7 @@ type AuthHandler struct {
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/auth/register [post]
-func (ah *AuthHandler) RegisterUser(ctx context.Context) http.HandlerFunc {
+func (ah *AuthHandler) RegisterUser(ctx context.Context, cfg config.Auth) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		const operation = "handler.RegisterUser"
@@ -60,7 +89,7 @@ func (ah *AuthHandler) RegisterUser(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		id, err := ah.service.RegisterUser(ctx, &userReq)
+		id, err := ah.service.RegisterUser(ctx, cfg, &userReq)
 		if err != nil {
 			if errors.Is(err, service.ErrAlreadyExists) {
 				log.Warn("user already exists", sl.Err(err))
@@ -81,13 +110,14 @@ func (ah *AuthHandler) RegisterUser(ctx context.Context) http.HandlerFunc {
 }
 
 // @Summary Login
-// @Description Login
+// @Description Login. If two-factor authentication is enabled, a challenge token is returned instead of the token pair, finish the login at /api/auth/login/2fa.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body model.LoginRequest true "User data"
-// @Success 200 {string} string "Token"
-// @Failure 400 {string} string "Bad request"
+// @Success 200 {object} model.LoginResponse
+// @Failure 400 {string} string "Bad request or invalid credentials"
+// @Failure 429 {string} string "Too many failed attempts, see Retry-After"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/auth/login [post]
 func (ah *AuthHandler) Login(ctx context.Context, cfg config.Auth) http.HandlerFunc {
@@ -112,11 +142,12 @@ func (ah *AuthHandler) Login(ctx context.Context, cfg config.Auth) http.HandlerF
 			return
 		}
 
-		token, err := ah.service.Login(ctx, cfg, userReq.Email, userReq.Password)
+		tokens, err := ah.service.Login(ctx, cfg, userReq.Email, userReq.Password, clientInfo(r))
 		if err != nil {
-			if errors.Is(err, service.ErrNotFound) {
-				log.Warn("user not found", sl.Err(err))
-				http.Error(w, err.Error(), http.StatusBadRequest)
+			var locked *service.LockedError
+			if errors.As(err, &locked) {
+				log.Warn("login attempt while locked", sl.Err(err))
+				tooManyAttempts(w, locked)
 
 				return
 			}
@@ -134,7 +165,426 @@ func (ah *AuthHandler) Login(ctx context.Context, cfg config.Auth) http.HandlerF
 			return
 		}
 
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tokens) //nolint:errcheck
+	}
+}
+
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new token pair. The refresh token is rotated and can be used only once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body model.RefreshRequest true "Refresh token"
+// @Success 200 {object} model.TokenPair
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Invalid, expired or reused refresh token"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/refresh [post]
+func (ah *AuthHandler) Refresh(ctx context.Context, cfg config.Auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.Refresh"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var refreshReq model.RefreshRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&refreshReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(refreshReq); err != nil {
+			log.Warn("error validating refresh request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		tokens, err := ah.service.Refresh(ctx, cfg, refreshReq.RefreshToken, clientInfo(r))
+		if err != nil {
+			if errors.Is(err, service.ErrTokenReused) {
+				log.Warn("refresh token reuse detected, token family revoked", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrInvalidToken) {
+				log.Warn("invalid refresh token", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+
+				return
+			}
+
+			log.Error("error refreshing tokens", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tokens) //nolint:errcheck
+	}
+}
+
+// @Summary Logout
+// @Description End the session of the request, its access and refresh tokens are revoked. For tokens issued without a session the access token is revoked, and the refresh token if it is provided.
+// @Security ApiKeyAuth
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body model.LogoutRequest false "Refresh token"
+// @Success 200 {string} string "Logged out"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/logout [post]
+func (ah *AuthHandler) Logout(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.Logout"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		sessionID := middleware.GetSessionIDFromCtx(r.Context())
+		jti := middleware.GetTokenIDFromCtx(r.Context())
+		exp := middleware.GetTokenExpFromCtx(r.Context())
+
+		var logoutReq model.LogoutRequest
+
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&logoutReq); err != nil {
+				log.Warn("error parsing request", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+		}
+
+		if err := ah.service.Logout(ctx, userID, sessionID, jti, exp, logoutReq.RefreshToken); err != nil {
+			log.Error("error logging out", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(token)
+		w.Write([]byte("Logged out")) //nolint:errcheck
 	}
 }
+
+// @Summary Logout from all devices
+// @Description Revoke every access and refresh token issued to the user
+// @Security ApiKeyAuth
+// @Tags auth
+// @Produce json
+// @Success 200 {string} string "Logged out from all devices"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/logout-all [post]
+func (ah *AuthHandler) LogoutAll(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.LogoutAll"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		if err := ah.service.LogoutAll(ctx, userID); err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error logging out from all devices", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Logged out from all devices")) //nolint:errcheck
+	}
+}
+
+// @Summary Set user role
+// @Description Change the role of the user. Available to admins only. All tokens of the user are revoked.
+// @Security ApiKeyAuth
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body model.RoleRequest true "New role"
+// @Success 200 {string} string "Role updated"
+// @Failure 400 {string} string "Bad request"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/admin/users/{id}/role [put]
+func (ah *AuthHandler) SetUserRole(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.SetUserRole"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		userID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		var roleReq model.RoleRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&roleReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(roleReq); err != nil {
+			log.Warn("error validating role", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.service.SetUserRole(ctx, userID, roleReq.Role); err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error setting user role", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		log.Info("user role changed",
+			slog.Int("uid", userID),
+			slog.String("role", string(roleReq.Role)),
+			slog.Int("by", middleware.GetUserIDFromCtx(r.Context())),
+		)
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Role updated")) //nolint:errcheck
+	}
+}
+
+// @Summary Forgot password
+// @Description Send a password reset link to the email. The response is the same whether the account exists or not.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body model.ForgotPasswordRequest true "Account email"
+// @Success 200 {string} string "Reset link sent"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/password/forgot [post]
+func (ah *AuthHandler) ForgotPassword(ctx context.Context, cfg config.Auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.ForgotPassword"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var forgotReq model.ForgotPasswordRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&forgotReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(forgotReq); err != nil {
+			log.Warn("error validating request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.service.ForgotPassword(ctx, cfg, forgotReq.Email); err != nil {
+			log.Error("error sending password reset link", sl.Err(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("If the account exists, a reset link has been sent")) //nolint:errcheck
+	}
+}
+
+// @Summary Reset password
+// @Description Set a new password using the token from the reset link. All sessions of the user are revoked.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body model.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {string} string "Password updated"
+// @Failure 400 {string} string "Bad request, invalid or expired token"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/password/reset [post]
+func (ah *AuthHandler) ResetPassword(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.ResetPassword"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var resetReq model.ResetPasswordRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&resetReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(resetReq); err != nil {
+			log.Warn("error validating request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.service.ResetPassword(ctx, resetReq.Token, resetReq.Password); err != nil {
+			if errors.Is(err, service.ErrInvalidToken) {
+				log.Warn("invalid password reset token", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			log.Error("error resetting password", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Password updated")) //nolint:errcheck
+	}
+}
+
+// @Summary Verify email
+// @Description Activate the account using the token from the verification link
+// @Tags auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {string} string "Email verified"
+// @Failure 400 {string} string "Invalid or expired token"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/verify [get]
+func (ah *AuthHandler) VerifyEmail(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.VerifyEmail"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			log.Warn("error getting token from query")
+			http.Error(w, "error getting token from query", http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.service.VerifyEmail(ctx, token); err != nil {
+			if errors.Is(err, service.ErrInvalidToken) {
+				log.Warn("invalid verification token", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			log.Error("error verifying email", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Email verified")) //nolint:errcheck
+	}
+}
+
+// @Summary Resend verification email
+// @Description Send a new verification link. Links sent earlier stop working.
+// @Security ApiKeyAuth
+// @Tags auth
+// @Produce json
+// @Success 200 {string} string "Verification link sent"
+// @Failure 400 {string} string "Email is already verified"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/verify/resend [post]
+func (ah *AuthHandler) ResendVerification(ctx context.Context, cfg config.Auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.ResendVerification"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		if err := ah.service.ResendVerification(ctx, cfg, userID); err != nil {
+			if errors.Is(err, service.ErrEmailAlreadyVerified) {
+				log.Warn("email is already verified", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error sending verification link", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Verification link sent")) //nolint:errcheck
+	}
+}
+
+// clientIP returns the IP address the request came from.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// clientInfo describes the client the request came from.
+func clientInfo(r *http.Request) model.ClientInfo {
+	return model.ClientInfo{IP: clientIP(r), UserAgent: r.UserAgent()}
+}
+
+// tooManyAttempts responds with 429 and tells the client when to retry.
+func tooManyAttempts(w http.ResponseWriter, locked *service.LockedError) {
+	seconds := int(math.Ceil(locked.RetryAfter.Seconds()))
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, locked.Error(), http.StatusTooManyRequests)
+}
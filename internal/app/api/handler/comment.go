@@ -19,9 +19,16 @@ type CommentSaver interface {
 	SaveComment(ctx context.Context, userID int, commentReq *model.CommentRequest) (int, error)
 }
 
-type CommentProvider interface{}
+type CommentProvider interface {
+	Comment(ctx context.Context, id, viewerID int) (*model.Comment, error)
+	CommentsByPost(ctx context.Context, postID, viewerID int) ([]*model.Comment, error)
+	CommentTree(ctx context.Context, postID, viewerID int) ([]*model.Comment, error)
+}
 
-type CommentProcessor interface{}
+type CommentProcessor interface {
+	UpdateComment(ctx context.Context, commentID, version, userID int, role model.Role, commentReq *model.CommentUpdateRequest) error
+	DeleteComment(ctx context.Context, commentID, version, userID int, role model.Role) error
+}
 
 type CommentHandler struct {
 	log       *slog.Logger
@@ -32,14 +39,15 @@ type CommentHandler struct {
 }
 
 // @Summary Create a comment
-// @Description Create a comment
+// @Description Create a comment or, with parent_id, a reply to a comment of the same post
 // @Security ApiKeyAuth
 // @Tags comments
 // @Accept json
 // @Produce json
 // @Param comment body model.CommentRequest true "Comment object that needs to be created"
 // @Success 201 {string} string "Comment created"
-// @Failure 400 {string} string "Invalid request"
+// @Failure 400 {string} string "Invalid request, invalid parent or too deep thread"
+// @Failure 403 {string} string "Email is not verified"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/comments [post]
 func (h *CommentHandler) CreateComment(ctx context.Context) http.HandlerFunc {
@@ -73,6 +81,20 @@ func (h *CommentHandler) CreateComment(ctx context.Context) http.HandlerFunc {
 				return
 			}
 
+			if errors.Is(err, service.ErrInvalidParent) || errors.Is(err, service.ErrThreadTooDeep) {
+				log.Warn("invalid reply", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrEmailNotVerified) {
+				log.Warn("email is not verified", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusForbidden)
+
+				return
+			}
+
 			log.Error("error saving comment", sl.Err(err))
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
@@ -84,3 +106,281 @@ func (h *CommentHandler) CreateComment(ctx context.Context) http.HandlerFunc {
 		w.Write([]byte(strconv.Itoa(id))) //nolint:errcheck
 	}
 }
+
+// @Summary Get comments of a post
+// @Description Get the comments of a post, newest first. With view=tree the replies are nested into their parents.
+// @Description The comments of a post that is not published are only shown to its author.
+// @Tags comments
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param view query string false "Flat list or nested threads" Enums(flat, tree) default(flat)
+// @Success 200 {array} model.Comment
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Post not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/posts/{id}/comments [get]
+func (h *CommentHandler) CommentsByPost(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.CommentsByPost"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		postID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		var comments []*model.Comment
+
+		viewerID := middleware.GetUserIDFromCtx(r.Context())
+
+		switch r.URL.Query().Get("view") {
+		case "", "flat":
+			comments, err = h.provider.CommentsByPost(ctx, postID, viewerID)
+		case "tree":
+			comments, err = h.provider.CommentTree(ctx, postID, viewerID)
+		default:
+			log.Warn("unknown comments view")
+			http.Error(w, "view must be flat or tree", http.StatusBadRequest)
+
+			return
+		}
+
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("post not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error getting comments", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(comments) //nolint:errcheck
+	}
+}
+
+// @Summary Get a comment
+// @Description Get a comment. The comments of a post that is not published are only shown to its author.
+// @Tags comments
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Success 200 {object} model.Comment
+// @Header 200 {string} ETag "Version of the comment"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Comment not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/comments/{id} [get]
+func (h *CommentHandler) Comment(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.Comment"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		commentID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		comment, err := h.provider.Comment(ctx, commentID, middleware.GetUserIDFromCtx(r.Context()))
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("comment not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error getting comment", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag(comment.Version))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(comment) //nolint:errcheck
+	}
+}
+
+// @Summary Update a comment
+// @Description Update the content of a comment. Users may update only their own comments, moderators may update any comment.
+// @Description The If-Match header must hold the ETag the comment was read with.
+// @Security ApiKeyAuth
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param If-Match header string true "ETag of the comment"
+// @Param comment body model.CommentUpdateRequest true "Comment object that needs to be updated"
+// @Success 200 {string} string "Comment updated"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "User is neither the owner of the comment nor a moderator"
+// @Failure 404 {string} string "Comment not found"
+// @Failure 412 {string} string "Comment has been modified since it was read"
+// @Failure 428 {string} string "If-Match header is missing"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/comments/{id} [put]
+func (h *CommentHandler) UpdateComment(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.UpdateComment"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		role := middleware.GetRoleFromCtx(r.Context())
+
+		commentID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			log.Warn("error checking If-Match", sl.Err(err))
+			http.Error(w, err.Error(), preconditionStatus(err))
+
+			return
+		}
+
+		var commentReq model.CommentUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&commentReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := h.validate.Struct(commentReq); err != nil {
+			log.Warn("error validating comment", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		err = h.processor.UpdateComment(ctx, commentID, version, userID, role, &commentReq)
+		if err != nil {
+			if errors.Is(err, service.ErrConflict) {
+				log.Warn("comment has been modified", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusPreconditionFailed)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotAllowed) {
+				log.Warn("user is not allowed to perform this operation", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusForbidden)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("comment not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error updating comment", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Comment updated")) //nolint:errcheck
+	}
+}
+
+// @Summary Delete a comment
+// @Description Delete a comment. Users may delete only their own comments, moderators may delete any comment.
+// @Description The If-Match header must hold the ETag the comment was read with.
+// @Security ApiKeyAuth
+// @Tags comments
+// @Produce json
+// @Param id path int true "Comment ID"
+// @Param If-Match header string true "ETag of the comment"
+// @Success 200 {string} string "Comment deleted"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "User is neither the owner of the comment nor a moderator"
+// @Failure 404 {string} string "Comment not found"
+// @Failure 412 {string} string "Comment has been modified since it was read"
+// @Failure 428 {string} string "If-Match header is missing"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/comments/{id} [delete]
+func (h *CommentHandler) DeleteComment(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.DeleteComment"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		role := middleware.GetRoleFromCtx(r.Context())
+
+		commentID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			log.Warn("error checking If-Match", sl.Err(err))
+			http.Error(w, err.Error(), preconditionStatus(err))
+
+			return
+		}
+
+		err = h.processor.DeleteComment(ctx, commentID, version, userID, role)
+		if err != nil {
+			if errors.Is(err, service.ErrConflict) {
+				log.Warn("comment has been modified", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusPreconditionFailed)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotAllowed) {
+				log.Warn("user is not allowed to perform this operation", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusForbidden)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("comment not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error deleting comment", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Comment deleted")) //nolint:errcheck
+	}
+}
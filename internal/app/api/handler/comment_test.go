@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/go-playground/validator"
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
 	"github.com/markraiter/simple-blog/internal/app/service"
 	"github.com/markraiter/simple-blog/internal/model"
 	"github.com/stretchr/testify/assert"
@@ -23,6 +24,47 @@ func (m *MockCommentSaver) SaveComment(ctx context.Context, userID int, commentR
 	return args.Int(0), args.Error(1)
 }
 
+type MockCommentProvider struct{ mock.Mock }
+
+func (m *MockCommentProvider) Comment(ctx context.Context, id, viewerID int) (*model.Comment, error) {
+	args := m.Called(ctx, id, viewerID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*model.Comment), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockCommentProvider) CommentsByPost(ctx context.Context, postID, viewerID int) ([]*model.Comment, error) {
+	args := m.Called(ctx, postID, viewerID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*model.Comment), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockCommentProvider) CommentTree(ctx context.Context, postID, viewerID int) ([]*model.Comment, error) {
+	args := m.Called(ctx, postID, viewerID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*model.Comment), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+type MockCommentProcessor struct{ mock.Mock }
+
+func (m *MockCommentProcessor) UpdateComment(ctx context.Context, commentID, version, userID int, role model.Role, commentReq *model.CommentUpdateRequest) error {
+	args := m.Called(ctx, commentID, version, userID, role, commentReq)
+	return args.Error(0)
+}
+
+func (m *MockCommentProcessor) DeleteComment(ctx context.Context, commentID, version, userID int, role model.Role) error {
+	args := m.Called(ctx, commentID, version, userID, role)
+	return args.Error(0)
+}
+
 // Tests
 func TestCommentHandler_CreateComment(t *testing.T) {
 	mockSaver := new(MockCommentSaver)
@@ -120,3 +162,379 @@ func TestCommentHandler_CreateComment(t *testing.T) {
 		})
 	}
 }
+
+func TestCommentHandler_CommentsByPost(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		authenticated  bool
+		mock           func(mockProvider *MockCommentProvider)
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			id:   "1",
+			mock: func(mockProvider *MockCommentProvider) {
+				mockProvider.On("CommentsByPost", mock.Anything, 1, 0).Return([]*model.Comment{{ID: 1, PostID: 1}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid ID",
+			id:             "abc",
+			mock:           func(*MockCommentProvider) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Post not found",
+			id:   "2",
+			mock: func(mockProvider *MockCommentProvider) {
+				mockProvider.On("CommentsByPost", mock.Anything, 2, 0).Return(nil, service.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "Internal server error",
+			id:   "3",
+			mock: func(mockProvider *MockCommentProvider) {
+				mockProvider.On("CommentsByPost", mock.Anything, 3, 0).Return(nil, assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:          "Draft post of another user",
+			id:            "4",
+			authenticated: true,
+			mock: func(mockProvider *MockCommentProvider) {
+				mockProvider.On("CommentsByPost", mock.Anything, 4, 1).Return(nil, service.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockCommentProvider)
+			h := &CommentHandler{log: log, validate: validator.New(), provider: mockProvider}
+
+			tt.mock(mockProvider)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/posts/"+tt.id+"/comments", nil)
+			if tt.authenticated {
+				req = withUser(req, model.RoleAuthor)
+			}
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			h.CommentsByPost(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCommentHandler_Comment(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		authenticated  bool
+		mock           func(mockProvider *MockCommentProvider)
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			id:   "1",
+			mock: func(mockProvider *MockCommentProvider) {
+				mockProvider.On("Comment", mock.Anything, 1, 0).Return(&model.Comment{ID: 1, Content: "content", Version: 2}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid ID",
+			id:             "abc",
+			mock:           func(*MockCommentProvider) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Comment not found",
+			id:   "2",
+			mock: func(mockProvider *MockCommentProvider) {
+				mockProvider.On("Comment", mock.Anything, 2, 0).Return(nil, service.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:          "Comment on a draft post of another user",
+			id:            "3",
+			authenticated: true,
+			mock: func(mockProvider *MockCommentProvider) {
+				mockProvider.On("Comment", mock.Anything, 3, 1).Return(nil, service.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockCommentProvider)
+			h := &CommentHandler{log: log, validate: validator.New(), provider: mockProvider}
+
+			tt.mock(mockProvider)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/comments/"+tt.id, nil)
+			if tt.authenticated {
+				req = withUser(req, model.RoleAuthor)
+			}
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			h.Comment(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+			}
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCommentHandler_UpdateComment(t *testing.T) {
+	commentReq := &model.CommentUpdateRequest{Content: "updated content"}
+
+	tests := []struct {
+		name           string
+		id             string
+		noIfMatch      bool
+		body           string
+		mock           func(mockProcessor *MockCommentProcessor)
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			id:   "1",
+			body: `{"content": "updated content"}`,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("UpdateComment", mock.Anything, 1, 2, 1, model.RoleAuthor, commentReq).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid ID",
+			id:             "abc",
+			body:           `{"content": "updated content"}`,
+			mock:           func(*MockCommentProcessor) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid JSON",
+			id:             "1",
+			body:           `{`,
+			mock:           func(*MockCommentProcessor) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Validation error",
+			id:             "1",
+			body:           `{"content": "", "post_id": 1}`,
+			mock:           func(*MockCommentProcessor) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Not the owner",
+			id:   "1",
+			body: `{"content": "updated content"}`,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("UpdateComment", mock.Anything, 1, 2, 1, model.RoleAuthor, commentReq).Return(service.ErrNotAllowed)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Comment not found",
+			id:   "1",
+			body: `{"content": "updated content"}`,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("UpdateComment", mock.Anything, 1, 2, 1, model.RoleAuthor, commentReq).Return(service.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "Internal server error",
+			id:   "1",
+			body: `{"content": "updated content"}`,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("UpdateComment", mock.Anything, 1, 2, 1, model.RoleAuthor, commentReq).Return(assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "Missing If-Match",
+			id:             "1",
+			noIfMatch:      true,
+			body:           `{"content": "updated content"}`,
+			mock:           func(*MockCommentProcessor) {},
+			expectedStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name: "Comment has been modified",
+			id:   "1",
+			body: `{"content": "updated content"}`,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("UpdateComment", mock.Anything, 1, 2, 1, model.RoleAuthor, commentReq).Return(service.ErrConflict)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProcessor := new(MockCommentProcessor)
+			h := &CommentHandler{log: log, validate: validator.New(), processor: mockProcessor}
+
+			tt.mock(mockProcessor)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/comments/"+tt.id, bytes.NewBufferString(tt.body))
+			if !tt.noIfMatch {
+				req.Header.Set("If-Match", `"2"`)
+			}
+			req.SetPathValue("id", tt.id)
+			ctx := context.WithValue(req.Context(), middleware.UIDKey, "1")
+			req = req.WithContext(context.WithValue(ctx, middleware.RoleKey, model.RoleAuthor))
+			w := httptest.NewRecorder()
+
+			h.UpdateComment(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockProcessor.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCommentHandler_DeleteComment(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		noIfMatch      bool
+		role           model.Role
+		mock           func(mockProcessor *MockCommentProcessor)
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			id:   "1",
+			role: model.RoleAuthor,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("DeleteComment", mock.Anything, 1, 2, 1, model.RoleAuthor).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Moderator",
+			id:   "2",
+			role: model.RoleModerator,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("DeleteComment", mock.Anything, 2, 2, 1, model.RoleModerator).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid ID",
+			id:             "abc",
+			role:           model.RoleAuthor,
+			mock:           func(*MockCommentProcessor) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Not the owner",
+			id:   "1",
+			role: model.RoleAuthor,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("DeleteComment", mock.Anything, 1, 2, 1, model.RoleAuthor).Return(service.ErrNotAllowed)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Comment not found",
+			id:   "1",
+			role: model.RoleAuthor,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("DeleteComment", mock.Anything, 1, 2, 1, model.RoleAuthor).Return(service.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Missing If-Match",
+			id:             "1",
+			role:           model.RoleAuthor,
+			noIfMatch:      true,
+			mock:           func(*MockCommentProcessor) {},
+			expectedStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name: "Comment has been modified",
+			id:   "1",
+			role: model.RoleAuthor,
+			mock: func(mockProcessor *MockCommentProcessor) {
+				mockProcessor.On("DeleteComment", mock.Anything, 1, 2, 1, model.RoleAuthor).Return(service.ErrConflict)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProcessor := new(MockCommentProcessor)
+			h := &CommentHandler{log: log, validate: validator.New(), processor: mockProcessor}
+
+			tt.mock(mockProcessor)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/comments/"+tt.id, nil)
+			if !tt.noIfMatch {
+				req.Header.Set("If-Match", `"2"`)
+			}
+			req.SetPathValue("id", tt.id)
+			ctx := context.WithValue(req.Context(), middleware.UIDKey, "1")
+			req = req.WithContext(context.WithValue(ctx, middleware.RoleKey, tt.role))
+			w := httptest.NewRecorder()
+
+			h.DeleteComment(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockProcessor.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCommentHandler_CommentsByPostTree(t *testing.T) {
+	mockProvider := new(MockCommentProvider)
+	h := &CommentHandler{log: log, validate: validator.New(), provider: mockProvider}
+
+	parentID := 1
+	mockProvider.On("CommentTree", mock.Anything, 1, 0).Return([]*model.Comment{
+		{ID: 1, PostID: 1, Replies: []*model.Comment{{ID: 2, PostID: 1, ParentID: &parentID, Depth: 1}}},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/posts/1/comments?view=tree", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	h.CommentsByPost(context.Background()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var comments []*model.Comment
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&comments))
+	assert.Len(t, comments, 1)
+	assert.Len(t, comments[0].Replies, 1)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/posts/1/comments?view=graph", nil)
+	req.SetPathValue("id", "1")
+	w = httptest.NewRecorder()
+
+	h.CommentsByPost(context.Background()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockProvider.AssertExpectations(t)
+}
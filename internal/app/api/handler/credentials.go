@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// @Summary Change password
+// @Description Set a new password after confirming the current one. Every other session of the user is logged out.
+// @Security ApiKeyAuth
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param password body model.ChangePasswordRequest true "Current and new password"
+// @Success 200 {string} string "Password changed"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Wrong current password"
+// @Failure 429 {string} string "Too many attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/me/password [post]
+func (ah *AuthHandler) ChangePassword(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.ChangePassword"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var passwordReq model.ChangePasswordRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&passwordReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(passwordReq); err != nil {
+			log.Warn("error validating password", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		sessionID := middleware.GetSessionIDFromCtx(r.Context())
+
+		if err := ah.service.ChangePassword(ctx, userID, sessionID, clientIP(r), &passwordReq); err != nil {
+			if !reauthenticationFailed(w, log, err) {
+				log.Error("error changing password", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Password changed")) //nolint:errcheck
+	}
+}
+
+// @Summary Change email
+// @Description Send a confirmation link to the new address after confirming the password. The address is switched only after the link is confirmed.
+// @Security ApiKeyAuth
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param email body model.ChangeEmailRequest true "Password and new email"
+// @Success 202 {string} string "Confirmation link sent"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Wrong password"
+// @Failure 409 {string} string "Email is already in use"
+// @Failure 429 {string} string "Too many attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/me/email [post]
+func (ah *AuthHandler) ChangeEmail(ctx context.Context, cfg config.Auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.ChangeEmail"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var emailReq model.ChangeEmailRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&emailReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(emailReq); err != nil {
+			log.Warn("error validating email", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		if err := ah.service.ChangeEmail(ctx, cfg, userID, clientIP(r), &emailReq); err != nil {
+			if reauthenticationFailed(w, log, err) {
+				return
+			}
+
+			if errors.Is(err, service.ErrAlreadyExists) {
+				log.Warn("email is already in use", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusConflict)
+
+				return
+			}
+
+			log.Error("error changing email", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Confirmation link sent")) //nolint:errcheck
+	}
+}
+
+// @Summary Confirm email change
+// @Description Switch the email address of the current user using the emailed token. Every other session of the user is logged out.
+// @Security ApiKeyAuth
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param token body model.ConfirmEmailChangeRequest true "Emailed token"
+// @Success 200 {string} string "Email changed"
+// @Failure 400 {string} string "Invalid or expired token"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 409 {string} string "Email is already in use"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/me/email/confirm [post]
+func (ah *AuthHandler) ConfirmEmailChange(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.ConfirmEmailChange"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var confirmReq model.ConfirmEmailChangeRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&confirmReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(confirmReq); err != nil {
+			log.Warn("error validating token", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		sessionID := middleware.GetSessionIDFromCtx(r.Context())
+
+		if err := ah.service.ConfirmEmailChange(ctx, userID, sessionID, confirmReq.Token); err != nil {
+			if errors.Is(err, service.ErrInvalidToken) {
+				log.Warn("invalid email change token", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrAlreadyExists) {
+				log.Warn("email is already in use", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusConflict)
+
+				return
+			}
+
+			log.Error("error confirming email change", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Email changed")) //nolint:errcheck
+	}
+}
+
+// @Summary Delete account
+// @Description Delete the account of the current user after confirming the password. Depending on the server policy its posts and comments are deleted or kept under a deleted user.
+// @Security ApiKeyAuth
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param password body model.DeleteAccountRequest true "Password"
+// @Success 200 {string} string "Account deleted"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Wrong password"
+// @Failure 404 {string} string "User not found"
+// @Failure 429 {string} string "Too many attempts"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/me [delete]
+func (ah *AuthHandler) DeleteAccount(ctx context.Context, cfg config.Account) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.DeleteAccount"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var deleteReq model.DeleteAccountRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&deleteReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(deleteReq); err != nil {
+			log.Warn("error validating request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		policy := model.DeletionPolicy(cfg.DeletionPolicy)
+
+		if err := ah.service.DeleteAccount(ctx, policy, userID, clientIP(r), deleteReq.Password); err != nil {
+			if !reauthenticationFailed(w, log, err) {
+				log.Error("error deleting account", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Account deleted")) //nolint:errcheck
+	}
+}
+
+// reauthenticationFailed responds to a rejected password confirmation
+// and reports whether it did.
+func reauthenticationFailed(w http.ResponseWriter, log *slog.Logger, err error) bool {
+	var locked *service.LockedError
+	if errors.As(err, &locked) {
+		log.Warn("password confirmation while locked", sl.Err(err))
+		tooManyAttempts(w, locked)
+
+		return true
+	}
+
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		log.Warn("wrong password", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusForbidden)
+
+		return true
+	}
+
+	if errors.Is(err, service.ErrNotFound) {
+		log.Warn("user not found", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusNotFound)
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+var (
+	errIfMatchRequired = errors.New("the ETag of the resource is required in the If-Match header")
+	errIfMatchInvalid  = errors.New("the If-Match header does not match the ETag of the resource")
+)
+
+// etag returns the entity tag of a post or comment at the version.
+func etag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// ifMatchVersion returns the version of the ETag in the If-Match header.
+//
+// Without the header it returns errIfMatchRequired. A header which is not
+// a single ETag of this API can never match and gives errIfMatchInvalid.
+func ifMatchVersion(r *http.Request) (int, error) {
+	value := r.Header.Get("If-Match")
+	if value == "" {
+		return 0, errIfMatchRequired
+	}
+
+	unquoted, err := strconv.Unquote(value)
+	if err != nil || value[0] != '"' {
+		return 0, errIfMatchInvalid
+	}
+
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, errIfMatchInvalid
+	}
+
+	return version, nil
+}
+
+// preconditionStatus returns the status of an If-Match error.
+func preconditionStatus(err error) int {
+	if errors.Is(err, errIfMatchRequired) {
+		return http.StatusPreconditionRequired
+	}
+
+	return http.StatusPreconditionFailed
+}
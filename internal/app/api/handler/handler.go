@@ -8,6 +8,8 @@ import (
 	"github.com/go-playground/validator"
 	"github.com/markraiter/simple-blog/config"
 	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/markraiter/simple-blog/internal/model"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
@@ -23,6 +25,16 @@ type PostService interface {
 
 type CommentService interface {
 	CommentSaver
+	CommentProvider
+	CommentProcessor
+}
+
+type UserService interface {
+	User
+}
+
+type SearchService interface {
+	Searcher
 }
 
 type Handler struct {
@@ -30,6 +42,8 @@ type Handler struct {
 	AuthHandler
 	PostHandler
 	CommentHandler
+	UserHandler
+	SearchHandler
 }
 
 // The response struct is used to send a message back to the client.
@@ -43,6 +57,8 @@ func New(
 	a AuthService,
 	p PostService,
 	c CommentService,
+	u UserService,
+	s SearchService,
 ) *Handler {
 	return &Handler{
 		Healthcheck{log: l},
@@ -62,39 +78,124 @@ func New(
 			log:       l,
 			validate:  v,
 			saver:     c,
-			provider:  nil,
-			processor: nil,
+			provider:  c,
+			processor: c,
+		},
+		UserHandler{
+			log:      l,
+			validate: v,
+			service:  u,
+		},
+		SearchHandler{
+			log:      l,
+			validate: v,
+			service:  s,
 		},
 	}
 }
 
-func (h *Handler) Router(ctx context.Context, cfg config.Config, log *slog.Logger) http.Handler {
+func (h *Handler) Router(ctx context.Context, cfg config.Config, log *slog.Logger, keys *jwt.KeySet, revocations middleware.RevocationChecker) http.Handler {
 	m := http.NewServeMux()
 
-	basicAuth := middleware.BasicAuth(cfg.Auth, log)
+	basicAuth := middleware.BasicAuth(keys, log, revocations, h.AuthHandler.service)
+	requireSession := middleware.RequireSession(log)
+	requireAuthor := middleware.RequireRole(model.RoleAuthor, log)
+	requireAdmin := middleware.RequireRole(model.RoleAdmin, log)
+	postsWrite := middleware.RequireScope(model.ScopePostsWrite, log)
+	commentsWrite := middleware.RequireScope(model.ScopeCommentsWrite, log)
+
+	// session accepts only JWT access tokens, personal access tokens
+	// can not manage the account.
+	session := func(next http.Handler) http.Handler {
+		return basicAuth(requireSession(next))
+	}
+
+	// optionalAuth lets authors see their own unpublished posts and their
+	// comments on public routes.
+	optionalAuth := middleware.OptionalAuth(basicAuth)
 
 	m.Handle("/swagger/", httpSwagger.Handler(httpSwagger.URL("/swagger/doc.json")))
 	m.Handle("GET /health", h.APIHealth())
+	m.Handle("GET /.well-known/jwks.json", h.JWKS(keys))
 	{
-		m.Handle("POST /api/auth/register", h.RegisterUser(ctx))
+		m.Handle("POST /api/auth/register", h.RegisterUser(ctx, cfg.Auth))
 		m.Handle("POST /api/auth/login", h.Login(ctx, cfg.Auth))
+		m.Handle("POST /api/auth/login/2fa", h.LoginTwoFactor(ctx, cfg.Auth))
+		m.Handle("GET /api/auth/oidc/login", h.OIDCLogin(cfg.Auth))
+		m.Handle("GET /api/auth/oidc/callback", h.OIDCCallback(ctx, cfg.Auth))
+		m.Handle("POST /api/auth/refresh", h.Refresh(ctx, cfg.Auth))
+		m.Handle("POST /api/auth/logout", session(h.Logout(ctx)))
+		m.Handle("POST /api/auth/logout-all", session(h.LogoutAll(ctx)))
+		m.Handle("POST /api/auth/password/forgot", h.ForgotPassword(ctx, cfg.Auth))
+		m.Handle("POST /api/auth/password/reset", h.ResetPassword(ctx))
+		m.Handle("GET /api/auth/verify", h.VerifyEmail(ctx))
+		m.Handle("POST /api/auth/verify/resend", session(h.ResendVerification(ctx, cfg.Auth)))
 	}
 
 	{
-		m.Handle("POST /api/posts", basicAuth(h.CreatePost(ctx)))
-		m.Handle("GET /api/posts", h.Posts(ctx))
-		m.Handle("GET /api/posts/{id}", h.Post(ctx))
-		m.Handle("PUT /api/posts/{id}", basicAuth(h.UpdatePost(ctx)))
-		m.Handle("DELETE /api/posts/{id}", basicAuth(h.DeletePost(ctx)))
+		m.Handle("POST /api/auth/2fa/enroll", session(h.EnrollTwoFactor(ctx, cfg.Auth)))
+		m.Handle("POST /api/auth/2fa/confirm", session(h.ConfirmTwoFactor(ctx)))
+		m.Handle("POST /api/auth/2fa/disable", session(h.DisableTwoFactor(ctx)))
+		m.Handle("POST /api/auth/2fa/recovery-codes", session(h.RegenerateRecoveryCodes(ctx)))
 	}
 
 	{
-		m.Handle("POST /api/comments", basicAuth(h.CreateComment(ctx)))
-		// m.Handle("GET /api/comments", h.Comments(ctx))
-		// m.Handle("GET /api/comments/{id}", h.Comment(ctx))
-		// m.Handle("PUT /api/comments/{id}", basicAuth(h.UpdateComment(ctx)))
-		// m.Handle("DELETE /api/comments/{id}", basicAuth(h.DeleteComment(ctx)))
+		m.Handle("POST /api/auth/tokens", session(h.CreatePersonalToken(ctx)))
+		m.Handle("GET /api/auth/tokens", session(h.PersonalTokens(ctx)))
+		m.Handle("DELETE /api/auth/tokens/{id}", session(h.RevokePersonalToken(ctx)))
 	}
 
-	return m
+	{
+		m.Handle("GET /api/auth/sessions", session(h.Sessions(ctx)))
+		m.Handle("DELETE /api/auth/sessions/{id}", session(h.RevokeSession(ctx)))
+	}
+
+	{
+		m.Handle("GET /api/users/me", basicAuth(h.CurrentUser(ctx)))
+		m.Handle("PATCH /api/users/me", session(h.UpdateCurrentUser(ctx)))
+		m.Handle("DELETE /api/users/me", session(h.DeleteAccount(ctx, cfg.Account)))
+		m.Handle("GET /api/users/me/export", session(h.ExportAccount(ctx)))
+		m.Handle("POST /api/users/me/password", session(h.ChangePassword(ctx)))
+		m.Handle("POST /api/users/me/email", session(h.ChangeEmail(ctx, cfg.Auth)))
+		m.Handle("POST /api/users/me/email/confirm", session(h.ConfirmEmailChange(ctx)))
+		m.Handle("GET /api/users/{id}", h.UserProfile(ctx))
+		m.Handle("GET /api/users/{id}/posts", optionalAuth(h.UserPosts(ctx)))
+	}
+
+	{
+		m.Handle("POST /api/posts", basicAuth(postsWrite(requireAuthor(h.CreatePost(ctx)))))
+		m.Handle("GET /api/posts", optionalAuth(h.Posts(ctx)))
+		m.Handle("GET /api/posts/{id}", optionalAuth(h.Post(ctx)))
+		m.Handle("GET /api/posts/{id}/comments", optionalAuth(h.CommentsByPost(ctx)))
+		m.Handle("PUT /api/posts/{id}", basicAuth(postsWrite(h.UpdatePost(ctx))))
+		m.Handle("DELETE /api/posts/{id}", basicAuth(postsWrite(h.DeletePost(ctx))))
+		m.Handle("GET /api/posts/{id}/revisions", basicAuth(h.Revisions(ctx)))
+		m.Handle("GET /api/posts/{id}/revisions/diff", basicAuth(h.RevisionDiff(ctx)))
+		m.Handle("POST /api/posts/{id}/revisions/{rev}/restore", basicAuth(postsWrite(h.RestoreRevision(ctx))))
+	}
+
+	{
+		m.Handle("POST /api/comments", basicAuth(commentsWrite(h.CreateComment(ctx))))
+		m.Handle("GET /api/comments/{id}", optionalAuth(h.Comment(ctx)))
+		m.Handle("PUT /api/comments/{id}", basicAuth(commentsWrite(h.UpdateComment(ctx))))
+		m.Handle("DELETE /api/comments/{id}", basicAuth(commentsWrite(h.DeleteComment(ctx))))
+	}
+
+	{
+		m.Handle("GET /api/tags", h.Tags(ctx))
+		m.Handle("GET /api/tags/{slug}/posts", optionalAuth(h.TagPosts(ctx)))
+	}
+
+	{
+		m.Handle("GET /api/search", h.Search(ctx))
+	}
+
+	{
+		m.Handle("PUT /api/admin/users/{id}/role", session(requireAdmin(h.SetUserRole(ctx))))
+	}
+
+	slugs := http.NewServeMux()
+	slugs.Handle("GET "+slugPrefix+"{slug}", optionalAuth(h.PostBySlug(ctx)))
+
+	return routeSlugs(slugs, m)
 }
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+)
+
+// @Summary JSON Web Key Set
+// @Description Public keys access tokens are signed with. Tokens carry the key ID in the kid header.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} jwt.JWKS
+// @Router /.well-known/jwks.json [get]
+func (ah *AuthHandler) JWKS(keys *jwt.KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(http.StatusOK)
+
+		json.NewEncoder(w).Encode(keys.JWKS()) //nolint:errcheck
+	}
+}
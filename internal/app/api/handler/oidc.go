@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+)
+
+// oidcStateCookie keeps the signed state of an external login between
+// the redirect to the identity provider and the callback.
+const oidcStateCookie = "oidc_state"
+
+// @Summary Login with identity provider
+// @Description Redirect to the OpenID Connect identity provider. After the login there, the provider redirects back to /api/auth/oidc/callback.
+// @Tags auth
+// @Success 302 {string} string "Redirect to the identity provider"
+// @Failure 404 {string} string "Login with an identity provider is not configured"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/oidc/login [get]
+func (ah *AuthHandler) OIDCLogin(cfg config.Auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.OIDCLogin"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		authURL, stateToken, err := ah.service.StartOIDCLogin(cfg)
+		if err != nil {
+			if errors.Is(err, service.ErrOIDCDisabled) {
+				log.Warn("oidc login is disabled", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error starting oidc login", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		setOIDCStateCookie(w, cfg, stateToken, int(cfg.OIDCStateTTL.Seconds()))
+
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// @Summary Identity provider callback
+// @Description Finish the login with the OpenID Connect identity provider. The first login links the account at the provider to the user with the same verified email or creates a new user. If two-factor authentication is enabled, a challenge token is returned instead of the token pair.
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} model.LoginResponse
+// @Failure 400 {string} string "Invalid or expired login state, or the provider returned an error"
+// @Failure 401 {string} string "The provider did not confirm the login"
+// @Failure 403 {string} string "The provider did not share a verified email"
+// @Failure 404 {string} string "Login with an identity provider is not configured"
+// @Failure 409 {string} string "An account with the email exists and has to verify it first"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/oidc/callback [get]
+func (ah *AuthHandler) OIDCCallback(ctx context.Context, cfg config.Auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.OIDCCallback"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		// The state is single-use, whatever happens next.
+		setOIDCStateCookie(w, cfg, "", -1)
+
+		query := r.URL.Query()
+
+		if providerErr := query.Get("error"); providerErr != "" {
+			log.Warn("identity provider returned an error", slog.String("error", providerErr), slog.String("description", query.Get("error_description")))
+			http.Error(w, "identity provider returned an error: "+providerErr, http.StatusBadRequest)
+
+			return
+		}
+
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil {
+			log.Warn("missing login state", sl.Err(err))
+			http.Error(w, service.ErrInvalidToken.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		resp, err := ah.service.FinishOIDCLogin(ctx, cfg, cookie.Value, query.Get("state"), query.Get("code"), clientInfo(r))
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrOIDCDisabled):
+				log.Warn("oidc login is disabled", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case errors.Is(err, service.ErrInvalidToken):
+				log.Warn("invalid login state", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case errors.Is(err, service.ErrInvalidCredentials):
+				log.Warn("identity provider did not confirm the login", sl.Err(err))
+				http.Error(w, service.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+			case errors.Is(err, service.ErrEmailNotVerified):
+				log.Warn("email not verified by identity provider", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusForbidden)
+			case errors.Is(err, service.ErrAlreadyExists):
+				log.Warn("account can not be linked", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				log.Error("error finishing oidc login", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp) //nolint:errcheck
+	}
+}
+
+// setOIDCStateCookie sets the state cookie, a negative maxAge deletes it.
+//
+// SameSite=Lax lets the browser send the cookie on the redirect back
+// from the identity provider.
+func setOIDCStateCookie(w http.ResponseWriter, cfg config.Auth, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(cfg.BaseURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// @Summary Create personal access token
+// @Description Create a token for scripts and CI. The token is shown only in this response.
+// @Security ApiKeyAuth
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Param token body model.PersonalTokenRequest true "Token name, scopes and optional expiry"
+// @Success 201 {object} model.PersonalTokenCreated
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Personal access tokens can not be used here"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/tokens [post]
+func (ah *AuthHandler) CreatePersonalToken(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.CreatePersonalToken"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var tokenReq model.PersonalTokenRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&tokenReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(tokenReq); err != nil {
+			log.Warn("error validating token", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		token, err := ah.service.CreatePersonalToken(ctx, userID, &tokenReq)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidExpiry) {
+				log.Warn("invalid expiry", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			log.Error("error creating personal access token", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(token) //nolint:errcheck
+	}
+}
+
+// @Summary List personal access tokens
+// @Description List active personal access tokens of the current user
+// @Security ApiKeyAuth
+// @Tags tokens
+// @Produce json
+// @Success 200 {array} model.PersonalAccessToken
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Personal access tokens can not be used here"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/tokens [get]
+func (ah *AuthHandler) PersonalTokens(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.PersonalTokens"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		tokens, err := ah.service.PersonalTokens(ctx, userID)
+		if err != nil {
+			log.Error("error getting personal access tokens", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tokens) //nolint:errcheck
+	}
+}
+
+// @Summary Revoke personal access token
+// @Description Revoke a personal access token of the current user
+// @Security ApiKeyAuth
+// @Tags tokens
+// @Produce json
+// @Param id path int true "Token ID"
+// @Success 200 {string} string "Token revoked"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Personal access tokens can not be used here"
+// @Failure 404 {string} string "Token not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/tokens/{id} [delete]
+func (ah *AuthHandler) RevokePersonalToken(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.RevokePersonalToken"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		tokenID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		if err := ah.service.RevokePersonalToken(ctx, userID, tokenID); err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("token not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error revoking personal access token", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Token revoked")) //nolint:errcheck
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/markraiter/simple-blog/internal/app/api/middleware"
@@ -20,13 +22,19 @@ type PostSaver interface {
 }
 
 type PostProvider interface {
-	Post(ctx context.Context, id int) (*model.Post, error)
-	Posts(ctx context.Context) ([]*model.Post, error)
+	Post(ctx context.Context, id, viewerID int) (*model.Post, error)
+	Posts(ctx context.Context, query *model.PostQuery, after string) (*model.PostPage, error)
+	Tags(ctx context.Context) ([]*model.Tag, error)
+	TagPosts(ctx context.Context, tag string, query *model.PostQuery, after string) (*model.PostPage, error)
+	Revisions(ctx context.Context, postID, userID int, role model.Role) ([]*model.PostRevision, error)
+	RevisionDiff(ctx context.Context, postID, from, to, userID int, role model.Role) (*model.RevisionDiff, error)
+	PostBySlug(ctx context.Context, slug string, viewerID int) (*model.Post, error)
 }
 
 type PostProcessor interface {
-	UpdatePost(ctx context.Context, postID, userID int, postReq *model.PostRequest) error
-	DeletePost(ctx context.Context, postID, userID int) error
+	UpdatePost(ctx context.Context, postID, version, userID int, role model.Role, postReq *model.PostRequest) error
+	DeletePost(ctx context.Context, postID, version, userID int, role model.Role) error
+	RestoreRevision(ctx context.Context, postID, number, userID int, role model.Role) error
 }
 
 type PostHandler struct {
@@ -38,7 +46,7 @@ type PostHandler struct {
 }
 
 // @Summary Create a post
-// @Description Create a post
+// @Description Create a post. The content is plain text unless the format is markdown.
 // @Security ApiKeyAuth
 // @Tags posts
 // @Accept json
@@ -46,6 +54,7 @@ type PostHandler struct {
 // @Param post body model.PostRequest true "Post object that needs to be created"
 // @Success 201 {string} string "Post created"
 // @Failure 400 {string} string "Invalid request"
+// @Failure 403 {string} string "User must be at least an author with a verified email"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/posts [post]
 func (h *PostHandler) CreatePost(ctx context.Context) http.HandlerFunc {
@@ -74,6 +83,20 @@ func (h *PostHandler) CreatePost(ctx context.Context) http.HandlerFunc {
 
 		id, err := h.saver.SavePost(ctx, userID, &postReq)
 		if err != nil {
+			if errors.Is(err, service.ErrEmailNotVerified) {
+				log.Warn("email is not verified", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusForbidden)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrInvalidTag) || errors.Is(err, service.ErrInvalidSchedule) {
+				log.Warn("invalid post", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
 			log.Error("error saving post", sl.Err(err))
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
@@ -87,12 +110,13 @@ func (h *PostHandler) CreatePost(ctx context.Context) http.HandlerFunc {
 }
 
 // @Summary Get a Post
-// @Description Get a Post
+// @Description Get a Post with its content both as the source and rendered to sanitised HTML
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Param id query int true "Post ID"
 // @Success 200 {object} model.Post
+// @Header 200 {string} ETag "Version of the post"
 // @Failure 400 {string} string "Invalid request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/posts/{id} [get]
@@ -118,7 +142,7 @@ func (h *PostHandler) Post(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		post, err := h.provider.Post(ctx, id)
+		post, err := h.provider.Post(ctx, id, middleware.GetUserIDFromCtx(r.Context()))
 		if err != nil {
 			if errors.Is(err, service.ErrNotFound) {
 				log.Warn("post not found", sl.Err(err))
@@ -134,6 +158,7 @@ func (h *PostHandler) Post(ctx context.Context) http.HandlerFunc {
 		}
 
 		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag(post.Version))
 		w.WriteHeader(http.StatusOK)
 
 		if err := json.NewEncoder(w).Encode(post); err != nil {
@@ -146,11 +171,21 @@ func (h *PostHandler) Post(ctx context.Context) http.HandlerFunc {
 }
 
 // @Summary Get Posts
-// @Description Get Posts
+// @Description Get a page of posts. The next page is requested with the next_cursor of the previous one,
+// @Description which is also linked in the Link header. The cursor is only valid with the same sort order.
 // @Tags posts
 // @Accept json
 // @Produce json
-// @Success 200 {array} model.Post
+// @Param limit query int false "Page size, 20 by default" minimum(1) maximum(100)
+// @Param after query string false "Cursor of the previous page"
+// @Param sort query string false "Sort order, newest first by default" Enums(created, updated, comments)
+// @Param author query int false "Author ID"
+// @Param tag query string false "Tag slug"
+// @Param from query string false "Created at or after, RFC 3339 time or date"
+// @Param to query string false "Created before, RFC 3339 time or date"
+// @Success 200 {object} model.PostPage
+// @Header 200 {string} Link "Link to the next page"
+// @Failure 400 {string} string "Invalid request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/posts [get]
 func (h *PostHandler) Posts(ctx context.Context) http.HandlerFunc {
@@ -159,18 +194,42 @@ func (h *PostHandler) Posts(ctx context.Context) http.HandlerFunc {
 
 		log := h.log.With(slog.String("operation", operation))
 
-		posts, err := h.provider.Posts(ctx)
+		query, err := parsePostQuery(r)
 		if err != nil {
+			log.Warn("error parsing query", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := h.validate.Struct(query); err != nil {
+			log.Warn("error validating query", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		page, err := h.provider.Posts(ctx, query, r.URL.Query().Get("after"))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidTag) {
+				log.Warn("invalid query", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
 			log.Error("error getting posts", sl.Err(err))
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
 			return
 		}
 
+		setNextLink(w, r, page.NextCursor)
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		if err := json.NewEncoder(w).Encode(posts); err != nil {
+		if err := json.NewEncoder(w).Encode(page); err != nil {
 			log.Error("error encoding posts", sl.Err(err))
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
@@ -179,18 +238,84 @@ func (h *PostHandler) Posts(ctx context.Context) http.HandlerFunc {
 	}
 }
 
+// parsePostQuery reads the filters and the page size of the post listing from the query string.
+func parsePostQuery(r *http.Request) (*model.PostQuery, error) {
+	values := r.URL.Query()
+
+	query := &model.PostQuery{
+		Sort:     model.PostSort(values.Get("sort")),
+		Tag:      values.Get("tag"),
+		ViewerID: middleware.GetUserIDFromCtx(r.Context()),
+	}
+
+	for name, dst := range map[string]*int{"limit": &query.Limit, "author": &query.AuthorID} {
+		if value := values.Get(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+
+			*dst = n
+		}
+	}
+
+	if values.Has("limit") && query.Limit == 0 {
+		return nil, errors.New("invalid limit: must be positive")
+	}
+
+	for name, dst := range map[string]**time.Time{"from": &query.From, "to": &query.To} {
+		if value := values.Get(name); value != "" {
+			t, err := parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+
+			*dst = &t
+		}
+	}
+
+	return query, nil
+}
+
+// setNextLink links the page after the cursor in the Link header, if there is one.
+func setNextLink(w http.ResponseWriter, r *http.Request, cursor string) {
+	if cursor == "" {
+		return
+	}
+
+	next := *r.URL
+	values := next.Query()
+	values.Set("after", cursor)
+	next.RawQuery = values.Encode()
+
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+}
+
+// parseTime accepts an RFC 3339 time or a date.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.DateOnly, value)
+}
+
 // @Summary Update a post
-// @Description Update a post
+// @Description Update a post. The new title, content and format are saved as a revision of the post.
+// @Description The If-Match header must hold the ETag the post was read with.
 // @Security ApiKeyAuth
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Param id query int true "Post ID"
+// @Param If-Match header string true "ETag of the post"
 // @Param post body model.PostRequest true "Post object that needs to be updated"
 // @Success 200 {string} string "Post updated"
 // @Failure 400 {string} string "Invalid request"
-// @Failure 403 {string} string "User is not the owner of the post"
+// @Failure 403 {string} string "User is neither the owner of the post nor a moderator"
 // @Failure 404 {string} string "Post not found"
+// @Failure 412 {string} string "Post has been modified since it was read"
+// @Failure 428 {string} string "If-Match header is missing"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/posts/{id} [put]
 func (h *PostHandler) UpdatePost(ctx context.Context) http.HandlerFunc {
@@ -200,6 +325,7 @@ func (h *PostHandler) UpdatePost(ctx context.Context) http.HandlerFunc {
 		log := h.log.With(slog.String("operation", operation))
 
 		userID := middleware.GetUserIDFromCtx(r.Context())
+		role := middleware.GetRoleFromCtx(r.Context())
 
 		postIDStr := r.URL.Query().Get("id")
 		if postIDStr == "" {
@@ -217,6 +343,14 @@ func (h *PostHandler) UpdatePost(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			log.Warn("error checking If-Match", sl.Err(err))
+			http.Error(w, err.Error(), preconditionStatus(err))
+
+			return
+		}
+
 		var postReq model.PostRequest
 		if err := json.NewDecoder(r.Body).Decode(&postReq); err != nil {
 			log.Warn("error parsing request", sl.Err(err))
@@ -232,8 +366,15 @@ func (h *PostHandler) UpdatePost(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		err = h.processor.UpdatePost(ctx, postID, userID, &postReq)
+		err = h.processor.UpdatePost(ctx, postID, version, userID, role, &postReq)
 		if err != nil {
+			if errors.Is(err, service.ErrConflict) {
+				log.Warn("post has been modified", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusPreconditionFailed)
+
+				return
+			}
+
 			if errors.Is(err, service.ErrNotAllowed) {
 				log.Warn("user is not allowed to perform this operation", sl.Err(err))
 				http.Error(w, err.Error(), http.StatusForbidden)
@@ -241,6 +382,13 @@ func (h *PostHandler) UpdatePost(ctx context.Context) http.HandlerFunc {
 				return
 			}
 
+			if errors.Is(err, service.ErrInvalidTag) || errors.Is(err, service.ErrInvalidSchedule) {
+				log.Warn("invalid post", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
 			if errors.Is(err, service.ErrNotFound) {
 				log.Warn("post not found", sl.Err(err))
 				http.Error(w, err.Error(), http.StatusNotFound)
@@ -261,16 +409,19 @@ func (h *PostHandler) UpdatePost(ctx context.Context) http.HandlerFunc {
 }
 
 // @Summary Delete a Post
-// @Description Delete a Post
+// @Description Delete a Post. The If-Match header must hold the ETag the post was read with.
 // @Security ApiKeyAuth
 // @Tags posts
 // @Accept json
 // @Produce json
 // @Param id query int true "Post ID"
+// @Param If-Match header string true "ETag of the post"
 // @Success 200 {string} string "Post deleted"
 // @Failure 400 {string} string "Invalid request"
-// @Failure 403 {string} string "User is not the owner of the post"
+// @Failure 403 {string} string "User is neither the owner of the post nor a moderator"
 // @Failure 404 {string} string "Post not found"
+// @Failure 412 {string} string "Post has been modified since it was read"
+// @Failure 428 {string} string "If-Match header is missing"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/posts/{id} [delete]
 func (hp *PostHandler) DeletePost(ctx context.Context) http.HandlerFunc {
@@ -280,6 +431,7 @@ func (hp *PostHandler) DeletePost(ctx context.Context) http.HandlerFunc {
 		log := hp.log.With(slog.String("operation", operation))
 
 		userID := middleware.GetUserIDFromCtx(r.Context())
+		role := middleware.GetRoleFromCtx(r.Context())
 
 		idStr := r.URL.Query().Get("id")
 		if idStr == "" {
@@ -297,8 +449,23 @@ func (hp *PostHandler) DeletePost(ctx context.Context) http.HandlerFunc {
 			return
 		}
 
-		err = hp.processor.DeletePost(ctx, postID, userID)
+		version, err := ifMatchVersion(r)
 		if err != nil {
+			log.Warn("error checking If-Match", sl.Err(err))
+			http.Error(w, err.Error(), preconditionStatus(err))
+
+			return
+		}
+
+		err = hp.processor.DeletePost(ctx, postID, version, userID, role)
+		if err != nil {
+			if errors.Is(err, service.ErrConflict) {
+				log.Warn("post has been modified", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusPreconditionFailed)
+
+				return
+			}
+
 			if errors.Is(err, service.ErrNotAllowed) {
 				log.Warn("user is not allowed to perform this operation", sl.Err(err))
 				http.Error(w, err.Error(), http.StatusForbidden)
@@ -12,6 +12,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator"
 	"github.com/markraiter/simple-blog/internal/app/api/middleware"
@@ -33,8 +34,8 @@ func (m *MockPostSaver) SavePost(ctx context.Context, userID int, postReq *model
 
 type MockPostProvider struct{ mock.Mock }
 
-func (m *MockPostProvider) Post(ctx context.Context, postID int) (*model.Post, error) {
-	args := m.Called(ctx, postID)
+func (m *MockPostProvider) Post(ctx context.Context, postID, viewerID int) (*model.Post, error) {
+	args := m.Called(ctx, postID, viewerID)
 	if args.Get(0) != nil {
 		return args.Get(0).(*model.Post), args.Error(1)
 	}
@@ -42,20 +43,42 @@ func (m *MockPostProvider) Post(ctx context.Context, postID int) (*model.Post, e
 	return nil, args.Error(1)
 }
 
-func (m *MockPostProvider) Posts(ctx context.Context) ([]*model.Post, error) {
+func (m *MockPostProvider) Posts(ctx context.Context, query *model.PostQuery, after string) (*model.PostPage, error) {
+	args := m.Called(ctx, query, after)
+	page := args.Get(0)
+	if page == nil {
+		return nil, args.Error(1)
+	}
+	return page.(*model.PostPage), args.Error(1)
+}
+
+func (m *MockPostProvider) Tags(ctx context.Context) ([]*model.Tag, error) {
 	args := m.Called(ctx)
-	return args.Get(0).([]*model.Post), args.Error(1)
+	tags := args.Get(0)
+	if tags == nil {
+		return nil, args.Error(1)
+	}
+	return tags.([]*model.Tag), args.Error(1)
+}
+
+func (m *MockPostProvider) TagPosts(ctx context.Context, tag string, query *model.PostQuery, after string) (*model.PostPage, error) {
+	args := m.Called(ctx, tag, query, after)
+	page := args.Get(0)
+	if page == nil {
+		return nil, args.Error(1)
+	}
+	return page.(*model.PostPage), args.Error(1)
 }
 
 type MockPostProcessor struct{ mock.Mock }
 
-func (m *MockPostProcessor) UpdatePost(ctx context.Context, posID, userID int, postReq *model.PostRequest) error {
-	args := m.Called(ctx, posID, userID, postReq)
+func (m *MockPostProcessor) UpdatePost(ctx context.Context, posID, version, userID int, role model.Role, postReq *model.PostRequest) error {
+	args := m.Called(ctx, posID, version, userID, role, postReq)
 	return args.Error(0)
 }
 
-func (m *MockPostProcessor) DeletePost(ctx context.Context, posID, userID int) error {
-	args := m.Called(ctx, posID, userID)
+func (m *MockPostProcessor) DeletePost(ctx context.Context, posID, version, userID int, role model.Role) error {
+	args := m.Called(ctx, posID, version, userID, role)
 	return args.Error(0)
 }
 
@@ -108,6 +131,69 @@ func TestPostHandler_CreatePost(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			expectSavePost: false,
 		},
+		{
+			name: "Invalid request - too many tags",
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "Content",
+				Tags:    []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectSavePost: false,
+		},
+		{
+			name: "Invalid tag",
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "Content",
+				Tags:    []string{"#!"},
+			},
+			mockReturnErr:  service.ErrInvalidTag,
+			expectedStatus: http.StatusBadRequest,
+			expectSavePost: true,
+		},
+		{
+			name: "Invalid request - unknown status",
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "Content",
+				Status:  "hidden",
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectSavePost: false,
+		},
+		{
+			name: "Markdown",
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "**Content**",
+				Format:  model.ContentFormatMarkdown,
+			},
+			mockReturnID:   1,
+			expectedStatus: http.StatusCreated,
+			expectSavePost: true,
+		},
+		{
+			name: "Invalid request - unknown format",
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "Content",
+				Format:  "html",
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectSavePost: false,
+		},
+		{
+			name: "Invalid schedule",
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "Content",
+				Status:  model.PostStatusScheduled,
+			},
+			mockReturnErr:  service.ErrInvalidSchedule,
+			expectedStatus: http.StatusBadRequest,
+			expectSavePost: true,
+		},
 		{
 			name: "Internal server error",
 			postReq: &model.PostRequest{
@@ -169,9 +255,12 @@ func TestPostHandler_Post(t *testing.T) {
 			name:   "Success",
 			postID: "1",
 			mockReturnPost: &model.Post{
-				ID:      1,
-				Title:   "Title",
-				Content: "Content",
+				ID:          1,
+				Title:       "Title",
+				Content:     "**Content**",
+				Format:      model.ContentFormatMarkdown,
+				ContentHTML: "<p><strong>Content</strong></p>\n",
+				Version:     2,
 			},
 			mockReturnErr:  nil,
 			expectedStatus: http.StatusOK,
@@ -218,7 +307,7 @@ func TestPostHandler_Post(t *testing.T) {
 
 			if tt.expectGetPost {
 				postID, _ := strconv.Atoi(tt.postID)
-				mockProvider.On("Post", mock.Anything, postID).Return(tt.mockReturnPost, tt.mockReturnErr).Once()
+				mockProvider.On("Post", mock.Anything, postID, 0).Return(tt.mockReturnPost, tt.mockReturnErr).Once()
 			}
 
 			handler := h.Post(context.Background())
@@ -226,62 +315,106 @@ func TestPostHandler_Post(t *testing.T) {
 
 			resp := w.Result()
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, `"2"`, resp.Header.Get("ETag"))
+
+				var got model.Post
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+				assert.Equal(t, tt.mockReturnPost.Content, got.Content)
+				assert.Equal(t, tt.mockReturnPost.ContentHTML, got.ContentHTML)
+			}
 			mockProvider.AssertExpectations(t)
 		})
 	}
 }
 
 func TestPostHandler_Posts(t *testing.T) {
-	mockProvider := new(MockPostProvider)
-	h := &PostHandler{
-		log:       log,
-		validate:  validator.New(),
-		saver:     nil,
-		provider:  mockProvider,
-		processor: nil,
-	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 
 	tests := []struct {
-		name            string
-		mockReturnPosts []*model.Post
-		mockReturnErr   error
-		expectedStatus  int
-		expectGetPosts  bool
+		name           string
+		target         string
+		wantQuery      *model.PostQuery
+		wantAfter      string
+		mockReturnPage *model.PostPage
+		mockReturnErr  error
+		expectedStatus int
+		expectedLink   string
 	}{
 		{
-			name: "Success",
-			mockReturnPosts: []*model.Post{
-				{
-					ID:      1,
-					Title:   "Title",
-					Content: "Content",
-				},
-				{
-					ID:      2,
-					Title:   "Title",
-					Content: "Content",
-				},
+			name:      "Success",
+			target:    "/api/posts",
+			wantQuery: &model.PostQuery{},
+			mockReturnPage: &model.PostPage{Posts: []*model.Post{
+				{ID: 1, Title: "Title", Content: "Content"},
+				{ID: 2, Title: "Title", Content: "Content"},
+			}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "Next Page",
+			target:    "/api/posts?sort=comments&limit=2&author=3&from=2024-01-01&after=abc",
+			wantQuery: &model.PostQuery{Limit: 2, Sort: model.PostSortComments, AuthorID: 3, From: &from},
+			wantAfter: "abc",
+			mockReturnPage: &model.PostPage{
+				Posts:      []*model.Post{{ID: 1}, {ID: 2}},
+				NextCursor: "def",
 			},
-			mockReturnErr:  nil,
 			expectedStatus: http.StatusOK,
-			expectGetPosts: true,
+			expectedLink:   `</api/posts?after=def&author=3&from=2024-01-01&limit=2&sort=comments>; rel="next"`,
+		},
+		{
+			name:           "Invalid Sort",
+			target:         "/api/posts?sort=title",
+			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:            "Internal server error",
-			mockReturnPosts: nil,
-			mockReturnErr:   fmt.Errorf("internal server error"),
-			expectedStatus:  http.StatusInternalServerError,
-			expectGetPosts:  true,
+			name:           "Limit Too Large",
+			target:         "/api/posts?limit=101",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Zero Limit",
+			target:         "/api/posts?limit=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Date",
+			target:         "/api/posts?to=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Cursor",
+			target:         "/api/posts?after=abc",
+			wantQuery:      &model.PostQuery{},
+			wantAfter:      "abc",
+			mockReturnErr:  service.ErrInvalidCursor,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Internal server error",
+			target:         "/api/posts",
+			wantQuery:      &model.PostQuery{},
+			mockReturnErr:  fmt.Errorf("internal server error"),
+			expectedStatus: http.StatusInternalServerError,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest("GET", "/api/posts", nil)
+			mockProvider := new(MockPostProvider)
+			h := &PostHandler{
+				log:      log,
+				validate: validator.New(),
+				provider: mockProvider,
+			}
+
+			req := httptest.NewRequest("GET", tt.target, nil)
 			w := httptest.NewRecorder()
 
-			if tt.expectGetPosts {
-				mockProvider.On("Posts", mock.Anything).Return(tt.mockReturnPosts, tt.mockReturnErr).Once()
+			if tt.wantQuery != nil {
+				mockProvider.On("Posts", mock.Anything, tt.wantQuery, tt.wantAfter).Return(tt.mockReturnPage, tt.mockReturnErr).Once()
 			}
 
 			handler := h.Posts(context.Background())
@@ -289,6 +422,15 @@ func TestPostHandler_Posts(t *testing.T) {
 
 			resp := w.Result()
 			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tt.expectedLink, resp.Header.Get("Link"))
+
+			if tt.expectedStatus == http.StatusOK {
+				var page model.PostPage
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+				assert.Len(t, page.Posts, len(tt.mockReturnPage.Posts))
+				assert.Equal(t, tt.mockReturnPage.NextCursor, page.NextCursor)
+			}
+
 			mockProvider.AssertExpectations(t)
 		})
 	}
@@ -308,15 +450,17 @@ func TestPostHandler_UpdatePost(t *testing.T) {
 		name             string
 		userID           string
 		postID           string
+		ifMatch          string
 		postReq          *model.PostRequest
 		mockReturnErr    error
 		expectedStatus   int
 		expectUpdatePost bool
 	}{
 		{
-			name:   "Success",
-			userID: "1",
-			postID: "1",
+			name:    "Success",
+			userID:  "1",
+			postID:  "1",
+			ifMatch: `"3"`,
 			postReq: &model.PostRequest{
 				Title:   "Title",
 				Content: "Content",
@@ -329,15 +473,17 @@ func TestPostHandler_UpdatePost(t *testing.T) {
 			name:             "Invalid request - JSON parsing error",
 			userID:           "1",
 			postID:           "1",
+			ifMatch:          `"3"`,
 			postReq:          nil,
 			mockReturnErr:    nil,
 			expectedStatus:   http.StatusBadRequest,
 			expectUpdatePost: false,
 		},
 		{
-			name:   "Invalid request - validation error",
-			userID: "1",
-			postID: "1",
+			name:    "Invalid request - validation error",
+			userID:  "1",
+			postID:  "1",
+			ifMatch: `"3"`,
 			postReq: &model.PostRequest{
 				Title:   "",
 				Content: "",
@@ -347,9 +493,10 @@ func TestPostHandler_UpdatePost(t *testing.T) {
 			expectUpdatePost: false,
 		},
 		{
-			name:   "Error getting postID from query",
-			userID: "1",
-			postID: "",
+			name:    "Error getting postID from query",
+			userID:  "1",
+			postID:  "",
+			ifMatch: `"3"`,
 			postReq: &model.PostRequest{
 				Title:   "Title",
 				Content: "Content",
@@ -358,9 +505,10 @@ func TestPostHandler_UpdatePost(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:   "Error parsing postID",
-			userID: "1",
-			postID: "a",
+			name:    "Error parsing postID",
+			userID:  "1",
+			postID:  "a",
+			ifMatch: `"3"`,
 			postReq: &model.PostRequest{
 				Title:   "Title",
 				Content: "Content",
@@ -369,9 +517,45 @@ func TestPostHandler_UpdatePost(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 		},
 		{
-			name:   "User is not allowed to perform this operation",
-			userID: "1",
-			postID: "1",
+			name:    "Missing If-Match",
+			userID:  "1",
+			postID:  "1",
+			ifMatch: "",
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "Content",
+			},
+			expectedStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:    "Weak ETag",
+			userID:  "1",
+			postID:  "1",
+			ifMatch: `W/"3"`,
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "Content",
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:    "Post has been modified",
+			userID:  "1",
+			postID:  "1",
+			ifMatch: `"3"`,
+			postReq: &model.PostRequest{
+				Title:   "Title",
+				Content: "Content",
+			},
+			mockReturnErr:    service.ErrConflict,
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectUpdatePost: true,
+		},
+		{
+			name:    "User is not allowed to perform this operation",
+			userID:  "1",
+			postID:  "1",
+			ifMatch: `"3"`,
 			postReq: &model.PostRequest{
 				Title:   "Title",
 				Content: "Content",
@@ -381,9 +565,10 @@ func TestPostHandler_UpdatePost(t *testing.T) {
 			expectUpdatePost: true,
 		},
 		{
-			name:   "Post not found",
-			userID: "1",
-			postID: "1",
+			name:    "Post not found",
+			userID:  "1",
+			postID:  "1",
+			ifMatch: `"3"`,
 			postReq: &model.PostRequest{
 				Title:   "Title",
 				Content: "Content",
@@ -393,9 +578,10 @@ func TestPostHandler_UpdatePost(t *testing.T) {
 			expectUpdatePost: true,
 		},
 		{
-			name:   "Internal server error",
-			userID: "1",
-			postID: "1",
+			name:    "Internal server error",
+			userID:  "1",
+			postID:  "1",
+			ifMatch: `"3"`,
 			postReq: &model.PostRequest{
 				Title:   "Title",
 				Content: "Content",
@@ -416,12 +602,16 @@ func TestPostHandler_UpdatePost(t *testing.T) {
 			}
 
 			req := httptest.NewRequest("PUT", "/api/posts?id="+tt.postID, bytes.NewBuffer(body))
-			req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, tt.userID))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			ctx := context.WithValue(req.Context(), middleware.UIDKey, tt.userID)
+			req = req.WithContext(context.WithValue(ctx, middleware.RoleKey, model.RoleAuthor))
 			w := httptest.NewRecorder()
 
 			if tt.expectUpdatePost {
 				postID, _ := strconv.Atoi(tt.postID)
-				mockProcessor.On("UpdatePost", mock.Anything, postID, 1, tt.postReq).Return(tt.mockReturnErr).Once()
+				mockProcessor.On("UpdatePost", mock.Anything, postID, 3, 1, model.RoleAuthor, tt.postReq).Return(tt.mockReturnErr).Once()
 			}
 
 			handler := h.UpdatePost(context.Background())
@@ -448,6 +638,7 @@ func TestPostHandler_DeletePost(t *testing.T) {
 		name             string
 		userID           string
 		postID           string
+		ifMatch          string
 		mockReturnErr    error
 		expectedStatus   int
 		expectDeletePost bool
@@ -456,6 +647,7 @@ func TestPostHandler_DeletePost(t *testing.T) {
 			name:             "Success",
 			userID:           "1",
 			postID:           "1",
+			ifMatch:          `"3"`,
 			mockReturnErr:    nil,
 			expectedStatus:   http.StatusOK,
 			expectDeletePost: true,
@@ -464,6 +656,7 @@ func TestPostHandler_DeletePost(t *testing.T) {
 			name:           "Error getting postID from query",
 			userID:         "1",
 			postID:         "",
+			ifMatch:        `"3"`,
 			mockReturnErr:  nil,
 			expectedStatus: http.StatusBadRequest,
 		},
@@ -471,13 +664,37 @@ func TestPostHandler_DeletePost(t *testing.T) {
 			name:           "Error parsing postID",
 			userID:         "1",
 			postID:         "a",
+			ifMatch:        `"3"`,
 			mockReturnErr:  nil,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:           "Missing If-Match",
+			userID:         "1",
+			postID:         "1",
+			expectedStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:           "Invalid If-Match",
+			userID:         "1",
+			postID:         "1",
+			ifMatch:        `"three"`,
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:             "Post has been modified",
+			userID:           "1",
+			postID:           "1",
+			ifMatch:          `"3"`,
+			mockReturnErr:    service.ErrConflict,
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectDeletePost: true,
+		},
 		{
 			name:             "User is not allowed to perform this operation",
 			userID:           "1",
 			postID:           "1",
+			ifMatch:          `"3"`,
 			mockReturnErr:    service.ErrNotAllowed,
 			expectedStatus:   http.StatusForbidden,
 			expectDeletePost: true,
@@ -486,6 +703,7 @@ func TestPostHandler_DeletePost(t *testing.T) {
 			name:             "Post not found",
 			userID:           "1",
 			postID:           "1",
+			ifMatch:          `"3"`,
 			mockReturnErr:    service.ErrNotFound,
 			expectedStatus:   http.StatusNotFound,
 			expectDeletePost: true,
@@ -494,6 +712,7 @@ func TestPostHandler_DeletePost(t *testing.T) {
 			name:             "Internal server error",
 			userID:           "1",
 			postID:           "1",
+			ifMatch:          `"3"`,
 			mockReturnErr:    fmt.Errorf("internal server error"),
 			expectedStatus:   http.StatusInternalServerError,
 			expectDeletePost: true,
@@ -503,12 +722,16 @@ func TestPostHandler_DeletePost(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest("DELETE", "/api/posts?id="+tt.postID, nil)
-			req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, tt.userID))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			ctx := context.WithValue(req.Context(), middleware.UIDKey, tt.userID)
+			req = req.WithContext(context.WithValue(ctx, middleware.RoleKey, model.RoleAuthor))
 			w := httptest.NewRecorder()
 
 			if tt.expectDeletePost {
 				postID, _ := strconv.Atoi(tt.postID)
-				mockProcessor.On("DeletePost", mock.Anything, postID, 1).Return(tt.mockReturnErr).Once()
+				mockProcessor.On("DeletePost", mock.Anything, postID, 3, 1, model.RoleAuthor).Return(tt.mockReturnErr).Once()
 			}
 
 			handler := h.DeletePost(context.Background())
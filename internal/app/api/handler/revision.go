@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+)
+
+// @Summary List post revisions
+// @Description List the revisions of a post, the newest first. The revisions are open to the owner of the post and to moderators.
+// @Security ApiKeyAuth
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Success 200 {array} model.PostRevision
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "User is neither the owner of the post nor a moderator"
+// @Failure 404 {string} string "Post not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/posts/{id}/revisions [get]
+func (h *PostHandler) Revisions(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.Revisions"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		role := middleware.GetRoleFromCtx(r.Context())
+
+		postID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		revisions, err := h.provider.Revisions(ctx, postID, userID, role)
+		if err != nil {
+			if errors.Is(err, service.ErrNotAllowed) {
+				log.Warn("user is not allowed to perform this operation", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusForbidden)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("post not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error getting revisions", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(revisions) //nolint:errcheck
+	}
+}
+
+// @Summary Diff post revisions
+// @Description Get the unified diff between two revisions of a post. A revision is compared as its title, a blank line and its content.
+// @Security ApiKeyAuth
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param from query int true "Number of the old revision"
+// @Param to query int true "Number of the new revision"
+// @Success 200 {object} model.RevisionDiff
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "User is neither the owner of the post nor a moderator"
+// @Failure 404 {string} string "Post or revision not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/posts/{id}/revisions/diff [get]
+func (h *PostHandler) RevisionDiff(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.RevisionDiff"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		role := middleware.GetRoleFromCtx(r.Context())
+
+		postID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		from, err := strconv.Atoi(r.URL.Query().Get("from"))
+		if err != nil {
+			log.Warn("error parsing from", sl.Err(err))
+			http.Error(w, "invalid from revision: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		to, err := strconv.Atoi(r.URL.Query().Get("to"))
+		if err != nil {
+			log.Warn("error parsing to", sl.Err(err))
+			http.Error(w, "invalid to revision: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		revisionDiff, err := h.provider.RevisionDiff(ctx, postID, from, to, userID, role)
+		if err != nil {
+			if errors.Is(err, service.ErrNotAllowed) {
+				log.Warn("user is not allowed to perform this operation", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusForbidden)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("post or revision not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error diffing revisions", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(revisionDiff) //nolint:errcheck
+	}
+}
+
+// @Summary Restore a post revision
+// @Description Update a post back to the title and content of a revision, which is saved as a new revision. The tags and the status of the post are kept. Users may restore only their own posts, moderators may restore any post.
+// @Security ApiKeyAuth
+// @Tags posts
+// @Produce json
+// @Param id path int true "Post ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {string} string "Revision restored"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "User is neither the owner of the post nor a moderator"
+// @Failure 404 {string} string "Post or revision not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/posts/{id}/revisions/{rev}/restore [post]
+func (h *PostHandler) RestoreRevision(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.RestoreRevision"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		role := middleware.GetRoleFromCtx(r.Context())
+
+		postID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		number, err := strconv.Atoi(r.PathValue("rev"))
+		if err != nil {
+			log.Warn("error parsing revision", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		err = h.processor.RestoreRevision(ctx, postID, number, userID, role)
+		if err != nil {
+			if errors.Is(err, service.ErrNotAllowed) {
+				log.Warn("user is not allowed to perform this operation", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusForbidden)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("post or revision not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error restoring revision", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Revision restored")) //nolint:errcheck
+	}
+}
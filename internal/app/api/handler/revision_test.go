@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockPostProvider) Revisions(ctx context.Context, postID, userID int, role model.Role) ([]*model.PostRevision, error) {
+	args := m.Called(ctx, postID, userID, role)
+	revisions := args.Get(0)
+	if revisions == nil {
+		return nil, args.Error(1)
+	}
+	return revisions.([]*model.PostRevision), args.Error(1)
+}
+
+func (m *MockPostProvider) RevisionDiff(ctx context.Context, postID, from, to, userID int, role model.Role) (*model.RevisionDiff, error) {
+	args := m.Called(ctx, postID, from, to, userID, role)
+	revisionDiff := args.Get(0)
+	if revisionDiff == nil {
+		return nil, args.Error(1)
+	}
+	return revisionDiff.(*model.RevisionDiff), args.Error(1)
+}
+
+func (m *MockPostProcessor) RestoreRevision(ctx context.Context, postID, number, userID int, role model.Role) error {
+	args := m.Called(ctx, postID, number, userID, role)
+	return args.Error(0)
+}
+
+// withUser returns the request authenticated as user 1 with the role.
+func withUser(req *http.Request, role model.Role) *http.Request {
+	ctx := context.WithValue(req.Context(), middleware.UIDKey, "1")
+
+	return req.WithContext(context.WithValue(ctx, middleware.RoleKey, role))
+}
+
+func TestPostHandler_Revisions(t *testing.T) {
+	tests := []struct {
+		name            string
+		id              string
+		mockReturn      []*model.PostRevision
+		mockReturnErr   error
+		expectRevisions bool
+		expectedStatus  int
+	}{
+		{
+			name:            "Success",
+			id:              "1",
+			mockReturn:      []*model.PostRevision{{Number: 2, PostID: 1}, {Number: 1, PostID: 1}},
+			expectRevisions: true,
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:            "Not Allowed",
+			id:              "1",
+			mockReturnErr:   service.ErrNotAllowed,
+			expectRevisions: true,
+			expectedStatus:  http.StatusForbidden,
+		},
+		{
+			name:            "Post Not Found",
+			id:              "1",
+			mockReturnErr:   service.ErrNotFound,
+			expectRevisions: true,
+			expectedStatus:  http.StatusNotFound,
+		},
+		{
+			name:           "Invalid ID",
+			id:             "one",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockPostProvider)
+			h := &PostHandler{log: log, validate: validator.New(), provider: mockProvider}
+
+			if tt.expectRevisions {
+				mockProvider.On("Revisions", mock.Anything, 1, 1, model.RoleAuthor).Return(tt.mockReturn, tt.mockReturnErr)
+			}
+
+			req := withUser(httptest.NewRequest(http.MethodGet, "/api/posts/"+tt.id+"/revisions", nil), model.RoleAuthor)
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			h.Revisions(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.expectedStatus == http.StatusOK {
+				var got []*model.PostRevision
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+				assert.Len(t, got, len(tt.mockReturn))
+			}
+
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostHandler_RevisionDiff(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		expectDiff     bool
+		mockReturn     *model.RevisionDiff
+		mockReturnErr  error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			target:         "/api/posts/1/revisions/diff?from=1&to=2",
+			expectDiff:     true,
+			mockReturn:     &model.RevisionDiff{From: 1, To: 2, Diff: "--- revision 1\n+++ revision 2\n"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Revision Not Found",
+			target:         "/api/posts/1/revisions/diff?from=1&to=2",
+			expectDiff:     true,
+			mockReturnErr:  service.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Missing To",
+			target:         "/api/posts/1/revisions/diff?from=1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockPostProvider)
+			h := &PostHandler{log: log, validate: validator.New(), provider: mockProvider}
+
+			if tt.expectDiff {
+				mockProvider.On("RevisionDiff", mock.Anything, 1, 1, 2, 1, model.RoleModerator).Return(tt.mockReturn, tt.mockReturnErr)
+			}
+
+			req := withUser(httptest.NewRequest(http.MethodGet, tt.target, nil), model.RoleModerator)
+			req.SetPathValue("id", "1")
+			w := httptest.NewRecorder()
+
+			h.RevisionDiff(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			if tt.mockReturn != nil {
+				var got model.RevisionDiff
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+				assert.Equal(t, *tt.mockReturn, got)
+			}
+
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostHandler_RestoreRevision(t *testing.T) {
+	tests := []struct {
+		name           string
+		rev            string
+		expectRestore  bool
+		mockReturnErr  error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			rev:            "2",
+			expectRestore:  true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Not Allowed",
+			rev:            "2",
+			expectRestore:  true,
+			mockReturnErr:  service.ErrNotAllowed,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Revision Not Found",
+			rev:            "2",
+			expectRestore:  true,
+			mockReturnErr:  service.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid Revision",
+			rev:            "latest",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProcessor := new(MockPostProcessor)
+			h := &PostHandler{log: log, validate: validator.New(), processor: mockProcessor}
+
+			if tt.expectRestore {
+				mockProcessor.On("RestoreRevision", mock.Anything, 1, 2, 1, model.RoleAuthor).Return(tt.mockReturnErr)
+			}
+
+			req := withUser(httptest.NewRequest(http.MethodPost, "/api/posts/1/revisions/"+tt.rev+"/restore", nil), model.RoleAuthor)
+			req.SetPathValue("id", "1")
+			req.SetPathValue("rev", tt.rev)
+			w := httptest.NewRecorder()
+
+			h.RestoreRevision(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockProcessor.AssertExpectations(t)
+		})
+	}
+}
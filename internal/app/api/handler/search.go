@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+type Searcher interface {
+	Search(ctx context.Context, query *model.SearchQuery, after string) (*model.SearchPage, error)
+}
+
+type SearchHandler struct {
+	log      *slog.Logger
+	validate *validator.Validate
+	service  Searcher
+}
+
+// @Summary Search posts and comments
+// @Description Full-text search over posts and comments, best matches first. The query supports quoted phrases,
+// @Description "or" and "-" to exclude words. Snippets are HTML-escaped with the matches wrapped in <mark> tags.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search query" maxlength(200)
+// @Param type query string false "Kind of results, all by default" Enums(all, posts, comments)
+// @Param limit query int false "Page size, 20 by default" minimum(1) maximum(100)
+// @Param after query string false "Cursor of the previous page"
+// @Success 200 {object} model.SearchPage
+// @Header 200 {string} Link "Link to the next page"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/search [get]
+func (sh *SearchHandler) Search(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.Search"
+
+		log := sh.log.With(slog.String("operation", operation))
+
+		values := r.URL.Query()
+
+		query := &model.SearchQuery{
+			Text: strings.TrimSpace(values.Get("q")),
+			Type: model.SearchType(values.Get("type")),
+		}
+
+		if values.Has("limit") {
+			limit, err := strconv.Atoi(values.Get("limit"))
+			if err != nil || limit == 0 {
+				log.Warn("error parsing limit", sl.Err(err))
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+
+				return
+			}
+
+			query.Limit = limit
+		}
+
+		if err := sh.validate.Struct(query); err != nil {
+			log.Warn("error validating query", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		page, err := sh.service.Search(ctx, query, values.Get("after"))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidCursor) {
+				log.Warn("invalid cursor", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			log.Error("error searching", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		setNextLink(w, r, page.NextCursor)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			log.Error("error encoding search results", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSearcher struct{ mock.Mock }
+
+func (m *MockSearcher) Search(ctx context.Context, query *model.SearchQuery, after string) (*model.SearchPage, error) {
+	args := m.Called(ctx, query, after)
+	page := args.Get(0)
+	if page == nil {
+		return nil, args.Error(1)
+	}
+	return page.(*model.SearchPage), args.Error(1)
+}
+
+func TestSearchHandler_Search(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		wantQuery      *model.SearchQuery
+		wantAfter      string
+		mockReturnPage *model.SearchPage
+		mockReturnErr  error
+		expectedStatus int
+		expectedLink   string
+	}{
+		{
+			name:      "Success",
+			target:    "/api/search?q=golang&type=posts&limit=1",
+			wantQuery: &model.SearchQuery{Text: "golang", Type: model.SearchPosts, Limit: 1},
+			mockReturnPage: &model.SearchPage{
+				Results:    []*model.SearchResult{{Type: model.SearchResultPost, ID: 1, PostID: 1, Snippet: "<mark>golang</mark>"}},
+				NextCursor: "next",
+			},
+			expectedStatus: http.StatusOK,
+			expectedLink:   `</api/search?after=next&limit=1&q=golang&type=posts>; rel="next"`,
+		},
+		{
+			name:           "Missing Query",
+			target:         "/api/search?q=+",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Type",
+			target:         "/api/search?q=golang&type=users",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Limit",
+			target:         "/api/search?q=golang&limit=ten",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Cursor",
+			target:         "/api/search?q=golang&after=abc",
+			wantQuery:      &model.SearchQuery{Text: "golang"},
+			wantAfter:      "abc",
+			mockReturnErr:  service.ErrInvalidCursor,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Internal Server Error",
+			target:         "/api/search?q=golang",
+			wantQuery:      &model.SearchQuery{Text: "golang"},
+			mockReturnErr:  errors.New("internal server error"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockSearcher)
+			h := &SearchHandler{log: log, validate: validator.New(), service: mockService}
+
+			if tt.wantQuery != nil {
+				mockService.On("Search", mock.Anything, tt.wantQuery, tt.wantAfter).Return(tt.mockReturnPage, tt.mockReturnErr)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			w := httptest.NewRecorder()
+
+			h.Search(context.Background()).ServeHTTP(w, req)
+
+			resp := w.Result()
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tt.expectedLink, resp.Header.Get("Link"))
+
+			if tt.expectedStatus == http.StatusOK {
+				var page model.SearchPage
+				assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+				assert.Equal(t, tt.mockReturnPage, &page)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+)
+
+// @Summary List sessions
+// @Description List the devices the current user is logged in on, the session of the request is marked as current
+// @Security ApiKeyAuth
+// @Tags sessions
+// @Produce json
+// @Success 200 {array} model.Session
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Personal access tokens can not be used here"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/sessions [get]
+func (ah *AuthHandler) Sessions(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.Sessions"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+		sessionID := middleware.GetSessionIDFromCtx(r.Context())
+
+		sessions, err := ah.service.Sessions(ctx, userID, sessionID)
+		if err != nil {
+			log.Error("error getting sessions", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(sessions) //nolint:errcheck
+	}
+}
+
+// @Summary Revoke session
+// @Description Log out the current user on one device. Access tokens of the session are rejected right away and it can not be refreshed.
+// @Security ApiKeyAuth
+// @Tags sessions
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {string} string "Session revoked"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Personal access tokens can not be used here"
+// @Failure 404 {string} string "Session not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/sessions/{id} [delete]
+func (ah *AuthHandler) RevokeSession(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.RevokeSession"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		if err := ah.service.RevokeSession(ctx, userID, r.PathValue("id")); err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("session not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error revoking session", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Session revoked")) //nolint:errcheck
+	}
+}
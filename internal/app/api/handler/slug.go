@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+)
+
+// slugPrefix is the path of the posts looked up by slug.
+const slugPrefix = "/api/posts/by-slug/"
+
+// @Summary Get a post by slug
+// @Description Get a post by its slug. A former slug of a post, from before its title changed,
+// @Description redirects to the current one.
+// @Tags posts
+// @Produce json
+// @Param slug path string true "Post slug"
+// @Success 200 {object} model.Post
+// @Header 200 {string} ETag "Version of the post"
+// @Success 301 {string} string "Post has moved to another slug"
+// @Header 301 {string} Location "Path of the post by its current slug"
+// @Failure 404 {string} string "Post not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/posts/by-slug/{slug} [get]
+func (h *PostHandler) PostBySlug(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.PostBySlug"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		post, err := h.provider.PostBySlug(ctx, r.PathValue("slug"), middleware.GetUserIDFromCtx(r.Context()))
+		if err != nil {
+			var moved *service.MovedError
+			if errors.As(err, &moved) {
+				http.Redirect(w, r, slugPrefix+url.PathEscape(moved.Slug), http.StatusMovedPermanently)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("post not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error getting post", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag(post.Version))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(post) //nolint:errcheck
+	}
+}
+
+// routeSlugs serves the paths of posts by slug with the slugs mux and
+// everything else with next.
+//
+// A slug may be any word, so the slug route overlaps the {id} routes of
+// posts like /api/posts/{id}/comments, which a single ServeMux refuses
+// to register together. The ID of a post is never "by-slug", so the slug
+// route always takes the path.
+func routeSlugs(slugs, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, slugPrefix) {
+			slugs.ServeHTTP(w, r)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockPostProvider) PostBySlug(ctx context.Context, slug string, viewerID int) (*model.Post, error) {
+	args := m.Called(ctx, slug, viewerID)
+	post := args.Get(0)
+	if post == nil {
+		return nil, args.Error(1)
+	}
+	return post.(*model.Post), args.Error(1)
+}
+
+func TestPostHandler_PostBySlug(t *testing.T) {
+	tests := []struct {
+		name             string
+		slug             string
+		mockReturn       *model.Post
+		mockReturnErr    error
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			name:           "Success",
+			slug:           "hello-world",
+			mockReturn:     &model.Post{ID: 1, Slug: "hello-world", Version: 3},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:             "Former Slug",
+			slug:             "hello",
+			mockReturnErr:    fmt.Errorf("service.PostBySlug: %w", &service.MovedError{Slug: "hello-world"}),
+			expectedStatus:   http.StatusMovedPermanently,
+			expectedLocation: "/api/posts/by-slug/hello-world",
+		},
+		{
+			name:           "Post Not Found",
+			slug:           "missing",
+			mockReturnErr:  service.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Internal Server Error",
+			slug:           "hello-world",
+			mockReturnErr:  assert.AnError,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockPostProvider)
+			h := &PostHandler{log: log, validate: validator.New(), provider: mockProvider}
+
+			mockProvider.On("PostBySlug", mock.Anything, tt.slug, 1).Return(tt.mockReturn, tt.mockReturnErr)
+
+			req := withUser(httptest.NewRequest(http.MethodGet, slugPrefix+tt.slug, nil), model.RoleReader)
+			req.SetPathValue("slug", tt.slug)
+			w := httptest.NewRecorder()
+
+			h.PostBySlug(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, tt.expectedLocation, w.Header().Get("Location"))
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+			}
+
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRouteSlugs(t *testing.T) {
+	serve := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.PathValue("slug") + r.PathValue("id"))) //nolint:errcheck
+		})
+	}
+
+	slugs := http.NewServeMux()
+	slugs.Handle("GET "+slugPrefix+"{slug}", serve("slug"))
+
+	m := http.NewServeMux()
+	m.Handle("GET /api/posts/{id}/comments", serve("comments"))
+
+	router := routeSlugs(slugs, m)
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "/api/posts/by-slug/comments", want: "slug comments"},
+		{target: "/api/posts/by-slug/hello-world", want: "slug hello-world"},
+		{target: "/api/posts/1/comments", want: "comments 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, w.Body.String())
+		})
+	}
+}
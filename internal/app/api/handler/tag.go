@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+)
+
+// @Summary Get tags
+// @Description Get the tags in use with the number of their posts, the most used first
+// @Tags tags
+// @Produce json
+// @Success 200 {array} model.Tag
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/tags [get]
+func (h *PostHandler) Tags(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.Tags"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		tags, err := h.provider.Tags(ctx)
+		if err != nil {
+			log.Error("error getting tags", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(tags); err != nil {
+			log.Error("error encoding tags", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+	}
+}
+
+// @Summary Get posts with a tag
+// @Description Get a page of the posts with the tag, paginated and filtered like the post list
+// @Tags tags
+// @Produce json
+// @Param slug path string true "Tag slug"
+// @Param limit query int false "Page size, 20 by default" minimum(1) maximum(100)
+// @Param after query string false "Cursor of the previous page"
+// @Param sort query string false "Sort order, newest first by default" Enums(created, updated, comments)
+// @Param author query int false "Author ID"
+// @Param from query string false "Created at or after, RFC 3339 time or date"
+// @Param to query string false "Created before, RFC 3339 time or date"
+// @Success 200 {object} model.PostPage
+// @Header 200 {string} Link "Link to the next page"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Tag not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/tags/{slug}/posts [get]
+func (h *PostHandler) TagPosts(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.TagPosts"
+
+		log := h.log.With(slog.String("operation", operation))
+
+		query, err := parsePostQuery(r)
+		if err != nil {
+			log.Warn("error parsing query", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := h.validate.Struct(query); err != nil {
+			log.Warn("error validating query", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		page, err := h.provider.TagPosts(ctx, r.PathValue("slug"), query, r.URL.Query().Get("after"))
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("tag not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrInvalidCursor) || errors.Is(err, service.ErrInvalidTag) {
+				log.Warn("invalid query", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			log.Error("error getting posts", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		setNextLink(w, r, page.NextCursor)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+
+		if err := json.NewEncoder(w).Encode(page); err != nil {
+			log.Error("error encoding posts", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPostHandler_Tags(t *testing.T) {
+	mockProvider := new(MockPostProvider)
+	h := &PostHandler{log: log, validate: validator.New(), provider: mockProvider}
+
+	tags := []*model.Tag{{Slug: "golang", PostsCount: 3}}
+	mockProvider.On("Tags", mock.Anything).Return(tags, nil).Once()
+
+	w := httptest.NewRecorder()
+	h.Tags(context.Background()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tags", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var got []*model.Tag
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, tags, got)
+
+	mockProvider.On("Tags", mock.Anything).Return(nil, errors.New("error")).Once()
+
+	w = httptest.NewRecorder()
+	h.Tags(context.Background()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/tags", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestPostHandler_TagPosts(t *testing.T) {
+	tests := []struct {
+		name           string
+		slug           string
+		target         string
+		wantQuery      *model.PostQuery
+		mockReturnPage *model.PostPage
+		mockReturnErr  error
+		expectedStatus int
+	}{
+		{
+			name:           "Success",
+			slug:           "golang",
+			target:         "/api/tags/golang/posts?sort=updated",
+			wantQuery:      &model.PostQuery{Sort: model.PostSortUpdated},
+			mockReturnPage: &model.PostPage{Posts: []*model.Post{{ID: 1, Tags: []string{"golang"}}}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Tag Not Found",
+			slug:           "unused",
+			target:         "/api/tags/unused/posts",
+			wantQuery:      &model.PostQuery{},
+			mockReturnErr:  service.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid Limit",
+			slug:           "golang",
+			target:         "/api/tags/golang/posts?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockPostProvider)
+			h := &PostHandler{log: log, validate: validator.New(), provider: mockProvider}
+
+			if tt.wantQuery != nil {
+				mockProvider.On("TagPosts", mock.Anything, tt.slug, tt.wantQuery, "").Return(tt.mockReturnPage, tt.mockReturnErr)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.SetPathValue("slug", tt.slug)
+			w := httptest.NewRecorder()
+
+			h.TagPosts(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
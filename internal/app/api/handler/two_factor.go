@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// @Summary Finish login with the second factor
+// @Description Exchange the challenge token returned by login and a TOTP or recovery code for a token pair
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorLoginRequest true "Challenge token and code"
+// @Success 200 {object} model.TokenPair
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Invalid challenge token or code"
+// @Failure 429 {string} string "Too many failed attempts, see Retry-After"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/login/2fa [post]
+func (ah *AuthHandler) LoginTwoFactor(ctx context.Context, cfg config.Auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.LoginTwoFactor"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		var loginReq model.TwoFactorLoginRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&loginReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := ah.validate.Struct(loginReq); err != nil {
+			log.Warn("error validating request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		tokens, err := ah.service.LoginTwoFactor(ctx, cfg, loginReq.ChallengeToken, loginReq.Code, clientInfo(r))
+		if err != nil {
+			var locked *service.LockedError
+			if errors.As(err, &locked) {
+				log.Warn("login attempt while locked", sl.Err(err))
+				tooManyAttempts(w, locked)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrInvalidCode) {
+				log.Warn("second factor rejected", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+
+				return
+			}
+
+			log.Error("error logging in", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(tokens) //nolint:errcheck
+	}
+}
+
+// @Summary Enroll two-factor authentication
+// @Description Generate a new TOTP secret. The second factor is required only after the enrollment is confirmed.
+// @Security ApiKeyAuth
+// @Tags two-factor
+// @Produce json
+// @Success 200 {object} model.TwoFactorEnrollment
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "User not found"
+// @Failure 409 {string} string "Two-factor authentication is already enabled"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/2fa/enroll [post]
+func (ah *AuthHandler) EnrollTwoFactor(ctx context.Context, cfg config.Auth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.EnrollTwoFactor"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		enrollment, err := ah.service.EnrollTwoFactor(ctx, cfg, userID)
+		if err != nil {
+			if errors.Is(err, service.ErrTwoFactorEnabled) {
+				log.Warn("two-factor authentication is already enabled", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusConflict)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error enrolling two-factor authentication", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(enrollment) //nolint:errcheck
+	}
+}
+
+// @Summary Confirm two-factor authentication
+// @Description Enable the second factor with a code from the authenticator app. Returns recovery codes, they are shown only once.
+// @Security ApiKeyAuth
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} model.RecoveryCodes
+// @Failure 400 {string} string "Bad request or not enrolled"
+// @Failure 401 {string} string "Unauthorized or invalid code"
+// @Failure 409 {string} string "Two-factor authentication is already enabled"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/2fa/confirm [post]
+func (ah *AuthHandler) ConfirmTwoFactor(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.ConfirmTwoFactor"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		codeReq, ok := ah.decodeTwoFactorCode(w, r, log)
+		if !ok {
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		codes, err := ah.service.ConfirmTwoFactor(ctx, userID, codeReq.Code)
+		if err != nil {
+			ah.twoFactorError(w, log, err)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(model.RecoveryCodes{Codes: codes}) //nolint:errcheck
+	}
+}
+
+// @Summary Disable two-factor authentication
+// @Description Disable the second factor. Requires a TOTP or recovery code.
+// @Security ApiKeyAuth
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorCodeRequest true "TOTP or recovery code"
+// @Success 200 {string} string "Two-factor authentication disabled"
+// @Failure 400 {string} string "Bad request or not enabled"
+// @Failure 401 {string} string "Unauthorized or invalid code"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/2fa/disable [post]
+func (ah *AuthHandler) DisableTwoFactor(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.DisableTwoFactor"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		codeReq, ok := ah.decodeTwoFactorCode(w, r, log)
+		if !ok {
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		if err := ah.service.DisableTwoFactor(ctx, userID, codeReq.Code); err != nil {
+			ah.twoFactorError(w, log, err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Two-factor authentication disabled")) //nolint:errcheck
+	}
+}
+
+// @Summary Regenerate recovery codes
+// @Description Issue new recovery codes, the old ones stop working. Requires a TOTP code.
+// @Security ApiKeyAuth
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param request body model.TwoFactorCodeRequest true "TOTP code"
+// @Success 200 {object} model.RecoveryCodes
+// @Failure 400 {string} string "Bad request or not enabled"
+// @Failure 401 {string} string "Unauthorized or invalid code"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/2fa/recovery-codes [post]
+func (ah *AuthHandler) RegenerateRecoveryCodes(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.RegenerateRecoveryCodes"
+
+		log := ah.log.With(slog.String("operation", operation))
+
+		codeReq, ok := ah.decodeTwoFactorCode(w, r, log)
+		if !ok {
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		codes, err := ah.service.RegenerateRecoveryCodes(ctx, userID, codeReq.Code)
+		if err != nil {
+			ah.twoFactorError(w, log, err)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(model.RecoveryCodes{Codes: codes}) //nolint:errcheck
+	}
+}
+
+func (ah *AuthHandler) decodeTwoFactorCode(w http.ResponseWriter, r *http.Request, log *slog.Logger) (*model.TwoFactorCodeRequest, bool) {
+	var codeReq model.TwoFactorCodeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&codeReq); err != nil {
+		log.Warn("error parsing request", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return nil, false
+	}
+
+	if err := ah.validate.Struct(codeReq); err != nil {
+		log.Warn("error validating request", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return nil, false
+	}
+
+	return &codeReq, true
+}
+
+func (ah *AuthHandler) twoFactorError(w http.ResponseWriter, log *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCode):
+		log.Warn("invalid two-factor code", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrTwoFactorEnabled):
+		log.Warn("two-factor authentication is already enabled", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		log.Warn("two-factor authentication is not enabled", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrNotFound):
+		log.Warn("user not found", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Error("error processing two-factor request", sl.Err(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-playground/validator"
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+type User interface {
+	Profile(ctx context.Context, userID int) (*model.Profile, error)
+	Account(ctx context.Context, userID int) (*model.Account, error)
+	UpdateProfile(ctx context.Context, userID int, profileReq *model.ProfileRequest) (*model.Account, error)
+	UserPosts(ctx context.Context, userID, viewerID int) ([]*model.Post, error)
+	ExportAccount(ctx context.Context, userID int) (*model.AccountExport, error)
+}
+
+type UserHandler struct {
+	log      *slog.Logger
+	validate *validator.Validate
+	service  User
+}
+
+// @Summary Get user profile
+// @Description Get the public profile of a user with the number of posts
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.Profile
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/{id} [get]
+func (uh *UserHandler) UserProfile(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.UserProfile"
+
+		log := uh.log.With(slog.String("operation", operation))
+
+		userID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		profile, err := uh.service.Profile(ctx, userID)
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error getting profile", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(profile) //nolint:errcheck
+	}
+}
+
+// @Summary Get current user
+// @Description Get the profile of the current user with its email and role
+// @Security ApiKeyAuth
+// @Tags users
+// @Produce json
+// @Success 200 {object} model.Account
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/me [get]
+func (uh *UserHandler) CurrentUser(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.CurrentUser"
+
+		log := uh.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		account, err := uh.service.Account(ctx, userID)
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error getting account", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(account) //nolint:errcheck
+	}
+}
+
+// @Summary Update current user
+// @Description Update the username, display name, bio, website or avatar of the current user. Only the fields present in the request are changed, an empty string clears an optional field.
+// @Security ApiKeyAuth
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param profile body model.ProfileRequest true "Profile fields to change"
+// @Success 200 {object} model.Account
+// @Failure 400 {string} string "Invalid request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Personal access tokens can not be used here"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/me [patch]
+func (uh *UserHandler) UpdateCurrentUser(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.UpdateCurrentUser"
+
+		log := uh.log.With(slog.String("operation", operation))
+
+		var profileReq model.ProfileRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&profileReq); err != nil {
+			log.Warn("error parsing request", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if err := uh.validate.Struct(profileReq); err != nil {
+			log.Warn("error validating profile", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		account, err := uh.service.UpdateProfile(ctx, userID, &profileReq)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidUsername) {
+				log.Warn("invalid username", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusBadRequest)
+
+				return
+			}
+
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error updating profile", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(account) //nolint:errcheck
+	}
+}
+
+// @Summary Get posts of a user
+// @Description Get the posts of a user, newest first
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} model.Post
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/{id}/posts [get]
+func (uh *UserHandler) UserPosts(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.UserPosts"
+
+		log := uh.log.With(slog.String("operation", operation))
+
+		userID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			log.Warn("error parsing id", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		posts, err := uh.service.UserPosts(ctx, userID, middleware.GetUserIDFromCtx(r.Context()))
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error getting posts", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(posts) //nolint:errcheck
+	}
+}
+
+// @Summary Export account data
+// @Description Download a ZIP archive with the profile, posts and comments of the current user as JSON files
+// @Security ApiKeyAuth
+// @Tags users
+// @Produce application/zip
+// @Success 200 {file} file "account-export.zip"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Personal access tokens can not be used here"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/users/me/export [get]
+func (uh *UserHandler) ExportAccount(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const operation = "handler.ExportAccount"
+
+		log := uh.log.With(slog.String("operation", operation))
+
+		userID := middleware.GetUserIDFromCtx(r.Context())
+
+		export, err := uh.service.ExportAccount(ctx, userID)
+		if err != nil {
+			if errors.Is(err, service.ErrNotFound) {
+				log.Warn("user not found", sl.Err(err))
+				http.Error(w, err.Error(), http.StatusNotFound)
+
+				return
+			}
+
+			log.Error("error exporting account", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		archive, err := exportArchive(export)
+		if err != nil {
+			log.Error("error writing export archive", sl.Err(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="account-export.zip"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(archive) //nolint:errcheck
+	}
+}
+
+// exportArchive packs the exported data into a ZIP archive of JSON files.
+func exportArchive(export *model.AccountExport) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+	modified := time.Now()
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{name: "profile.json", data: export.Account},
+		{name: "posts.json", data: export.Posts},
+		{name: "comments.json", data: export.Comments},
+	}
+
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := json.NewEncoder(fw)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
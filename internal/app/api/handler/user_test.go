@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/markraiter/simple-blog/internal/app/api/middleware"
+	"github.com/markraiter/simple-blog/internal/app/service"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// mocks
+type MockUserService struct{ mock.Mock }
+
+func (m *MockUserService) Profile(ctx context.Context, userID int) (*model.Profile, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*model.Profile), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockUserService) Account(ctx context.Context, userID int) (*model.Account, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*model.Account), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockUserService) UpdateProfile(ctx context.Context, userID int, profileReq *model.ProfileRequest) (*model.Account, error) {
+	args := m.Called(ctx, userID, profileReq)
+	if args.Get(0) != nil {
+		return args.Get(0).(*model.Account), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockUserService) UserPosts(ctx context.Context, userID, viewerID int) ([]*model.Post, error) {
+	args := m.Called(ctx, userID, viewerID)
+	if args.Get(0) != nil {
+		return args.Get(0).([]*model.Post), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+func (m *MockUserService) ExportAccount(ctx context.Context, userID int) (*model.AccountExport, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*model.AccountExport), args.Error(1)
+	}
+
+	return nil, args.Error(1)
+}
+
+// tests
+func TestUserHandler_UserProfile(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		mock           func(mockService *MockUserService)
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			id:   "1",
+			mock: func(mockService *MockUserService) {
+				mockService.On("Profile", mock.Anything, 1).Return(&model.Profile{ID: 1, Username: "user"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid ID",
+			id:             "abc",
+			mock:           func(*MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Not Found",
+			id:   "2",
+			mock: func(mockService *MockUserService) {
+				mockService.On("Profile", mock.Anything, 2).Return(nil, service.ErrNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			h := &UserHandler{log: log, validate: validator.New(), service: mockService}
+
+			tt.mock(mockService)
+
+			req := httptest.NewRequest("GET", "/api/users/"+tt.id, nil)
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+
+			h.UserProfile(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.NotContains(t, w.Body.String(), "email")
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_UpdateCurrentUser(t *testing.T) {
+	validate := validator.New()
+	validate.RegisterValidation("weblink", model.ValidateWebLink, false) // nolint:errcheck
+
+	tests := []struct {
+		name           string
+		body           string
+		mock           func(mockService *MockUserService)
+		expectedStatus int
+	}{
+		{
+			name: "Success",
+			body: `{"bio": "Writes about Go", "website": ""}`,
+			mock: func(mockService *MockUserService) {
+				mockService.On("UpdateProfile", mock.Anything, 1, mock.MatchedBy(func(profileReq *model.ProfileRequest) bool {
+					return profileReq.Username == nil && *profileReq.Bio == "Writes about Go" && *profileReq.Website == ""
+				})).Return(&model.Account{Profile: model.Profile{ID: 1}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid JSON",
+			body:           `{`,
+			mock:           func(*MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Script Link",
+			body:           `{"website": "javascript:alert(1)"}`,
+			mock:           func(*MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Empty Username",
+			body:           `{"username": ""}`,
+			mock:           func(*MockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Username Too Short After Trimming",
+			body: `{"username": "  ab  "}`,
+			mock: func(mockService *MockUserService) {
+				mockService.On("UpdateProfile", mock.Anything, 1, mock.Anything).Return(nil, service.ErrInvalidUsername)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			h := &UserHandler{log: log, validate: validate, service: mockService}
+
+			tt.mock(mockService)
+
+			req := httptest.NewRequest("PATCH", "/api/users/me", bytes.NewBufferString(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, "1"))
+			w := httptest.NewRecorder()
+
+			h.UpdateCurrentUser(context.Background()).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserHandler_ExportAccount(t *testing.T) {
+	mockService := new(MockUserService)
+	h := &UserHandler{log: log, validate: validator.New(), service: mockService}
+
+	mockService.On("ExportAccount", mock.Anything, 1).Return(&model.AccountExport{
+		Account:  &model.Account{Profile: model.Profile{ID: 1, Username: "user"}, Email: "user@example.com"},
+		Posts:    []*model.Post{{ID: 3, UserID: 1}},
+		Comments: []*model.Comment{},
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/api/users/me/export", nil)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UIDKey, "1"))
+	w := httptest.NewRecorder()
+
+	h.ExportAccount(context.Background()).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+
+	assert.Equal(t, []string{"profile.json", "posts.json", "comments.json"}, names)
+	mockService.AssertExpectations(t)
+}
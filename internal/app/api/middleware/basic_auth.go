@@ -7,22 +7,43 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/service"
 	"github.com/markraiter/simple-blog/internal/lib/jwt"
 	"github.com/markraiter/simple-blog/internal/lib/sl"
+	"github.com/markraiter/simple-blog/internal/model"
 )
 
 type contextKey string
 
 const (
 	UIDKey           contextKey = "uid"
-	RefreshStringKey contextKey = "refreshString"
+	AccessTokenKey   contextKey = "accessToken"
 	EmailKey         contextKey = "email"
 	UsernameKey      contextKey = "username"
+	TokenIDKey       contextKey = "jti"
+	TokenExpKey      contextKey = "exp"
+	RoleKey          contextKey = "role"
+	ScopesKey        contextKey = "scopes"
+	SessionIDKey     contextKey = "sid"
 )
 
-func BasicAuth(cfg config.Auth, log *slog.Logger) func(http.Handler) http.Handler {
+// RevocationChecker tells whether a successfully parsed token has been revoked.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *jwt.TokenClaims) (bool, error)
+}
+
+// PersonalTokenAuthenticator resolves a personal access token to its owner.
+type PersonalTokenAuthenticator interface {
+	AuthenticatePersonalToken(ctx context.Context, token string) (*model.User, *model.PersonalAccessToken, error)
+}
+
+// BasicAuth accepts either a JWT access token or a personal access token.
+//
+// Personal access tokens put their scopes into the context, routes check
+// them with RequireScope.
+func BasicAuth(keys *jwt.KeySet, log *slog.Logger, revocations RevocationChecker, personalTokens PersonalTokenAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			const operation = "middleware.BasicAuth"
@@ -38,7 +59,47 @@ func BasicAuth(cfg config.Auth, log *slog.Logger) func(http.Handler) http.Handle
 			}
 			tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 
-			tokenClaims, err := jwt.ParseToken(tokenString, cfg.SigningKey)
+			if strings.HasPrefix(tokenString, model.PersonalTokenPrefix) {
+				user, pat, err := personalTokens.AuthenticatePersonalToken(r.Context(), tokenString)
+				if err != nil {
+					if errors.Is(err, service.ErrInvalidToken) {
+						l.Warn("invalid personal access token", sl.Err(err))
+						http.Error(w, "Invalid token", http.StatusUnauthorized)
+						return
+					}
+
+					l.Error("error checking personal access token", sl.Err(err))
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+
+				// A personal access token is treated like an access token issued
+				// at its creation, so logging out from all devices rejects it
+				// through the same cached cut-off.
+				revoked, err := revocations.IsRevoked(r.Context(), &jwt.TokenClaims{UID: strconv.Itoa(user.ID), IssuedAt: pat.CreatedAt})
+				if err != nil {
+					l.Error("error checking token revocation", sl.Err(err))
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+
+				if revoked {
+					l.Warn("personal access token revoked", slog.Int("uid", user.ID))
+					http.Error(w, "Token revoked", http.StatusUnauthorized)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), UIDKey, strconv.Itoa(user.ID))
+				ctx = context.WithValue(ctx, EmailKey, user.Email)
+				ctx = context.WithValue(ctx, UsernameKey, user.Username)
+				ctx = context.WithValue(ctx, RoleKey, user.Role)
+				ctx = context.WithValue(ctx, ScopesKey, pat.Scopes)
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			tokenClaims, err := jwt.ParseToken(tokenString, keys)
 			if err != nil {
 				if errors.Is(err, jwt.ErrTokenExpired) {
 					l.Warn("token expired", sl.Err(err))
@@ -51,10 +112,27 @@ func BasicAuth(cfg config.Auth, log *slog.Logger) func(http.Handler) http.Handle
 				return
 			}
 
+			revoked, err := revocations.IsRevoked(r.Context(), tokenClaims)
+			if err != nil {
+				l.Error("error checking token revocation", sl.Err(err))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			if revoked {
+				l.Warn("token revoked", slog.String("uid", tokenClaims.UID))
+				http.Error(w, "Token revoked", http.StatusUnauthorized)
+				return
+			}
+
 			ctx := context.WithValue(r.Context(), UIDKey, tokenClaims.UID)
-			ctx = context.WithValue(ctx, RefreshStringKey, tokenString)
+			ctx = context.WithValue(ctx, AccessTokenKey, tokenString)
 			ctx = context.WithValue(ctx, EmailKey, tokenClaims.Email)
 			ctx = context.WithValue(ctx, UsernameKey, tokenClaims.Username)
+			ctx = context.WithValue(ctx, TokenIDKey, tokenClaims.ID)
+			ctx = context.WithValue(ctx, TokenExpKey, tokenClaims.Exp)
+			ctx = context.WithValue(ctx, RoleKey, tokenClaims.Role)
+			ctx = context.WithValue(ctx, SessionIDKey, tokenClaims.SessionID)
 
 			// spew.Dump(ctx)
 
@@ -77,13 +155,13 @@ func GetUserIDFromCtx(ctx context.Context) int {
     return userID
 }
 
-func GetRefreshStringFromCtx(ctx context.Context) string {
-    refreshString, ok := ctx.Value(RefreshStringKey).(string)
+func GetAccessTokenFromCtx(ctx context.Context) string {
+    accessToken, ok := ctx.Value(AccessTokenKey).(string)
     if !ok {
         return ""
     }
 
-    return refreshString
+    return accessToken
 }
 
 func GetEmailFromCtx(ctx context.Context) string {
@@ -103,3 +181,52 @@ func GetUsernameFromCtx(ctx context.Context) string {
 
     return username
 }
+
+func GetTokenIDFromCtx(ctx context.Context) string {
+    tokenID, ok := ctx.Value(TokenIDKey).(string)
+    if !ok {
+        return ""
+    }
+
+    return tokenID
+}
+
+// GetSessionIDFromCtx returns the session of the access token the request
+// was made with, or an empty string if the token has none.
+func GetSessionIDFromCtx(ctx context.Context) string {
+    sessionID, ok := ctx.Value(SessionIDKey).(string)
+    if !ok {
+        return ""
+    }
+
+    return sessionID
+}
+
+func GetTokenExpFromCtx(ctx context.Context) time.Time {
+    exp, ok := ctx.Value(TokenExpKey).(int64)
+    if !ok {
+        return time.Time{}
+    }
+
+    return time.Unix(exp, 0)
+}
+
+func GetRoleFromCtx(ctx context.Context) model.Role {
+    role, ok := ctx.Value(RoleKey).(model.Role)
+    if !ok {
+        return ""
+    }
+
+    return role
+}
+
+// GetScopesFromCtx returns the scopes of the personal access token the
+// request was made with, or nil for a JWT access token.
+func GetScopesFromCtx(ctx context.Context) []model.Scope {
+    scopes, ok := ctx.Value(ScopesKey).([]model.Scope)
+    if !ok {
+        return nil
+    }
+
+    return scopes
+}
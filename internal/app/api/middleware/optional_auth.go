@@ -0,0 +1,21 @@
+package middleware
+
+import "net/http"
+
+// OptionalAuth authenticates requests with an Authorization header through
+// auth and lets the others through anonymously, so public routes can tell
+// who is asking. A header with an invalid token is still rejected.
+func OptionalAuth(auth func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := auth(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// RequireRole lets the request through only if the user has at least the min role.
+//
+// It relies on the role put into the context by BasicAuth, so it must be
+// applied after it.
+func RequireRole(min model.Role, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const operation = "middleware.RequireRole"
+
+			l := log.With(slog.String("operation", operation))
+
+			role := GetRoleFromCtx(r.Context())
+
+			if !role.AtLeast(min) {
+				l.Warn("insufficient role",
+					slog.String("role", string(role)),
+					slog.String("required", string(min)),
+				)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// RequireScope lets requests made with a personal access token through only
+// if the token was granted the scope. JWT access tokens are not limited.
+//
+// It must be applied after BasicAuth.
+func RequireScope(scope model.Scope, log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const operation = "middleware.RequireScope"
+
+			l := log.With(slog.String("operation", operation))
+
+			scopes := GetScopesFromCtx(r.Context())
+
+			if scopes != nil && !slices.Contains(scopes, scope) {
+				l.Warn("insufficient scope", slog.String("required", string(scope)))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests made with a personal access token.
+//
+// It guards account management, so a leaked token can not be used to
+// create more tokens or take over the account.
+func RequireSession(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const operation = "middleware.RequireSession"
+
+			l := log.With(slog.String("operation", operation))
+
+			if GetScopesFromCtx(r.Context()) != nil {
+				l.Warn("personal access token used for account management")
+				http.Error(w, "Personal access tokens can not be used here", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
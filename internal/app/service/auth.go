@@ -4,39 +4,114 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/markraiter/simple-blog/config"
 	"github.com/markraiter/simple-blog/internal/app/storage"
 	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
 	"github.com/markraiter/simple-blog/internal/model"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// refreshTokenSize is the number of random bytes in a refresh token.
+const refreshTokenSize = 32
+
 type UserSaver interface {
 	SaveUser(ctx context.Context, user *model.User) (int, error)
 }
 
 type UserProvider interface {
 	User(ctx context.Context, email string) (*model.User, error)
+	UserByID(ctx context.Context, id int) (*model.User, error)
+}
+
+type UserProcessor interface {
+	UpdateUserRole(ctx context.Context, userID int, role model.Role) error
+	UpdatePassword(ctx context.Context, userID int, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID int) error
+	UpdateEmail(ctx context.Context, userID int, email string) error
+	DeleteUser(ctx context.Context, userID int, policy model.DeletionPolicy) error
+}
+
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Compare(hash, password string) error
+	NeedsRehash(hash string) bool
+}
+
+type RefreshTokenSaver interface {
+	SaveRefreshToken(ctx context.Context, token *model.RefreshToken) error
+}
+
+type RefreshTokenProcessor interface {
+	RotateRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	RevokeRefreshTokenFamily(ctx context.Context, tokenHash string) error
+}
+
+type TokenRevoker interface {
+	RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID int) error
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error
 }
 
 type AuthService struct {
-	saver    UserSaver
-	provider UserProvider
+	saver          UserSaver
+	provider       UserProvider
+	processor      UserProcessor
+	tokenSaver     RefreshTokenSaver
+	tokenProcessor RefreshTokenProcessor
+	revoker        TokenRevoker
+	limiter        LoginLimiter
+	keys           *jwt.KeySet
+	hasher         PasswordHasher
+
+	dummyHashOnce sync.Once
+	dummyHash     string
+
+	sessionSaver    SessionSaver
+	sessionProvider SessionProvider
+
+	userTokenSaver     UserTokenSaver
+	userTokenProcessor UserTokenProcessor
+	mailer             mailer.Mailer
+
+	twoFactorProvider  TwoFactorProvider
+	twoFactorProcessor TwoFactorProcessor
+
+	personalTokenSaver     PersonalTokenSaver
+	personalTokenProvider  PersonalTokenProvider
+	personalTokenProcessor PersonalTokenProcessor
+
+	oidcClient       OIDCClient
+	identitySaver    UserIdentitySaver
+	identityProvider UserIdentityProvider
+
+	log *slog.Logger
 }
 
-func (as *AuthService) RegisterUser(ctx context.Context, user *model.UserRequest) (int, error) {
+// RegisterUser creates a new account and emails a link to verify the address.
+//
+// The account can log in right away, but can not publish until the address is verified.
+// The account is kept if the email can not be sent, the user can ask for
+// a new link with ResendVerification.
+func (as *AuthService) RegisterUser(ctx context.Context, cfg config.Auth, user *model.UserRequest) (int, error) {
 	const operation = "service.RegisterUser"
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	passHash, err := as.hasher.Hash(user.Password)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", operation, err)
 	}
 
 	userResp := model.User{
 		Username: user.Username,
-		Password: string(passHash),
+		Password: passHash,
 		Email:    user.Email,
+		Role:     model.RoleAuthor,
 	}
 
 	id, err := as.saver.SaveUser(ctx, &userResp)
@@ -48,29 +123,272 @@ func (as *AuthService) RegisterUser(ctx context.Context, user *model.UserRequest
 		return 0, fmt.Errorf("%s: %w", operation, err)
 	}
 
+	if err := as.sendVerification(ctx, cfg, &userResp); err != nil {
+		as.log.With(slog.String("operation", operation)).
+			Error("error sending verification email", slog.Int("user_id", id), sl.Err(err))
+	}
+
 	return id, nil
 }
 
-func (as *AuthService) Login(ctx context.Context, cfg config.Auth, email, password string) (string, error) {
+// Login checks user credentials and issues a new access/refresh token pair.
+//
+// Every login starts a new session. If the account has the
+// second factor enabled, only a short-lived challenge token is returned,
+// which has to be exchanged with LoginTwoFactor.
+//
+// An unknown email and a wrong password both return ErrInvalidCredentials.
+// Failed attempts are counted per account and per client IP, while they
+// are locked a *LockedError is returned.
+func (as *AuthService) Login(ctx context.Context, cfg config.Auth, email, password string, client model.ClientInfo) (*model.LoginResponse, error) {
 	const operation = "service.Login"
 
+	if wait := as.limiter.Check(email, client.IP); wait > 0 {
+		return nil, fmt.Errorf("%s: %w", operation, &LockedError{RetryAfter: wait})
+	}
+
 	user, err := as.provider.User(ctx, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
-			return "", fmt.Errorf("%s: %w", operation, ErrNotFound)
+			as.hasher.Compare(as.dummyPasswordHash(), password) //nolint:errcheck
+			as.limiter.Fail(email, client.IP)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidCredentials)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.hasher.Compare(user.Password, password); err != nil {
+		as.limiter.Fail(email, client.IP)
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidCredentials)
+	}
+
+	if as.hasher.NeedsRehash(user.Password) {
+		as.rehashPassword(ctx, user.ID, password)
+	}
+
+	resp, err := as.completeLogin(ctx, cfg, user, client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !resp.TwoFactorRequired {
+		as.limiter.Succeed(email)
+	}
+
+	return resp, nil
+}
+
+// rehashPassword upgrades the hash of a verified password to the current
+// algorithm and parameters. A failure does not fail the login, the upgrade
+// is tried again on the next one.
+func (as *AuthService) rehashPassword(ctx context.Context, userID int, password string) {
+	passHash, err := as.hasher.Hash(password)
+	if err != nil {
+		return
+	}
+
+	as.processor.UpdatePassword(ctx, userID, passHash) //nolint:errcheck
+}
+
+// dummyPasswordHash returns the hash compared against when the account does
+// not exist, so the response time does not reveal whether an email is registered.
+func (as *AuthService) dummyPasswordHash() string {
+	as.dummyHashOnce.Do(func() {
+		as.dummyHash, _ = as.hasher.Hash("dummy password")
+	})
+
+	return as.dummyHash
+}
+
+// completeLogin finishes the login of an authenticated user.
+//
+// Every login starts a new session. If the user has the second factor
+// enabled, only a challenge token is returned.
+func (as *AuthService) completeLogin(ctx context.Context, cfg config.Auth, user *model.User, client model.ClientInfo) (*model.LoginResponse, error) {
+	const operation = "service.completeLogin"
+
+	tf, err := as.twoFactor(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if tf.Enabled() {
+		challenge, err := jwt.NewChallengeToken(as.keys, user.ID, cfg.TwoFactorTTL)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		return &model.LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	sessionID, err := secret.Generate(16)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tokens, err := as.issueTokens(ctx, cfg, user, sessionID, client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &model.LoginResponse{TokenPair: tokens}, nil
+}
+
+// Refresh exchanges a refresh token for a new access/refresh token pair.
+//
+// The presented refresh token is rotated and can not be used again,
+// the session continues with the client details of the refresh.
+// If an already rotated token is presented, the whole token family is
+// revoked, because either the client or an attacker holds a stolen copy.
+func (as *AuthService) Refresh(ctx context.Context, cfg config.Auth, refreshToken string, client model.ClientInfo) (*model.TokenPair, error) {
+	const operation = "service.Refresh"
+
+	tokenHash := secret.Hash(refreshToken)
+
+	token, err := as.tokenProcessor.RotateRefreshToken(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenReused) {
+			if err := as.tokenProcessor.RevokeRefreshTokenFamily(ctx, tokenHash); err != nil {
+				return nil, fmt.Errorf("%s: %w", operation, err)
+			}
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrTokenReused)
+		}
+
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrTokenRevoked) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+	}
+
+	user, err := as.provider.UserByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tokens, err := as.issueTokens(ctx, cfg, user, token.FamilyID, client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return tokens, nil
+}
+
+// Logout ends the session the request was made with.
+//
+// Tokens issued before sessions were recorded have no session, for them
+// the access token is revoked, and if the refresh token is provided, its
+// whole family as well.
+func (as *AuthService) Logout(ctx context.Context, userID int, sessionID, jti string, expiresAt time.Time, refreshToken string) error {
+	const operation = "service.Logout"
+
+	if sessionID != "" {
+		err := as.revoker.RevokeSession(ctx, userID, sessionID)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	if jti != "" {
+		if err := as.revoker.RevokeToken(ctx, jti, userID, expiresAt); err != nil {
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	if refreshToken != "" {
+		if err := as.tokenProcessor.RevokeRefreshTokenFamily(ctx, secret.Hash(refreshToken)); err != nil {
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	return nil
+}
+
+// LogoutAll revokes every access and refresh token issued to the user.
+func (as *AuthService) LogoutAll(ctx context.Context, userID int) error {
+	const operation = "service.LogoutAll"
+
+	if err := as.revoker.RevokeUserTokens(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// SetUserRole changes the role of the user.
+//
+// Tokens of the user carry the old role, so all of them are revoked
+// and the user has to log in again.
+func (as *AuthService) SetUserRole(ctx context.Context, userID int, role model.Role) error {
+	const operation = "service.SetUserRole"
+
+	if err := as.processor.UpdateUserRole(ctx, userID, role); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
 		}
 
-		return "", fmt.Errorf("%s: %w", operation, err)
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.revoker.RevokeUserTokens(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", fmt.Errorf("%s: %w", operation, ErrInvalidCredentials)
+	return nil
+}
+
+// issueTokens records the session, signs a new access token for it and
+// stores a new refresh token of the family.
+//
+// The ID of the session is the family ID of its refresh tokens.
+func (as *AuthService) issueTokens(ctx context.Context, cfg config.Auth, user *model.User, familyID string, client model.ClientInfo) (*model.TokenPair, error) {
+	const operation = "service.issueTokens"
+
+	session := newSession(familyID, user.ID, client)
+	session.ExpiresAt = time.Now().Add(cfg.RefreshTTL)
+
+	if err := as.sessionSaver.SaveSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	accessToken, err := jwt.NewToken(as.keys, user, familyID, cfg.AccessTTL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	refreshToken, err := secret.Generate(refreshTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
-	token, err := jwt.NewToken(cfg, user, cfg.AccessTTL)
+	err = as.tokenSaver.SaveRefreshToken(ctx, &model.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: secret.Hash(refreshToken),
+		ExpiresAt: session.ExpiresAt,
+	})
 	if err != nil {
-		return "", fmt.Errorf("%s: %w", operation, err)
+		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
-	return token, nil
+	return &model.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
 }
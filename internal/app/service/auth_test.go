@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/hasher"
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mocks
+type MockUserSaver struct{ mock.Mock }
+
+func (m *MockUserSaver) SaveUser(ctx context.Context, user *model.User) (int, error) {
+	args := m.Called(ctx, user)
+	return args.Int(0), args.Error(1)
+}
+
+type MockUserProvider struct{ mock.Mock }
+
+func (m *MockUserProvider) User(ctx context.Context, email string) (*model.User, error) {
+	args := m.Called(ctx, email)
+	user := args.Get(0)
+	if user == nil {
+		return nil, args.Error(1)
+	}
+	return user.(*model.User), args.Error(1)
+}
+
+func (m *MockUserProvider) UserByID(ctx context.Context, id int) (*model.User, error) {
+	args := m.Called(ctx, id)
+	user := args.Get(0)
+	if user == nil {
+		return nil, args.Error(1)
+	}
+	return user.(*model.User), args.Error(1)
+}
+
+type MockRefreshTokenSaver struct{ mock.Mock }
+
+func (m *MockRefreshTokenSaver) SaveRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+type MockRefreshTokenProcessor struct{ mock.Mock }
+
+func (m *MockRefreshTokenProcessor) RotateRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash)
+	token := args.Get(0)
+	if token == nil {
+		return nil, args.Error(1)
+	}
+	return token.(*model.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenProcessor) RevokeRefreshTokenFamily(ctx context.Context, tokenHash string) error {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+
+func newTestKeySet(t *testing.T, cfg config.Auth) *jwt.KeySet {
+	t.Helper()
+
+	keys, err := jwt.NewKeySet(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keys
+}
+
+// Tests
+func TestAuthService_RegisterUser(t *testing.T) {
+	const operation = "service.RegisterUser"
+
+	cfg := config.Auth{EmailVerifyTTL: time.Hour, BaseURL: "https://example.com"}
+	req := &model.UserRequest{Username: "user", Email: "email@example.com", Password: "Password12345!"}
+
+	tests := []struct {
+		name    string
+		mock    func(mockSaver *MockUserSaver, mockTokenSaver *MockUserTokenSaver, mockMailer *MockMailer)
+		wantID  int
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func(mockSaver *MockUserSaver, mockTokenSaver *MockUserTokenSaver, mockMailer *MockMailer) {
+				mockSaver.On("SaveUser", mock.Anything, mock.AnythingOfType("*model.User")).
+					Run(func(args mock.Arguments) { args.Get(1).(*model.User).ID = 1 }).
+					Return(1, nil)
+				mockTokenSaver.On("SaveUserToken", mock.Anything, mock.MatchedBy(func(token *model.UserToken) bool {
+					return token.UserID == 1 && token.Purpose == model.TokenPurposeEmailVerification
+				})).Return(nil)
+				mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+					return msg.To == req.Email
+				})).Return(nil)
+			},
+			wantID: 1,
+		},
+		{
+			name: "Mailer Fails",
+			mock: func(mockSaver *MockUserSaver, mockTokenSaver *MockUserTokenSaver, mockMailer *MockMailer) {
+				mockSaver.On("SaveUser", mock.Anything, mock.AnythingOfType("*model.User")).
+					Run(func(args mock.Arguments) { args.Get(1).(*model.User).ID = 1 }).
+					Return(1, nil)
+				mockTokenSaver.On("SaveUserToken", mock.Anything, mock.AnythingOfType("*model.UserToken")).Return(nil)
+				mockMailer.On("Send", mock.Anything, mock.Anything).Return(errors.New("smtp unavailable"))
+			},
+			wantID: 1,
+		},
+		{
+			name: "Already Exists",
+			mock: func(mockSaver *MockUserSaver, _ *MockUserTokenSaver, _ *MockMailer) {
+				mockSaver.On("SaveUser", mock.Anything, mock.AnythingOfType("*model.User")).
+					Return(0, fmt.Errorf("storage.SaveUser: %w", storage.ErrAlreadyExists))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, ErrAlreadyExists),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSaver := new(MockUserSaver)
+			mockTokenSaver := new(MockUserTokenSaver)
+			mockMailer := new(MockMailer)
+			authService := &AuthService{
+				saver:          mockSaver,
+				hasher:         newTestHasher(t),
+				userTokenSaver: mockTokenSaver,
+				mailer:         mockMailer,
+				log:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+
+			tt.mock(mockSaver, mockTokenSaver, mockMailer)
+
+			id, err := authService.RegisterUser(context.Background(), cfg, req)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantID, id)
+			}
+
+			mockSaver.AssertExpectations(t)
+			mockTokenSaver.AssertExpectations(t)
+			mockMailer.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_Refresh(t *testing.T) {
+	const operation = "service.Refresh"
+	var err = errors.New("error")
+
+	cfg := config.Auth{
+		SigningKey: "testKey",
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	}
+
+	refreshToken := "refresh-token"
+	tokenHash := secret.Hash(refreshToken)
+	user := &model.User{ID: 1, Username: "testUser", Email: "test@test.com"}
+	client := model.ClientInfo{IP: "10.0.0.1", UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"}
+
+	tests := []struct {
+		name         string
+		rotateReturn *model.RefreshToken
+		rotateError  error
+		expectRevoke bool
+		expectIssue  bool
+		expectedErr  error
+	}{
+		{
+			name: "Success",
+			rotateReturn: &model.RefreshToken{
+				UserID:    1,
+				FamilyID:  "family",
+				ExpiresAt: time.Now().Add(time.Hour),
+			},
+			expectIssue: true,
+		},
+		{
+			name:         "Token reused",
+			rotateError:  storage.ErrTokenReused,
+			expectRevoke: true,
+			expectedErr:  fmt.Errorf("%s: %w", operation, ErrTokenReused),
+		},
+		{
+			name:        "Token not found",
+			rotateError: storage.ErrNotFound,
+			expectedErr: fmt.Errorf("%s: %w", operation, ErrInvalidToken),
+		},
+		{
+			name:        "Token revoked",
+			rotateError: storage.ErrTokenRevoked,
+			expectedErr: fmt.Errorf("%s: %w", operation, ErrInvalidToken),
+		},
+		{
+			name: "Token expired",
+			rotateReturn: &model.RefreshToken{
+				UserID:    1,
+				FamilyID:  "family",
+				ExpiresAt: time.Now().Add(-time.Hour),
+			},
+			expectedErr: fmt.Errorf("%s: %w", operation, ErrInvalidToken),
+		},
+		{
+			name:        "Error",
+			rotateError: err,
+			expectedErr: fmt.Errorf("%s: %w", operation, err),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockUserProvider)
+			mockSaver := new(MockRefreshTokenSaver)
+			mockProcessor := new(MockRefreshTokenProcessor)
+			mockSessions := new(MockSessionStorage)
+			authService := &AuthService{
+				provider:       mockProvider,
+				tokenSaver:     mockSaver,
+				tokenProcessor: mockProcessor,
+				sessionSaver:   mockSessions,
+				keys:           newTestKeySet(t, cfg),
+			}
+
+			mockProcessor.On("RotateRefreshToken", mock.Anything, tokenHash).Return(tt.rotateReturn, tt.rotateError)
+
+			if tt.expectRevoke {
+				mockProcessor.On("RevokeRefreshTokenFamily", mock.Anything, tokenHash).Return(nil)
+			}
+
+			if tt.expectIssue {
+				mockProvider.On("UserByID", mock.Anything, 1).Return(user, nil)
+				mockSessions.On("SaveSession", mock.Anything, mock.MatchedBy(func(session *model.Session) bool {
+					return session.ID == "family" && session.UserID == 1 && session.Device == "Firefox on Linux"
+				})).Return(nil)
+				mockSaver.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(token *model.RefreshToken) bool {
+					return token.UserID == 1 && token.FamilyID == "family" && token.TokenHash != tokenHash
+				})).Return(nil)
+			}
+
+			tokens, err := authService.Refresh(context.Background(), cfg, refreshToken, client)
+
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				assert.Nil(t, tokens)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, tokens.AccessToken)
+				assert.NotEqual(t, refreshToken, tokens.RefreshToken)
+			}
+
+			mockProvider.AssertExpectations(t)
+			mockSaver.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+			mockSessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_LoginRehash(t *testing.T) {
+	cfg := config.Auth{SigningKey: "testKey", AccessTTL: time.Minute, RefreshTTL: time.Hour}
+
+	argon2Hasher, err := hasher.New(config.Auth{PasswordHasher: hasher.AlgorithmArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1})
+	assert.NoError(t, err)
+
+	bcryptHash, err := newTestHasher(t).Hash("Password12345!")
+	assert.NoError(t, err)
+
+	argon2Hash, err := argon2Hasher.Hash("Password12345!")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		hash       string
+		wantRehash bool
+	}{
+		{name: "Outdated Algorithm", hash: bcryptHash, wantRehash: true},
+		{name: "Current Parameters", hash: argon2Hash, wantRehash: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &model.User{ID: 1, Username: "user", Email: "email@example.com", Password: tt.hash, Role: model.RoleAuthor}
+
+			mockProvider := new(MockUserProvider)
+			mockProcessor := new(MockUserProcessor)
+			mockTwoFactor := new(MockTwoFactorProvider)
+			mockTokenSaver := new(MockRefreshTokenSaver)
+			mockSessions := new(MockSessionStorage)
+			authService := &AuthService{
+				provider:          mockProvider,
+				processor:         mockProcessor,
+				twoFactorProvider: mockTwoFactor,
+				tokenSaver:        mockTokenSaver,
+				sessionSaver:      mockSessions,
+				limiter:           newTestLoginGuard(),
+				hasher:            argon2Hasher,
+				keys:              newTestKeySet(t, cfg),
+			}
+
+			mockProvider.On("User", mock.Anything, user.Email).Return(user, nil)
+			mockTwoFactor.On("TwoFactor", mock.Anything, user.ID).Return(&model.TwoFactor{}, nil)
+			mockSessions.On("SaveSession", mock.Anything, mock.AnythingOfType("*model.Session")).Return(nil)
+			mockTokenSaver.On("SaveRefreshToken", mock.Anything, mock.AnythingOfType("*model.RefreshToken")).Return(nil)
+
+			if tt.wantRehash {
+				mockProcessor.On("UpdatePassword", mock.Anything, user.ID, mock.MatchedBy(func(hash string) bool {
+					return argon2Hasher.Compare(hash, "Password12345!") == nil && !argon2Hasher.NeedsRehash(hash)
+				})).Return(nil)
+			}
+
+			_, err := authService.Login(context.Background(), cfg, user.Email, "Password12345!", model.ClientInfo{IP: "127.0.0.1"})
+			assert.NoError(t, err)
+
+			if tt.wantRehash {
+				mockProcessor.AssertExpectations(t)
+			} else {
+				mockProcessor.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+)
+
+type ExpiredTokenRemover interface {
+	DeleteExpiredTokens(ctx context.Context) (int, error)
+}
+
+// Cleaner removes the records of expired tokens, which would otherwise
+// stay in the database forever.
+type Cleaner struct {
+	remover  ExpiredTokenRemover
+	interval time.Duration
+	log      *slog.Logger
+}
+
+func NewCleaner(r ExpiredTokenRemover, cfg config.Scheduler, log *slog.Logger) *Cleaner {
+	return &Cleaner{
+		remover:  r,
+		interval: cfg.CleanupInterval,
+		log:      log,
+	}
+}
+
+// Run removes the expired tokens right away and then every interval
+// until the context is done.
+func (c *Cleaner) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.cleanup(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Cleaner) cleanup(ctx context.Context) {
+	const operation = "service.Cleaner.cleanup"
+
+	log := c.log.With(slog.String("operation", operation))
+
+	deleted, err := c.remover.DeleteExpiredTokens(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error("error deleting expired tokens", sl.Err(err))
+		}
+
+		return
+	}
+
+	if deleted > 0 {
+		log.Info("deleted expired tokens", slog.Int("count", deleted))
+	}
+}
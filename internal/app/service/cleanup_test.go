@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockExpiredTokenRemover struct{ mock.Mock }
+
+func (m *MockExpiredTokenRemover) DeleteExpiredTokens(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func TestCleaner_Run(t *testing.T) {
+	mockRemover := new(MockExpiredTokenRemover)
+	cleaner := NewCleaner(mockRemover, config.Scheduler{CleanupInterval: time.Millisecond}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// A failed run does not stop the cleaner, the next tick retries.
+	mockRemover.On("DeleteExpiredTokens", mock.Anything).Return(0, errors.New("error")).Once()
+	mockRemover.On("DeleteExpiredTokens", mock.Anything).Return(5, nil).Once()
+	mockRemover.On("DeleteExpiredTokens", mock.Anything).Return(0, nil).Run(func(mock.Arguments) { cancel() })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		cleaner.Run(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleaner did not stop after the context was cancelled")
+	}
+
+	mockRemover.AssertExpectations(t)
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/markraiter/simple-blog/internal/model"
 )
 
+// maxCommentDepth is the deepest level of replies, top-level comments have depth 0.
+const maxCommentDepth = 5
+
 type CommentSaver interface {
 	SaveComment(ctx context.Context, comment *model.Comment) (int, error)
 }
@@ -16,26 +19,50 @@ type CommentSaver interface {
 type CommentProvider interface {
 	Comment(ctx context.Context, id int) (*model.Comment, error)
 	CommentsByPost(ctx context.Context, postID int) ([]*model.Comment, error)
+	Post(ctx context.Context, id int) (*model.Post, error)
 }
 
 type CommentProcessor interface {
 	UpdateComment(ctx context.Context, comment *model.Comment) error
-	DeleteComment(ctx context.Context, commentID, userID int) error
+	DeleteComment(ctx context.Context, commentID, userID, version int) error
 }
 
 type CommentService struct {
 	saver     CommentSaver
 	provider  CommentProvider
 	processor CommentProcessor
+	verifier  UserVerifier
+	renderer  *renderCache
 }
 
+// SaveComment creates a new comment of the user.
+//
+// Users with unverified email addresses get ErrEmailNotVerified.
+// A reply must answer a comment of the same post which is not deleted,
+// otherwise ErrInvalidParent is returned. Replies deeper than
+// maxCommentDepth get ErrThreadTooDeep.
 func (s *CommentService) SaveComment(ctx context.Context, userID int, commentReq *model.CommentRequest) (int, error) {
 	const operation = "service.SaveComment"
 
+	if err := checkVerified(ctx, s.verifier, userID); err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
 	commentModel := model.Comment{
-		Content: commentReq.Content,
-		PostID:  commentReq.PostID,
-		UserID:  userID,
+		Content:  commentReq.Content,
+		Format:   contentFormat(commentReq.Format, ""),
+		PostID:   commentReq.PostID,
+		UserID:   userID,
+		ParentID: commentReq.ParentID,
+	}
+
+	if commentReq.ParentID != nil {
+		depth, err := s.replyDepth(ctx, commentReq.PostID, *commentReq.ParentID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		commentModel.Depth = depth
 	}
 
 	id, err := s.saver.SaveComment(ctx, &commentModel)
@@ -50,7 +77,11 @@ func (s *CommentService) SaveComment(ctx context.Context, userID int, commentReq
 	return id, nil
 }
 
-func (s *CommentService) Comment(ctx context.Context, id int) (*model.Comment, error) {
+// Comment returns the comment by its ID.
+//
+// Like their post, comments of posts that are not published are only
+// shown to the author of the post, for others they give ErrNotFound.
+func (s *CommentService) Comment(ctx context.Context, id, viewerID int) (*model.Comment, error) {
 	const operation = "service.Comment"
 
 	comment, err := s.provider.Comment(ctx, id)
@@ -62,12 +93,28 @@ func (s *CommentService) Comment(ctx context.Context, id int) (*model.Comment, e
 		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
+	if err := s.checkPostVisible(ctx, comment.PostID, viewerID); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := s.renderer.renderComments(comment); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
 	return comment, nil
 }
 
-func (s *CommentService) CommentsByPost(ctx context.Context, postID int) ([]*model.Comment, error) {
+// CommentsByPost returns the comments of the post, newest first.
+//
+// Like the post itself, the comments of a post that is not published are
+// only shown to its author, for others they give ErrNotFound.
+func (s *CommentService) CommentsByPost(ctx context.Context, postID, viewerID int) ([]*model.Comment, error) {
 	const operation = "service.CommentsByPost"
 
+	if err := s.checkPostVisible(ctx, postID, viewerID); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
 	comments, err := s.provider.CommentsByPost(ctx, postID)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
@@ -77,20 +124,47 @@ func (s *CommentService) CommentsByPost(ctx context.Context, postID int) ([]*mod
 		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
+	if err := s.renderer.renderComments(comments...); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
 	return comments, nil
 }
 
-func (s *CommentService) UpdateComment(ctx context.Context, commentID, userID int, commentReq *model.CommentRequest) error {
+// CommentTree returns the comments of the post nested into threads,
+// newest first on every level.
+func (s *CommentService) CommentTree(ctx context.Context, postID, viewerID int) ([]*model.Comment, error) {
+	const operation = "service.CommentTree"
+
+	comments, err := s.CommentsByPost(ctx, postID, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nestComments(comments), nil
+}
+
+// UpdateComment updates the comment on behalf of the user.
+//
+// Users may update only their own comments, moderators may update any comment.
+// The comment is only updated at the version, otherwise it returns ErrConflict.
+func (s *CommentService) UpdateComment(ctx context.Context, commentID, version, userID int, role model.Role, commentReq *model.CommentUpdateRequest) error {
 	const operation = "service.UpdateComment"
 
+	ownerID, err := s.ownerID(ctx, commentID, userID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
 	comentModel := model.Comment{
 		ID:      commentID,
 		Content: commentReq.Content,
-		PostID:  commentReq.PostID,
-		UserID:  userID,
+		Format:  commentReq.Format,
+		UserID:  ownerID,
+		Version: version,
 	}
 
-	err := s.processor.UpdateComment(ctx, &comentModel)
+	err = s.processor.UpdateComment(ctx, &comentModel)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return fmt.Errorf("%s: %w", operation, ErrNotFound)
@@ -100,16 +174,29 @@ func (s *CommentService) UpdateComment(ctx context.Context, commentID, userID in
 			return fmt.Errorf("%s: %w", operation, ErrNotAllowed)
 		}
 
+		if errors.Is(err, storage.ErrConflict) {
+			return fmt.Errorf("%s: %w", operation, ErrConflict)
+		}
+
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
 	return nil
 }
 
-func (s *CommentService) DeleteComment(ctx context.Context, commentID, userID int) error {
+// DeleteComment deletes the comment on behalf of the user.
+//
+// Users may delete only their own comments, moderators may delete any comment.
+// The comment is only deleted at the version, otherwise it returns ErrConflict.
+func (s *CommentService) DeleteComment(ctx context.Context, commentID, version, userID int, role model.Role) error {
 	const operation = "service.DeleteComment"
 
-	err := s.processor.DeleteComment(ctx, commentID, userID)
+	ownerID, err := s.ownerID(ctx, commentID, userID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = s.processor.DeleteComment(ctx, commentID, ownerID, version)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return fmt.Errorf("%s: %w", operation, ErrNotFound)
@@ -119,8 +206,101 @@ func (s *CommentService) DeleteComment(ctx context.Context, commentID, userID in
 			return fmt.Errorf("%s: %w", operation, ErrNotAllowed)
 		}
 
+		if errors.Is(err, storage.ErrConflict) {
+			return fmt.Errorf("%s: %w", operation, ErrConflict)
+		}
+
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
 	return nil
 }
+
+// ownerID returns the ID the storage checks the comment ownership against.
+//
+// For moderators it is the actual author of the comment, for everyone else
+// it is the user ID itself.
+func (s *CommentService) ownerID(ctx context.Context, commentID, userID int, role model.Role) (int, error) {
+	if !role.CanModerate() {
+		return userID, nil
+	}
+
+	comment, err := s.provider.Comment(ctx, commentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return 0, ErrNotFound
+		}
+
+		return 0, err
+	}
+
+	return comment.UserID, nil
+}
+
+// checkPostVisible returns ErrNotFound unless the post exists and the
+// viewer may see it.
+func (s *CommentService) checkPostVisible(ctx context.Context, postID, viewerID int) error {
+	post, err := s.provider.Post(ctx, postID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	if !visible(post, viewerID) {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// replyDepth returns the depth of a reply to the parent comment after
+// checking that the parent can be replied to.
+func (s *CommentService) replyDepth(ctx context.Context, postID, parentID int) (int, error) {
+	parent, err := s.provider.Comment(ctx, parentID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return 0, ErrInvalidParent
+		}
+
+		return 0, err
+	}
+
+	if parent.PostID != postID || parent.Deleted {
+		return 0, ErrInvalidParent
+	}
+
+	if parent.Depth >= maxCommentDepth {
+		return 0, ErrThreadTooDeep
+	}
+
+	return parent.Depth + 1, nil
+}
+
+// nestComments moves the replies into the Replies of their parents and
+// returns the top-level comments, keeping the order of the list.
+//
+// Replies whose parent is not in the list are returned as top-level comments.
+func nestComments(comments []*model.Comment) []*model.Comment {
+	byID := make(map[int]*model.Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	roots := make([]*model.Comment, 0)
+	for _, comment := range comments {
+		if comment.ParentID != nil {
+			if parent, ok := byID[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, comment)
+
+				continue
+			}
+		}
+
+		roots = append(roots, comment)
+	}
+
+	return roots
+}
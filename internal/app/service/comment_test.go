@@ -36,6 +36,15 @@ func (m *MockCommentProvider) CommentsByPost(ctx context.Context, postID int) ([
 	return args.Get(0).([]*model.Comment), args.Error(1)
 }
 
+func (m *MockCommentProvider) Post(ctx context.Context, id int) (*model.Post, error) {
+	args := m.Called(ctx, id)
+	post := args.Get(0)
+	if post == nil {
+		return nil, args.Error(1)
+	}
+	return post.(*model.Post), args.Error(1)
+}
+
 type MockCommentProcessor struct{ mock.Mock }
 
 func (m *MockCommentProcessor) UpdateComment(ctx context.Context, comment *model.Comment) error {
@@ -43,8 +52,8 @@ func (m *MockCommentProcessor) UpdateComment(ctx context.Context, comment *model
 	return args.Error(0)
 }
 
-func (m *MockCommentProcessor) DeleteComment(ctx context.Context, commentID, userID int) error {
-	args := m.Called(ctx, commentID, userID)
+func (m *MockCommentProcessor) DeleteComment(ctx context.Context, commentID, userID, version int) error {
+	args := m.Called(ctx, commentID, userID, version)
 	return args.Error(0)
 }
 
@@ -54,12 +63,14 @@ func TestCommentService_SaveComment(t *testing.T) {
 	var err = errors.New("error")
 
 	mockSaver := new(MockCommentSaver)
-	commentService := &CommentService{saver: mockSaver}
+	mockVerifier := new(MockUserVerifier)
+	commentService := &CommentService{saver: mockSaver, verifier: mockVerifier}
 
 	tests := []struct {
 		name       string
 		commentReq *model.CommentRequest
 		userID     int
+		verified   bool
 		mockReturn int
 		mockError  error
 		wantError  error
@@ -71,6 +82,7 @@ func TestCommentService_SaveComment(t *testing.T) {
 				PostID:  1,
 			},
 			userID:     1,
+			verified:   true,
 			mockReturn: 1,
 			mockError:  nil,
 			wantError:  nil,
@@ -82,19 +94,35 @@ func TestCommentService_SaveComment(t *testing.T) {
 				PostID:  1,
 			},
 			userID:     0,
+			verified:   true,
 			mockReturn: 0,
 			mockError:  err,
 			wantError:  fmt.Errorf("%s: %w", operation, err),
 		},
+		{
+			name: "Email Not Verified",
+			commentReq: &model.CommentRequest{
+				Content: "Test Content",
+				PostID:  1,
+			},
+			userID:    2,
+			verified:  false,
+			wantError: fmt.Errorf("%s: %w", operation, ErrEmailNotVerified),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockSaver.On("SaveComment", mock.Anything, &model.Comment{
-				Content: tt.commentReq.Content,
-				PostID:  tt.commentReq.PostID,
-				UserID:  tt.userID,
-			}).Return(tt.mockReturn, tt.mockError)
+			mockVerifier.On("EmailVerified", mock.Anything, tt.userID).Return(tt.verified, nil)
+
+			if tt.verified {
+				mockSaver.On("SaveComment", mock.Anything, &model.Comment{
+					Content: tt.commentReq.Content,
+					Format:  model.ContentFormatPlain,
+					PostID:  tt.commentReq.PostID,
+					UserID:  tt.userID,
+				}).Return(tt.mockReturn, tt.mockError)
+			}
 
 			_, err := commentService.SaveComment(context.Background(), tt.userID, tt.commentReq)
 
@@ -105,6 +133,7 @@ func TestCommentService_SaveComment(t *testing.T) {
 			}
 
 			mockSaver.AssertExpectations(t)
+			mockVerifier.AssertExpectations(t)
 		})
 	}
 }
@@ -113,14 +142,16 @@ func TestCommentService_Comment(t *testing.T) {
 	const operation = "service.Comment"
 	var err = errors.New("error")
 
-	mockProvider := new(MockCommentProvider)
-	commentService := &CommentService{provider: mockProvider}
+	published := &model.Post{ID: 1, UserID: 7, Status: model.PostStatusPublished}
+	draft := &model.Post{ID: 2, UserID: 7, Status: model.PostStatusDraft}
 
 	tests := []struct {
 		name        string
 		id          int
+		viewerID    int
 		mockReturn  *model.Comment
 		mockError   error
+		post        *model.Post
 		wantComment *model.Comment
 		wantError   error
 	}{
@@ -134,14 +165,37 @@ func TestCommentService_Comment(t *testing.T) {
 				UserID:  1,
 			},
 			mockError: nil,
+			post:      published,
 			wantComment: &model.Comment{
-				ID:      1,
-				Content: "Test Content",
-				PostID:  1,
-				UserID:  1,
+				ID:          1,
+				Content:     "Test Content",
+				ContentHTML: "<p>Test Content</p>\n",
+				PostID:      1,
+				UserID:      1,
 			},
 			wantError: nil,
 		},
+		{
+			name:       "Draft post of the viewer",
+			id:         4,
+			viewerID:   7,
+			mockReturn: &model.Comment{ID: 4, PostID: 2, UserID: 1},
+			post:       draft,
+			wantComment: &model.Comment{
+				ID:     4,
+				PostID: 2,
+				UserID: 1,
+			},
+		},
+		{
+			name:        "Draft post of another user",
+			id:          5,
+			viewerID:    8,
+			mockReturn:  &model.Comment{ID: 5, PostID: 2, UserID: 8},
+			post:        draft,
+			wantComment: &model.Comment{ID: 5, PostID: 2, UserID: 8},
+			wantError:   fmt.Errorf("%s: %w", operation, ErrNotFound),
+		},
 		{
 			name:        "Comment Not Found",
 			id:          2,
@@ -162,9 +216,15 @@ func TestCommentService_Comment(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockCommentProvider)
+			commentService := &CommentService{provider: mockProvider}
+
 			mockProvider.On("Comment", mock.Anything, tt.id).Return(tt.mockReturn, tt.mockError)
+			if tt.post != nil {
+				mockProvider.On("Post", mock.Anything, tt.post.ID).Return(tt.post, nil)
+			}
 
-			_, err := commentService.Comment(context.Background(), tt.id)
+			_, err := commentService.Comment(context.Background(), tt.id, tt.viewerID)
 
 			if tt.wantError != nil {
 				assert.EqualError(t, err, tt.wantError.Error())
@@ -182,13 +242,13 @@ func TestCommentService_CommentsByPost(t *testing.T) {
 	const operation = "service.CommentsByPost"
 	var err = errors.New("error")
 
-	mockProvider := new(MockCommentProvider)
-	commentService := &CommentService{provider: mockProvider}
-
 	tests := []struct {
 		name         string
 		ctx          context.Context
 		postID       int
+		viewerID     int
+		post         *model.Post
+		postError    error
 		mockReturn   []*model.Comment
 		mockError    error
 		wantComments []*model.Comment
@@ -198,16 +258,19 @@ func TestCommentService_CommentsByPost(t *testing.T) {
 			name:   "Success",
 			ctx:    context.Background(),
 			postID: 1,
+			post:   &model.Post{ID: 1, UserID: 7, Status: model.PostStatusPublished},
 			mockReturn: []*model.Comment{
 				{
 					ID:      1,
 					Content: "Test Content 1",
+					Format:  model.ContentFormatPlain,
 					PostID:  1,
 					UserID:  1,
 				},
 				{
 					ID:      2,
-					Content: "Test Content 2",
+					Content: "Test **Content** 2",
+					Format:  model.ContentFormatMarkdown,
 					PostID:  1,
 					UserID:  2,
 				},
@@ -215,16 +278,20 @@ func TestCommentService_CommentsByPost(t *testing.T) {
 			mockError: nil,
 			wantComments: []*model.Comment{
 				{
-					ID:      1,
-					Content: "Test Content 1",
-					PostID:  1,
-					UserID:  1,
+					ID:          1,
+					Content:     "Test Content 1",
+					Format:      model.ContentFormatPlain,
+					ContentHTML: "<p>Test Content 1</p>\n",
+					PostID:      1,
+					UserID:      1,
 				},
 				{
-					ID:      2,
-					Content: "Test Content 2",
-					PostID:  1,
-					UserID:  2,
+					ID:          2,
+					Content:     "Test **Content** 2",
+					Format:      model.ContentFormatMarkdown,
+					ContentHTML: "<p>Test <strong>Content</strong> 2</p>\n",
+					PostID:      1,
+					UserID:      2,
 				},
 			},
 			wantError: nil,
@@ -233,15 +300,36 @@ func TestCommentService_CommentsByPost(t *testing.T) {
 			name:         "Post not found",
 			ctx:          context.Background(),
 			postID:       0,
-			mockReturn:   nil,
-			mockError:    storage.ErrNotFound,
+			postError:    storage.ErrNotFound,
 			wantComments: nil,
 			wantError:    fmt.Errorf("%s: %w", operation, ErrNotFound),
 		},
+		{
+			name:      "Draft post of another user",
+			ctx:       context.Background(),
+			postID:    2,
+			viewerID:  8,
+			post:      &model.Post{ID: 2, UserID: 7, Status: model.PostStatusDraft},
+			wantError: fmt.Errorf("%s: %w", operation, ErrNotFound),
+		},
+		{
+			name:     "Draft post of the viewer",
+			ctx:      context.Background(),
+			postID:   2,
+			viewerID: 7,
+			post:     &model.Post{ID: 2, UserID: 7, Status: model.PostStatusDraft},
+			mockReturn: []*model.Comment{
+				{ID: 3, PostID: 2, UserID: 8},
+			},
+			wantComments: []*model.Comment{
+				{ID: 3, PostID: 2, UserID: 8},
+			},
+		},
 		{
 			name:         "Error",
 			ctx:          nil,
 			postID:       1,
+			post:         &model.Post{ID: 1, UserID: 7, Status: model.PostStatusPublished},
 			mockReturn:   nil,
 			mockError:    err,
 			wantComments: nil,
@@ -251,9 +339,15 @@ func TestCommentService_CommentsByPost(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockProvider.On("CommentsByPost", tt.ctx, tt.postID).Return(tt.mockReturn, tt.mockError)
+			mockProvider := new(MockCommentProvider)
+			commentService := &CommentService{provider: mockProvider}
+
+			mockProvider.On("Post", tt.ctx, tt.postID).Return(tt.post, tt.postError)
+			if tt.mockReturn != nil || tt.mockError != nil {
+				mockProvider.On("CommentsByPost", tt.ctx, tt.postID).Return(tt.mockReturn, tt.mockError)
+			}
 
-			_, err := commentService.CommentsByPost(tt.ctx, tt.postID)
+			_, err := commentService.CommentsByPost(tt.ctx, tt.postID, tt.viewerID)
 
 			if tt.wantError != nil {
 				assert.EqualError(t, err, tt.wantError.Error())
@@ -268,7 +362,10 @@ func TestCommentService_CommentsByPost(t *testing.T) {
 }
 
 func TestCommentService_UpdateComment(t *testing.T) {
-	const operation = "service.UpdateComment"
+	const (
+		operation = "service.UpdateComment"
+		version   = 3
+	)
 	var err = errors.New("error")
 
 	mockProcessor := new(MockCommentProcessor)
@@ -279,7 +376,7 @@ func TestCommentService_UpdateComment(t *testing.T) {
 		ctx        context.Context
 		commentID  int
 		userID     int
-		commentReq *model.CommentRequest
+		commentReq *model.CommentUpdateRequest
 		mockError  error
 		wantError  error
 	}{
@@ -288,9 +385,8 @@ func TestCommentService_UpdateComment(t *testing.T) {
 			ctx:       context.Background(),
 			commentID: 1,
 			userID:    1,
-			commentReq: &model.CommentRequest{
+			commentReq: &model.CommentUpdateRequest{
 				Content: "Test Content",
-				PostID:  1,
 			},
 			mockError: nil,
 			wantError: nil,
@@ -300,9 +396,8 @@ func TestCommentService_UpdateComment(t *testing.T) {
 			ctx:       context.Background(),
 			commentID: 2,
 			userID:    2,
-			commentReq: &model.CommentRequest{
+			commentReq: &model.CommentUpdateRequest{
 				Content: "Test Content",
-				PostID:  1,
 			},
 			mockError: storage.ErrNotFound,
 			wantError: fmt.Errorf("%s: %w", operation, ErrNotFound),
@@ -312,21 +407,30 @@ func TestCommentService_UpdateComment(t *testing.T) {
 			ctx:       context.Background(),
 			commentID: 1,
 			userID:    2,
-			commentReq: &model.CommentRequest{
+			commentReq: &model.CommentUpdateRequest{
 				Content: "Test Content",
-				PostID:  1,
 			},
 			mockError: storage.ErrNotAllowed,
 			wantError: fmt.Errorf("%s: %w", operation, ErrNotAllowed),
 		},
+		{
+			name:      "Conflict",
+			ctx:       context.Background(),
+			commentID: 3,
+			userID:    1,
+			commentReq: &model.CommentUpdateRequest{
+				Content: "Test Content",
+			},
+			mockError: storage.ErrConflict,
+			wantError: fmt.Errorf("%s: %w", operation, ErrConflict),
+		},
 		{
 			name:      "Error",
 			ctx:       nil,
 			commentID: 1,
 			userID:    1,
-			commentReq: &model.CommentRequest{
+			commentReq: &model.CommentUpdateRequest{
 				Content: "Test Content",
-				PostID:  1,
 			},
 			mockError: err,
 			wantError: fmt.Errorf("%s: %w", operation, err),
@@ -336,10 +440,11 @@ func TestCommentService_UpdateComment(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockProcessor.On("UpdateComment", tt.ctx, mock.MatchedBy(func(comment *model.Comment) bool {
-				return comment.ID == tt.commentID && comment.PostID == tt.commentReq.PostID && comment.UserID == tt.userID
+				return comment.ID == tt.commentID && comment.Content == tt.commentReq.Content && comment.UserID == tt.userID &&
+					comment.Version == version
 			})).Return(tt.mockError)
 
-			err := commentService.UpdateComment(tt.ctx, tt.commentID, tt.userID, tt.commentReq)
+			err := commentService.UpdateComment(tt.ctx, tt.commentID, version, tt.userID, model.RoleAuthor, tt.commentReq)
 
 			if tt.wantError != nil {
 				assert.EqualError(t, err, tt.wantError.Error())
@@ -353,7 +458,10 @@ func TestCommentService_UpdateComment(t *testing.T) {
 }
 
 func TestCommentService_DeleteComment(t *testing.T) {
-	const operation = "service.DeleteComment"
+	const (
+		operation = "service.DeleteComment"
+		version   = 3
+	)
 	var err = errors.New("error")
 
 	mockProcessor := new(MockCommentProcessor)
@@ -391,6 +499,14 @@ func TestCommentService_DeleteComment(t *testing.T) {
 			mockError: storage.ErrNotAllowed,
 			wantError: fmt.Errorf("%s: %w", operation, ErrNotAllowed),
 		},
+		{
+			name:      "Conflict",
+			ctx:       context.Background(),
+			commentID: 3,
+			userID:    1,
+			mockError: storage.ErrConflict,
+			wantError: fmt.Errorf("%s: %w", operation, ErrConflict),
+		},
 		{
 			name:      "Error",
 			ctx:       nil,
@@ -403,9 +519,9 @@ func TestCommentService_DeleteComment(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockProcessor.On("DeleteComment", tt.ctx, tt.commentID, tt.userID).Return(tt.mockError)
+			mockProcessor.On("DeleteComment", tt.ctx, tt.commentID, tt.userID, version).Return(tt.mockError)
 
-			err := commentService.DeleteComment(tt.ctx, tt.commentID, tt.userID)
+			err := commentService.DeleteComment(tt.ctx, tt.commentID, version, tt.userID, model.RoleAuthor)
 
 			if tt.wantError != nil {
 				assert.EqualError(t, err, tt.wantError.Error())
@@ -417,3 +533,159 @@ func TestCommentService_DeleteComment(t *testing.T) {
 		})
 	}
 }
+
+func TestCommentService_Moderation(t *testing.T) {
+	const (
+		commentID   = 1
+		authorID    = 1
+		moderatorID = 2
+	)
+
+	t.Run("Moderator updates comment of another user", func(t *testing.T) {
+		mockProvider := new(MockCommentProvider)
+		mockProcessor := new(MockCommentProcessor)
+		commentService := &CommentService{provider: mockProvider, processor: mockProcessor}
+
+		mockProvider.On("Comment", mock.Anything, commentID).Return(&model.Comment{ID: commentID, UserID: authorID}, nil)
+		mockProcessor.On("UpdateComment", mock.Anything, mock.MatchedBy(func(comment *model.Comment) bool {
+			return comment.ID == commentID && comment.UserID == authorID
+		})).Return(nil)
+
+		err := commentService.UpdateComment(context.Background(), commentID, 1, moderatorID, model.RoleModerator, &model.CommentUpdateRequest{
+			Content: "Test Content",
+		})
+		assert.NoError(t, err)
+
+		mockProvider.AssertExpectations(t)
+		mockProcessor.AssertExpectations(t)
+	})
+
+	t.Run("Moderator deletes comment of another user", func(t *testing.T) {
+		mockProvider := new(MockCommentProvider)
+		mockProcessor := new(MockCommentProcessor)
+		commentService := &CommentService{provider: mockProvider, processor: mockProcessor}
+
+		mockProvider.On("Comment", mock.Anything, commentID).Return(&model.Comment{ID: commentID, UserID: authorID}, nil)
+		mockProcessor.On("DeleteComment", mock.Anything, commentID, authorID, 1).Return(nil)
+
+		err := commentService.DeleteComment(context.Background(), commentID, 1, moderatorID, model.RoleModerator)
+		assert.NoError(t, err)
+
+		mockProvider.AssertExpectations(t)
+		mockProcessor.AssertExpectations(t)
+	})
+
+	t.Run("Author can not delete comment of another user", func(t *testing.T) {
+		mockProcessor := new(MockCommentProcessor)
+		commentService := &CommentService{processor: mockProcessor}
+
+		mockProcessor.On("DeleteComment", mock.Anything, commentID, moderatorID, 1).Return(storage.ErrNotAllowed)
+
+		err := commentService.DeleteComment(context.Background(), commentID, 1, moderatorID, model.RoleAuthor)
+		assert.ErrorIs(t, err, ErrNotAllowed)
+
+		mockProcessor.AssertExpectations(t)
+	})
+}
+
+func TestCommentService_SaveReply(t *testing.T) {
+	parentID := 10
+
+	tests := []struct {
+		name      string
+		parent    *model.Comment
+		parentErr error
+		wantDepth int
+		wantErr   error
+	}{
+		{
+			name:      "Success",
+			parent:    &model.Comment{ID: parentID, PostID: 1, Depth: 1},
+			wantDepth: 2,
+		},
+		{
+			name:      "Parent Not Found",
+			parentErr: storage.ErrNotFound,
+			wantErr:   ErrInvalidParent,
+		},
+		{
+			name:    "Parent In Another Post",
+			parent:  &model.Comment{ID: parentID, PostID: 2},
+			wantErr: ErrInvalidParent,
+		},
+		{
+			name:    "Deleted Parent",
+			parent:  &model.Comment{ID: parentID, PostID: 1, Deleted: true},
+			wantErr: ErrInvalidParent,
+		},
+		{
+			name:    "Too Deep",
+			parent:  &model.Comment{ID: parentID, PostID: 1, Depth: maxCommentDepth},
+			wantErr: ErrThreadTooDeep,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSaver := new(MockCommentSaver)
+			mockProvider := new(MockCommentProvider)
+			mockVerifier := new(MockUserVerifier)
+			commentService := &CommentService{saver: mockSaver, provider: mockProvider, verifier: mockVerifier}
+
+			mockVerifier.On("EmailVerified", mock.Anything, 1).Return(true, nil)
+			mockProvider.On("Comment", mock.Anything, parentID).Return(tt.parent, tt.parentErr)
+
+			if tt.wantErr == nil {
+				mockSaver.On("SaveComment", mock.Anything, &model.Comment{
+					Content:  "Test Content",
+					Format:   model.ContentFormatPlain,
+					PostID:   1,
+					UserID:   1,
+					ParentID: &parentID,
+					Depth:    tt.wantDepth,
+				}).Return(11, nil)
+			}
+
+			_, err := commentService.SaveComment(context.Background(), 1, &model.CommentRequest{
+				Content:  "Test Content",
+				PostID:   1,
+				ParentID: &parentID,
+			})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockSaver.AssertExpectations(t)
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCommentService_CommentTree(t *testing.T) {
+	mockProvider := new(MockCommentProvider)
+	commentService := &CommentService{provider: mockProvider}
+
+	one, two := 1, 2
+
+	mockProvider.On("Post", mock.Anything, 1).Return(&model.Post{ID: 1, Status: model.PostStatusPublished}, nil)
+	mockProvider.On("CommentsByPost", mock.Anything, 1).Return([]*model.Comment{
+		{ID: 4, PostID: 1, ParentID: &two, Depth: 2},
+		{ID: 3, PostID: 1},
+		{ID: 2, PostID: 1, ParentID: &one, Depth: 1},
+		{ID: 1, PostID: 1, Deleted: true},
+	}, nil)
+
+	tree, err := commentService.CommentTree(context.Background(), 1, 0)
+	assert.NoError(t, err)
+
+	assert.Len(t, tree, 2)
+	assert.Equal(t, 3, tree[0].ID)
+	assert.Equal(t, 1, tree[1].ID)
+	assert.Equal(t, 2, tree[1].Replies[0].ID)
+	assert.Equal(t, 4, tree[1].Replies[0].Replies[0].ID)
+
+	mockProvider.AssertExpectations(t)
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// ChangePassword sets a new password after checking the current one.
+//
+// Every other session of the user is ended, the session the request was
+// made with stays logged in. Wrong passwords count as failed logins.
+func (as *AuthService) ChangePassword(ctx context.Context, userID int, sessionID, ip string, req *model.ChangePasswordRequest) error {
+	const operation = "service.ChangePassword"
+
+	if _, err := as.reauthenticate(ctx, userID, ip, req.CurrentPassword); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	passHash, err := as.hasher.Hash(req.NewPassword)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.processor.UpdatePassword(ctx, userID, passHash); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.revoker.RevokeOtherSessions(ctx, userID, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// ChangeEmail emails a confirmation link to the new address after checking
+// the password. The address is switched only once the link is confirmed.
+func (as *AuthService) ChangeEmail(ctx context.Context, cfg config.Auth, userID int, ip string, req *model.ChangeEmailRequest) error {
+	const operation = "service.ChangeEmail"
+
+	user, err := as.reauthenticate(ctx, userID, ip, req.Password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+
+	if strings.EqualFold(newEmail, user.Email) {
+		return fmt.Errorf("%s: %w", operation, ErrAlreadyExists)
+	}
+
+	_, err = as.provider.User(ctx, newEmail)
+	if err == nil {
+		return fmt.Errorf("%s: %w", operation, ErrAlreadyExists)
+	}
+
+	if !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	token, err := as.issueUserToken(ctx, &model.UserToken{
+		UserID:   userID,
+		Purpose:  model.TokenPurposeEmailChange,
+		NewEmail: newEmail,
+	}, cfg.EmailVerifyTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	msg := mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nyou asked to use this address for your account. To confirm it follow the link below:\n\n%s\n\n"+
+				"The link is valid for %s and can be used once. Until then you keep using %s. "+
+				"If it was not you, just ignore this email.\n",
+			user.Username,
+			cfg.BaseURL+"/confirm-email?token="+url.QueryEscape(token),
+			cfg.EmailVerifyTTL,
+			user.Email,
+		),
+	}
+
+	if err := as.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// ConfirmEmailChange switches the email address of the user to the one
+// confirmed by the emailed token.
+//
+// The token has to be confirmed by the user who asked for the change.
+// Every other session of the user is ended afterwards.
+func (as *AuthService) ConfirmEmailChange(ctx context.Context, userID int, sessionID, token string) error {
+	const operation = "service.ConfirmEmailChange"
+
+	userToken, err := as.userTokenProcessor.ConsumeUserToken(ctx, model.TokenPurposeEmailChange, secret.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if userToken.UserID != userID || userToken.NewEmail == "" {
+		return fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+	}
+
+	if err := as.processor.UpdateEmail(ctx, userID, userToken.NewEmail); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return fmt.Errorf("%s: %w", operation, ErrAlreadyExists)
+		}
+
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.revoker.RevokeOtherSessions(ctx, userID, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// DeleteAccount deletes the account of the user after checking the password.
+//
+// All tokens of the user are revoked first, what happens to the posts and
+// comments is decided by the policy.
+func (as *AuthService) DeleteAccount(ctx context.Context, policy model.DeletionPolicy, userID int, ip, password string) error {
+	const operation = "service.DeleteAccount"
+
+	if _, err := as.reauthenticate(ctx, userID, ip, password); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.revoker.RevokeUserTokens(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.processor.DeleteUser(ctx, userID, policy); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// reauthenticate checks the password of a logged in user before
+// a sensitive change, applying the same lockout as the login.
+func (as *AuthService) reauthenticate(ctx context.Context, userID int, ip, password string) (*model.User, error) {
+	user, err := as.provider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	if wait := as.limiter.Check(user.Email, ip); wait > 0 {
+		return nil, &LockedError{RetryAfter: wait}
+	}
+
+	if err := as.hasher.Compare(user.Password, password); err != nil {
+		as.limiter.Fail(user.Email, ip)
+
+		return nil, ErrInvalidCredentials
+	}
+
+	as.limiter.Succeed(user.Email)
+
+	return user, nil
+}
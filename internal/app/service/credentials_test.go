@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	passHash, err := bcrypt.GenerateFromPassword([]byte("Password12345!"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	user := &model.User{ID: 1, Username: "user", Email: "user@example.com", Password: string(passHash)}
+
+	tests := []struct {
+		name            string
+		currentPassword string
+		wantErr         error
+	}{
+		{name: "Success", currentPassword: "Password12345!"},
+		{name: "Wrong Current Password", currentPassword: "Wrong12345!", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockUserProvider)
+			mockProcessor := new(MockUserProcessor)
+			mockRevoker := new(MockTokenRevoker)
+			authService := &AuthService{
+				provider:  mockProvider,
+				processor: mockProcessor,
+				revoker:   mockRevoker,
+				limiter:   newTestLoginGuard(),
+				hasher:    newTestHasher(t),
+			}
+
+			mockProvider.On("UserByID", mock.Anything, 1).Return(user, nil)
+
+			if tt.wantErr == nil {
+				mockProcessor.On("UpdatePassword", mock.Anything, 1, mock.MatchedBy(func(hash string) bool {
+					return bcrypt.CompareHashAndPassword([]byte(hash), []byte("NewPassword12345!")) == nil
+				})).Return(nil)
+				mockRevoker.On("RevokeOtherSessions", mock.Anything, 1, "current").Return(nil)
+			}
+
+			err := authService.ChangePassword(context.Background(), 1, "current", "10.0.0.1", &model.ChangePasswordRequest{
+				CurrentPassword: tt.currentPassword,
+				NewPassword:     "NewPassword12345!",
+			})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockProvider.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+			mockRevoker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_ChangePasswordLockout(t *testing.T) {
+	passHash, err := bcrypt.GenerateFromPassword([]byte("Password12345!"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	user := &model.User{ID: 1, Username: "user", Email: "user@example.com", Password: string(passHash)}
+
+	mockProvider := new(MockUserProvider)
+	authService := &AuthService{provider: mockProvider, limiter: newTestLoginGuard(), hasher: newTestHasher(t)}
+
+	mockProvider.On("UserByID", mock.Anything, 1).Return(user, nil)
+
+	wrong := &model.ChangePasswordRequest{CurrentPassword: "Wrong12345!", NewPassword: "NewPassword12345!"}
+
+	for range 3 {
+		err := authService.ChangePassword(context.Background(), 1, "current", "10.0.0.1", wrong)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	var locked *LockedError
+	err = authService.ChangePassword(context.Background(), 1, "current", "10.0.0.1", wrong)
+	assert.ErrorAs(t, err, &locked)
+}
+
+func TestAuthService_ChangeEmail(t *testing.T) {
+	cfg := config.Auth{BaseURL: "http://localhost:8080", EmailVerifyTTL: time.Hour}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte("Password12345!"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	user := &model.User{ID: 1, Username: "user", Email: "user@example.com", Password: string(passHash)}
+
+	tests := []struct {
+		name     string
+		newEmail string
+		mock     func(mockProvider *MockUserProvider, mockSaver *MockUserTokenSaver, mockMailer *MockMailer)
+		wantErr  error
+	}{
+		{
+			name:     "Success",
+			newEmail: "new@example.com",
+			mock: func(mockProvider *MockUserProvider, mockSaver *MockUserTokenSaver, mockMailer *MockMailer) {
+				mockProvider.On("User", mock.Anything, "new@example.com").Return(nil, storage.ErrNotFound)
+				mockSaver.On("SaveUserToken", mock.Anything, mock.MatchedBy(func(token *model.UserToken) bool {
+					return token.UserID == 1 && token.Purpose == model.TokenPurposeEmailChange && token.NewEmail == "new@example.com"
+				})).Return(nil)
+				mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+					return msg.To == "new@example.com"
+				})).Return(nil)
+			},
+		},
+		{
+			name:     "Same Email",
+			newEmail: "User@Example.com",
+			mock:     func(*MockUserProvider, *MockUserTokenSaver, *MockMailer) {},
+			wantErr:  ErrAlreadyExists,
+		},
+		{
+			name:     "Email Taken",
+			newEmail: "taken@example.com",
+			mock: func(mockProvider *MockUserProvider, mockSaver *MockUserTokenSaver, mockMailer *MockMailer) {
+				mockProvider.On("User", mock.Anything, "taken@example.com").Return(&model.User{ID: 2}, nil)
+			},
+			wantErr: ErrAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockUserProvider)
+			mockSaver := new(MockUserTokenSaver)
+			mockMailer := new(MockMailer)
+			authService := &AuthService{
+				provider:       mockProvider,
+				userTokenSaver: mockSaver,
+				mailer:         mockMailer,
+				limiter:        newTestLoginGuard(),
+				hasher:         newTestHasher(t),
+			}
+
+			mockProvider.On("UserByID", mock.Anything, 1).Return(user, nil)
+			tt.mock(mockProvider, mockSaver, mockMailer)
+
+			err := authService.ChangeEmail(context.Background(), cfg, 1, "10.0.0.1", &model.ChangeEmailRequest{
+				Password: "Password12345!",
+				NewEmail: tt.newEmail,
+			})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockProvider.AssertExpectations(t)
+			mockSaver.AssertExpectations(t)
+			mockMailer.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_ConfirmEmailChange(t *testing.T) {
+	tokenHash := secret.Hash("token")
+
+	tests := []struct {
+		name      string
+		userToken *model.UserToken
+		updateErr error
+		wantErr   error
+	}{
+		{
+			name:      "Success",
+			userToken: &model.UserToken{UserID: 1, NewEmail: "new@example.com"},
+		},
+		{
+			name:      "Token Of Another User",
+			userToken: &model.UserToken{UserID: 2, NewEmail: "new@example.com"},
+			wantErr:   ErrInvalidToken,
+		},
+		{
+			name:      "Email Taken Meanwhile",
+			userToken: &model.UserToken{UserID: 1, NewEmail: "new@example.com"},
+			updateErr: storage.ErrAlreadyExists,
+			wantErr:   ErrAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTokens := new(MockUserTokenProcessor)
+			mockProcessor := new(MockUserProcessor)
+			mockRevoker := new(MockTokenRevoker)
+			authService := &AuthService{
+				userTokenProcessor: mockTokens,
+				processor:          mockProcessor,
+				revoker:            mockRevoker,
+			}
+
+			mockTokens.On("ConsumeUserToken", mock.Anything, model.TokenPurposeEmailChange, tokenHash).Return(tt.userToken, nil)
+
+			if tt.userToken.UserID == 1 {
+				mockProcessor.On("UpdateEmail", mock.Anything, 1, "new@example.com").Return(tt.updateErr)
+			}
+
+			if tt.wantErr == nil {
+				mockRevoker.On("RevokeOtherSessions", mock.Anything, 1, "current").Return(nil)
+			}
+
+			err := authService.ConfirmEmailChange(context.Background(), 1, "current", "token")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockTokens.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+			mockRevoker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_DeleteAccount(t *testing.T) {
+	passHash, err := bcrypt.GenerateFromPassword([]byte("Password12345!"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	user := &model.User{ID: 1, Username: "user", Email: "user@example.com", Password: string(passHash)}
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "Success", password: "Password12345!"},
+		{name: "Wrong Password", password: "Wrong12345!", wantErr: ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockUserProvider)
+			mockProcessor := new(MockUserProcessor)
+			mockRevoker := new(MockTokenRevoker)
+			authService := &AuthService{
+				provider:  mockProvider,
+				processor: mockProcessor,
+				revoker:   mockRevoker,
+				limiter:   newTestLoginGuard(),
+				hasher:    newTestHasher(t),
+			}
+
+			mockProvider.On("UserByID", mock.Anything, 1).Return(user, nil)
+
+			if tt.wantErr == nil {
+				mockRevoker.On("RevokeUserTokens", mock.Anything, 1).Return(nil)
+				mockProcessor.On("DeleteUser", mock.Anything, 1, model.DeletionPolicyAnonymize).Return(nil)
+			}
+
+			err := authService.DeleteAccount(context.Background(), model.DeletionPolicyAnonymize, 1, "10.0.0.1", tt.password)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockProvider.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+			mockRevoker.AssertExpectations(t)
+		})
+	}
+}
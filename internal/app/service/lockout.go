@@ -0,0 +1,175 @@
+package service
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+)
+
+// logKeyLength is how many hex digits of the email hash identify a locked
+// account in the log.
+const logKeyLength = 12
+
+// LoginLimiter tracks failed logins and tells when to stop accepting attempts.
+type LoginLimiter interface {
+	Check(email, ip string) time.Duration
+	Fail(email, ip string)
+	Succeed(email string)
+}
+
+// LockedError is returned when login attempts are temporarily blocked.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LoginGuard counts failed logins per account and per client IP in memory
+// and locks further attempts with exponential backoff.
+//
+// The counters are kept per instance, so with several instances behind a
+// load balancer an attacker gets the allowance of each of them.
+type LoginGuard struct {
+	cfg config.Lockout
+	log *slog.Logger
+	now func() time.Time
+
+	mu       sync.Mutex
+	accounts map[string]*loginAttempts
+	ips      map[string]*loginAttempts
+	prunedAt time.Time
+}
+
+func NewLoginGuard(cfg config.Lockout, log *slog.Logger) *LoginGuard {
+	return &LoginGuard{
+		cfg:      cfg,
+		log:      log,
+		now:      time.Now,
+		accounts: make(map[string]*loginAttempts),
+		ips:      make(map[string]*loginAttempts),
+	}
+}
+
+// Check returns how long the login is still locked for the account or
+// the client IP, zero means the attempt is allowed.
+func (lg *LoginGuard) Check(email, ip string) time.Duration {
+	now := lg.now()
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	var wait time.Duration
+
+	for _, attempts := range []*loginAttempts{lg.accounts[normalizeEmail(email)], lg.ips[ip]} {
+		if attempts != nil && attempts.lockedUntil.After(now) {
+			wait = max(wait, attempts.lockedUntil.Sub(now))
+		}
+	}
+
+	return wait
+}
+
+// Fail records a failed attempt for both the account and the client IP.
+//
+// Unknown accounts are tracked as well, so the lockout does not reveal
+// whether an account exists.
+func (lg *LoginGuard) Fail(email, ip string) {
+	now := lg.now()
+
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+
+	lg.pruneLocked(now)
+
+	lg.failLocked(now, lg.accounts, "account", normalizeEmail(email), lg.cfg.MaxAccountAttempts)
+
+	if ip != "" {
+		lg.failLocked(now, lg.ips, "ip", ip, lg.cfg.MaxIPAttempts)
+	}
+}
+
+// Succeed forgets the failures of the account.
+//
+// The client IP keeps its failures, otherwise an attacker could reset the
+// counter by logging into an account of their own between the guesses.
+func (lg *LoginGuard) Succeed(email string) {
+	lg.mu.Lock()
+	delete(lg.accounts, normalizeEmail(email))
+	lg.mu.Unlock()
+}
+
+func (lg *LoginGuard) failLocked(now time.Time, entries map[string]*loginAttempts, scope, key string, limit int) {
+	attempts, ok := entries[key]
+	if !ok || now.Sub(attempts.lastFailure) > lg.cfg.Window {
+		attempts = &loginAttempts{}
+		entries[key] = attempts
+	}
+
+	attempts.failures++
+	attempts.lastFailure = now
+
+	if attempts.failures < limit {
+		return
+	}
+
+	delay := lg.cfg.BaseDelay
+	for i := limit; i < attempts.failures && delay < lg.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, lg.cfg.MaxDelay)
+	attempts.lockedUntil = now.Add(delay)
+
+	// The email is not logged, its hash is enough to tell the locks of
+	// different accounts apart.
+	logKey := key
+	if scope == "account" {
+		logKey = secret.Hash(key)[:logKeyLength]
+	}
+
+	lg.log.Warn("login locked",
+		slog.String("scope", scope),
+		slog.String("key", logKey),
+		slog.Int("failures", attempts.failures),
+		slog.Duration("duration", delay),
+	)
+}
+
+// pruneLocked drops counters which are neither locked nor recent enough to
+// be continued. It runs at most once per window.
+// The caller must hold the lock.
+func (lg *LoginGuard) pruneLocked(now time.Time) {
+	if now.Sub(lg.prunedAt) < lg.cfg.Window {
+		return
+	}
+
+	lg.prunedAt = now
+
+	for _, entries := range []map[string]*loginAttempts{lg.accounts, lg.ips} {
+		for key, attempts := range entries {
+			if now.Sub(attempts.lastFailure) > lg.cfg.Window && now.After(attempts.lockedUntil) {
+				delete(entries, key)
+			}
+		}
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/hasher"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestHasher returns a bcrypt hasher with the cost the test hashes are made with.
+func newTestHasher(t *testing.T) *hasher.Hasher {
+	h, err := hasher.New(config.Auth{PasswordHasher: hasher.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func newTestLoginGuard() *LoginGuard {
+	return NewLoginGuard(config.Lockout{
+		MaxAccountAttempts: 3,
+		MaxIPAttempts:      5,
+		BaseDelay:          time.Minute,
+		MaxDelay:           10 * time.Minute,
+		Window:             time.Hour,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestLoginGuard_LogsNoEmail(t *testing.T) {
+	var buf bytes.Buffer
+
+	guard := NewLoginGuard(config.Lockout{
+		MaxAccountAttempts: 1,
+		MaxIPAttempts:      1,
+		BaseDelay:          time.Minute,
+		MaxDelay:           time.Minute,
+		Window:             time.Hour,
+	}, slog.New(slog.NewTextHandler(&buf, nil)))
+
+	guard.Fail("Email@Example.com", "10.0.0.1")
+
+	assert.NotContains(t, buf.String(), "example.com")
+	assert.Contains(t, buf.String(), "scope=account key="+secret.Hash("email@example.com")[:logKeyLength])
+	assert.Contains(t, buf.String(), "scope=ip key=10.0.0.1")
+}
+
+func TestLoginGuard(t *testing.T) {
+	now := time.Now()
+
+	guard := newTestLoginGuard()
+	guard.now = func() time.Time { return now }
+
+	for range 2 {
+		guard.Fail("Email@Example.com", "10.0.0.1")
+	}
+
+	assert.Zero(t, guard.Check("email@example.com", "10.0.0.1"))
+
+	// The third failure locks the account for the base delay.
+	guard.Fail("email@example.com", "10.0.0.1")
+	assert.Equal(t, time.Minute, guard.Check("email@example.com", "10.0.0.2"))
+	assert.Zero(t, guard.Check("other@example.com", "10.0.0.1"))
+
+	// Every further failure doubles the delay up to the maximum.
+	guard.Fail("email@example.com", "10.0.0.2")
+	assert.Equal(t, 2*time.Minute, guard.Check("email@example.com", "10.0.0.2"))
+
+	for range 5 {
+		guard.Fail("email@example.com", "10.0.0.3")
+	}
+	assert.Equal(t, 10*time.Minute, guard.Check("email@example.com", "10.0.0.3"))
+
+	// The IP is locked on its own after too many failures across accounts.
+	assert.Equal(t, time.Minute, guard.Check("other@example.com", "10.0.0.3"))
+
+	// A successful login resets the account, but not the IP.
+	guard.Succeed("email@example.com")
+	assert.Zero(t, guard.Check("email@example.com", "10.0.0.1"))
+	assert.NotZero(t, guard.Check("email@example.com", "10.0.0.3"))
+
+	// The lock expires.
+	now = now.Add(2 * time.Minute)
+	assert.Zero(t, guard.Check("other@example.com", "10.0.0.3"))
+}
+
+func TestAuthService_LoginLockout(t *testing.T) {
+	cfg := config.Auth{SigningKey: "testKey", AccessTTL: time.Minute, RefreshTTL: time.Hour}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte("Password12345!"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	user := &model.User{ID: 1, Username: "user", Email: "email@example.com", Password: string(passHash), Role: model.RoleAuthor}
+
+	mockProvider := new(MockUserProvider)
+	authService := &AuthService{provider: mockProvider, limiter: newTestLoginGuard(), hasher: newTestHasher(t), keys: newTestKeySet(t, cfg)}
+
+	mockProvider.On("User", mock.Anything, user.Email).Return(user, nil)
+	mockProvider.On("User", mock.Anything, "unknown@example.com").Return(nil, storage.ErrNotFound)
+
+	// Unknown accounts and wrong passwords are not distinguishable.
+	_, unknownErr := authService.Login(context.Background(), cfg, "unknown@example.com", "Password12345!", model.ClientInfo{IP: "10.0.0.1"})
+	_, wrongErr := authService.Login(context.Background(), cfg, user.Email, "Wrong12345!", model.ClientInfo{IP: "10.0.0.1"})
+
+	assert.ErrorIs(t, unknownErr, ErrInvalidCredentials)
+	assert.ErrorIs(t, wrongErr, ErrInvalidCredentials)
+	assert.Equal(t, unknownErr.Error(), wrongErr.Error())
+
+	for range 2 {
+		_, err = authService.Login(context.Background(), cfg, user.Email, "Wrong12345!", model.ClientInfo{IP: "10.0.0.1"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// Even the right password is refused while the account is locked.
+	_, err = authService.Login(context.Background(), cfg, user.Email, "Password12345!", model.ClientInfo{IP: "10.0.0.2"})
+
+	var locked *LockedError
+	assert.ErrorAs(t, err, &locked)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Equal(t, time.Minute, locked.RetryAfter.Round(time.Second))
+
+	mockProvider.AssertNumberOfCalls(t, "User", 4)
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/markraiter/simple-blog/internal/lib/oidc"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// oidcStateSize is the number of random bytes in the state and the nonce.
+const oidcStateSize = 16
+
+// OIDCClient runs the authorization code flow with the identity provider.
+type OIDCClient interface {
+	AuthCodeURL(state, nonce, verifier string) string
+	Exchange(ctx context.Context, code, verifier, nonce string) (*oidc.Claims, error)
+}
+
+type UserIdentitySaver interface {
+	SaveUserIdentity(ctx context.Context, identity *model.UserIdentity) error
+	SaveUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) (int, error)
+}
+
+type UserIdentityProvider interface {
+	UserByIdentity(ctx context.Context, issuer, subject string) (*model.User, error)
+}
+
+// StartOIDCLogin returns the URL of the identity provider the user is
+// redirected to and the state token the browser keeps until the callback.
+//
+// The state token binds the callback to the browser which started the login.
+func (as *AuthService) StartOIDCLogin(cfg config.Auth) (string, string, error) {
+	const operation = "service.StartOIDCLogin"
+
+	if as.oidcClient == nil {
+		return "", "", fmt.Errorf("%s: %w", operation, ErrOIDCDisabled)
+	}
+
+	state, err := secret.Generate(oidcStateSize)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	nonce, err := secret.Generate(oidcStateSize)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	stateToken, err := jwt.NewOIDCStateToken(as.keys, jwt.OIDCState{State: state, Nonce: nonce, Verifier: verifier}, cfg.OIDCStateTTL)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return as.oidcClient.AuthCodeURL(state, nonce, verifier), stateToken, nil
+}
+
+// FinishOIDCLogin redeems the authorization code and logs in the user
+// linked to the account at the identity provider.
+//
+// On the first login the account is linked to the user with the same
+// email, or a new user is created. Both require the provider to have
+// verified the email. Like Login, it returns a challenge token instead
+// of tokens if the user has the second factor enabled.
+func (as *AuthService) FinishOIDCLogin(ctx context.Context, cfg config.Auth, stateToken, state, code string, client model.ClientInfo) (*model.LoginResponse, error) {
+	const operation = "service.FinishOIDCLogin"
+
+	if as.oidcClient == nil {
+		return nil, fmt.Errorf("%s: %w", operation, ErrOIDCDisabled)
+	}
+
+	saved, err := jwt.ParseOIDCStateToken(stateToken, as.keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(saved.State), []byte(state)) != 1 {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+	}
+
+	claims, err := as.oidcClient.Exchange(ctx, code, saved.Verifier, saved.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrExchange) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			return nil, fmt.Errorf("%s: %w: %w", operation, ErrInvalidCredentials, err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	user, err := as.userByIdentity(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	resp, err := as.completeLogin(ctx, cfg, user, client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return resp, nil
+}
+
+// userByIdentity returns the user linked to the account at the identity
+// provider, linking or creating one on the first login.
+//
+// An existing user is linked only if the email is verified on both sides,
+// otherwise whoever registered the address first could take over the
+// account of its real owner.
+func (as *AuthService) userByIdentity(ctx context.Context, claims *oidc.Claims) (*model.User, error) {
+	const operation = "service.userByIdentity"
+
+	user, err := as.identityProvider.UserByIdentity(ctx, claims.Issuer, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, fmt.Errorf("%s: %w", operation, ErrEmailNotVerified)
+	}
+
+	identity := &model.UserIdentity{Issuer: claims.Issuer, Subject: claims.Subject, Email: claims.Email}
+
+	user, err = as.provider.User(ctx, claims.Email)
+	if err == nil {
+		if user.EmailVerifiedAt == nil {
+			return nil, fmt.Errorf("%s: %w", operation, ErrAlreadyExists)
+		}
+
+		identity.UserID = user.ID
+
+		if err := as.identitySaver.SaveUserIdentity(ctx, identity); err != nil {
+			if errors.Is(err, storage.ErrAlreadyExists) {
+				return nil, fmt.Errorf("%s: %w", operation, ErrAlreadyExists)
+			}
+
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		return user, nil
+	}
+
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	// Nobody knows the password of the new user, one can be set with
+	// the password reset.
+	password, err := secret.Generate(refreshTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	passHash, err := as.hasher.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	verifiedAt := time.Now()
+
+	user = &model.User{
+		Username:        identityUsername(claims),
+		Password:        passHash,
+		Email:           claims.Email,
+		Role:            model.RoleAuthor,
+		EmailVerifiedAt: &verifiedAt,
+	}
+
+	if _, err := as.identitySaver.SaveUserWithIdentity(ctx, user, identity); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrAlreadyExists)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return user, nil
+}
+
+// identityUsername picks the username of a new user from the claims.
+func identityUsername(claims *oidc.Claims) string {
+	local, _, _ := strings.Cut(claims.Email, "@")
+
+	for _, candidate := range []string{claims.PreferredUsername, claims.Name, local} {
+		candidate = strings.TrimSpace(candidate)
+
+		if utf8.RuneCountInString(candidate) < 3 {
+			continue
+		}
+
+		if runes := []rune(candidate); len(runes) > 50 {
+			candidate = string(runes[:50])
+		}
+
+		return candidate
+	}
+
+	return "user"
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/oidc"
+	"github.com/markraiter/simple-blog/internal/lib/oidc/oidctest"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserIdentityStorage struct{ mock.Mock }
+
+func (m *MockUserIdentityStorage) SaveUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *MockUserIdentityStorage) SaveUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) (int, error) {
+	args := m.Called(ctx, user, identity)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserIdentityStorage) UserByIdentity(ctx context.Context, issuer, subject string) (*model.User, error) {
+	args := m.Called(ctx, issuer, subject)
+	user := args.Get(0)
+	if user == nil {
+		return nil, args.Error(1)
+	}
+	return user.(*model.User), args.Error(1)
+}
+
+func TestAuthService_OIDCLogin(t *testing.T) {
+	cfg := config.Auth{SigningKey: "testKey", AccessTTL: time.Minute, RefreshTTL: time.Hour, OIDCStateTTL: time.Minute}
+
+	verifiedAt := time.Now()
+	verified := &model.User{ID: 1, Username: "user", Email: "user@example.com", Role: model.RoleAuthor, EmailVerifiedAt: &verifiedAt}
+	unverified := &model.User{ID: 1, Username: "user", Email: "user@example.com", Role: model.RoleAuthor}
+
+	identity := oidc.Claims{Subject: "42", Email: "user@example.com", EmailVerified: true, PreferredUsername: "idp-user"}
+
+	tests := []struct {
+		name       string
+		claims     oidc.Claims
+		wrongState bool
+		mock       func(issuer string, users *MockUserProvider, identities *MockUserIdentityStorage)
+		wantUserID int
+		wantErr    error
+	}{
+		{
+			name:   "Linked Identity",
+			claims: identity,
+			mock: func(issuer string, users *MockUserProvider, identities *MockUserIdentityStorage) {
+				identities.On("UserByIdentity", mock.Anything, issuer, "42").Return(verified, nil)
+			},
+			wantUserID: 1,
+		},
+		{
+			name:   "Link By Verified Email",
+			claims: identity,
+			mock: func(issuer string, users *MockUserProvider, identities *MockUserIdentityStorage) {
+				identities.On("UserByIdentity", mock.Anything, issuer, "42").Return(nil, storage.ErrNotFound)
+				users.On("User", mock.Anything, "user@example.com").Return(verified, nil)
+				identities.On("SaveUserIdentity", mock.Anything, &model.UserIdentity{UserID: 1, Issuer: issuer, Subject: "42", Email: "user@example.com"}).Return(nil)
+			},
+			wantUserID: 1,
+		},
+		{
+			name:   "Create User",
+			claims: identity,
+			mock: func(issuer string, users *MockUserProvider, identities *MockUserIdentityStorage) {
+				identities.On("UserByIdentity", mock.Anything, issuer, "42").Return(nil, storage.ErrNotFound)
+				users.On("User", mock.Anything, "user@example.com").Return(nil, storage.ErrNotFound)
+				identities.On("SaveUserWithIdentity", mock.Anything,
+					mock.MatchedBy(func(user *model.User) bool {
+						return user.Username == "idp-user" && user.Email == "user@example.com" && user.EmailVerifiedAt != nil && user.Password != ""
+					}),
+					&model.UserIdentity{Issuer: issuer, Subject: "42", Email: "user@example.com"},
+				).Run(func(args mock.Arguments) {
+					args.Get(1).(*model.User).ID = 5
+				}).Return(5, nil)
+			},
+			wantUserID: 5,
+		},
+		{
+			name:   "Email Not Verified By Provider",
+			claims: oidc.Claims{Subject: "42", Email: "user@example.com"},
+			mock: func(issuer string, users *MockUserProvider, identities *MockUserIdentityStorage) {
+				identities.On("UserByIdentity", mock.Anything, issuer, "42").Return(nil, storage.ErrNotFound)
+			},
+			wantErr: ErrEmailNotVerified,
+		},
+		{
+			name:   "Local Account Not Verified",
+			claims: identity,
+			mock: func(issuer string, users *MockUserProvider, identities *MockUserIdentityStorage) {
+				identities.On("UserByIdentity", mock.Anything, issuer, "42").Return(nil, storage.ErrNotFound)
+				users.On("User", mock.Anything, "user@example.com").Return(unverified, nil)
+			},
+			wantErr: ErrAlreadyExists,
+		},
+		{
+			name:       "State Mismatch",
+			claims:     identity,
+			wrongState: true,
+			mock:       func(string, *MockUserProvider, *MockUserIdentityStorage) {},
+			wantErr:    ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := oidctest.NewServer(t, "blog", "secret")
+
+			client, err := oidc.New(context.Background(), config.OIDC{
+				Issuer:       server.URL,
+				ClientID:     "blog",
+				ClientSecret: "secret",
+				RedirectURL:  "http://localhost:8080/api/auth/oidc/callback",
+				Scopes:       []string{"openid", "email"},
+			}, server.Client())
+			assert.NoError(t, err)
+
+			mockUsers := new(MockUserProvider)
+			mockIdentities := new(MockUserIdentityStorage)
+			mockTwoFactor := new(MockTwoFactorProvider)
+			mockTokenSaver := new(MockRefreshTokenSaver)
+			mockSessions := new(MockSessionStorage)
+			authService := &AuthService{
+				provider:          mockUsers,
+				twoFactorProvider: mockTwoFactor,
+				tokenSaver:        mockTokenSaver,
+				sessionSaver:      mockSessions,
+				keys:              newTestKeySet(t, cfg),
+				oidcClient:        client,
+				identitySaver:     mockIdentities,
+				identityProvider:  mockIdentities,
+				hasher:            newTestHasher(t),
+			}
+
+			tt.mock(server.URL, mockUsers, mockIdentities)
+
+			if tt.wantErr == nil {
+				mockTwoFactor.On("TwoFactor", mock.Anything, tt.wantUserID).Return(&model.TwoFactor{}, nil)
+				mockSessions.On("SaveSession", mock.Anything, mock.MatchedBy(func(session *model.Session) bool {
+					return session.UserID == tt.wantUserID
+				})).Return(nil)
+				mockTokenSaver.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(token *model.RefreshToken) bool {
+					return token.UserID == tt.wantUserID
+				})).Return(nil)
+			}
+
+			authURL, stateToken, err := authService.StartOIDCLogin(cfg)
+			assert.NoError(t, err)
+
+			code, state, err := server.Authorize(authURL, tt.claims)
+			assert.NoError(t, err)
+
+			if tt.wrongState {
+				state = "other"
+			}
+
+			resp, err := authService.FinishOIDCLogin(context.Background(), cfg, stateToken, state, code, model.ClientInfo{})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.False(t, resp.TwoFactorRequired)
+				assert.NotEmpty(t, resp.AccessToken)
+				assert.NotEmpty(t, resp.RefreshToken)
+			}
+
+			mockUsers.AssertExpectations(t)
+			mockIdentities.AssertExpectations(t)
+			mockTwoFactor.AssertExpectations(t)
+			mockTokenSaver.AssertExpectations(t)
+			mockSessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_OIDCLoginDisabled(t *testing.T) {
+	authService := &AuthService{}
+
+	_, _, err := authService.StartOIDCLogin(config.Auth{})
+	assert.ErrorIs(t, err, ErrOIDCDisabled)
+
+	_, err = authService.FinishOIDCLogin(context.Background(), config.Auth{}, "state-token", "state", "code", model.ClientInfo{})
+	assert.ErrorIs(t, err, ErrOIDCDisabled)
+}
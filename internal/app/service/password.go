@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// userTokenSize is the number of random bytes in an emailed token.
+const userTokenSize = 32
+
+type UserTokenSaver interface {
+	SaveUserToken(ctx context.Context, token *model.UserToken) error
+}
+
+type UserTokenProcessor interface {
+	ConsumeUserToken(ctx context.Context, purpose model.TokenPurpose, tokenHash string) (*model.UserToken, error)
+}
+
+// ForgotPassword emails the user a link to reset the password.
+//
+// Unknown emails are silently ignored, so the caller can not find out
+// whether an account exists.
+func (as *AuthService) ForgotPassword(ctx context.Context, cfg config.Auth, email string) error {
+	const operation = "service.ForgotPassword"
+
+	user, err := as.provider.User(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	token, err := as.newUserToken(ctx, user.ID, model.TokenPurposePasswordReset, cfg.PasswordResetTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nsomebody asked to reset the password of your account. "+
+				"To choose a new password follow the link below:\n\n%s\n\n"+
+				"The link is valid for %s and can be used once. "+
+				"If it was not you, just ignore this email.\n",
+			user.Username,
+			cfg.BaseURL+"/reset-password?token="+url.QueryEscape(token),
+			cfg.PasswordResetTTL,
+		),
+	}
+
+	if err := as.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password using the emailed token.
+//
+// All tokens issued to the user are revoked afterwards, so whoever
+// knew the old password is logged out.
+func (as *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	const operation = "service.ResetPassword"
+
+	userToken, err := as.userTokenProcessor.ConsumeUserToken(ctx, model.TokenPurposePasswordReset, secret.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	passHash, err := as.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.processor.UpdatePassword(ctx, userToken.UserID, passHash); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.revoker.RevokeUserTokens(ctx, userToken.UserID); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// newUserToken generates a single-use token, stores its hash and returns the token itself.
+func (as *AuthService) newUserToken(ctx context.Context, userID int, purpose model.TokenPurpose, ttl time.Duration) (string, error) {
+	return as.issueUserToken(ctx, &model.UserToken{UserID: userID, Purpose: purpose}, ttl)
+}
+
+// issueUserToken is newUserToken for tokens which carry more than the user and the purpose.
+func (as *AuthService) issueUserToken(ctx context.Context, userToken *model.UserToken, ttl time.Duration) (string, error) {
+	const operation = "service.issueUserToken"
+
+	token, err := secret.Generate(userTokenSize)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	userToken.TokenHash = secret.Hash(token)
+	userToken.ExpiresAt = time.Now().Add(ttl)
+
+	err = as.userTokenSaver.SaveUserToken(ctx, userToken)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mocks
+type MockUserProcessor struct{ mock.Mock }
+
+func (m *MockUserProcessor) UpdateUserRole(ctx context.Context, userID int, role model.Role) error {
+	args := m.Called(ctx, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockUserProcessor) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserProcessor) MarkEmailVerified(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockUserProcessor) UpdateEmail(ctx context.Context, userID int, email string) error {
+	args := m.Called(ctx, userID, email)
+	return args.Error(0)
+}
+
+func (m *MockUserProcessor) DeleteUser(ctx context.Context, userID int, policy model.DeletionPolicy) error {
+	args := m.Called(ctx, userID, policy)
+	return args.Error(0)
+}
+
+type MockUserTokenSaver struct{ mock.Mock }
+
+func (m *MockUserTokenSaver) SaveUserToken(ctx context.Context, token *model.UserToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+type MockUserTokenProcessor struct{ mock.Mock }
+
+func (m *MockUserTokenProcessor) ConsumeUserToken(ctx context.Context, purpose model.TokenPurpose, tokenHash string) (*model.UserToken, error) {
+	args := m.Called(ctx, purpose, tokenHash)
+	token := args.Get(0)
+	if token == nil {
+		return nil, args.Error(1)
+	}
+	return token.(*model.UserToken), args.Error(1)
+}
+
+type MockTokenRevoker struct{ mock.Mock }
+
+func (m *MockTokenRevoker) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	args := m.Called(ctx, jti, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevoker) RevokeUserTokens(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevoker) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockTokenRevoker) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	args := m.Called(ctx, userID, keepSessionID)
+	return args.Error(0)
+}
+
+type MockMailer struct{ mock.Mock }
+
+func (m *MockMailer) Send(ctx context.Context, msg mailer.Message) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+// Tests
+func TestAuthService_ForgotPassword(t *testing.T) {
+	cfg := config.Auth{
+		PasswordResetTTL: time.Hour,
+		BaseURL:          "https://example.com",
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockProvider := new(MockUserProvider)
+		mockSaver := new(MockUserTokenSaver)
+		mockMailer := new(MockMailer)
+		authService := &AuthService{provider: mockProvider, userTokenSaver: mockSaver, mailer: mockMailer}
+
+		var tokenHash string
+
+		mockProvider.On("User", mock.Anything, "test@test.com").Return(&model.User{ID: 1, Email: "test@test.com"}, nil)
+		mockSaver.On("SaveUserToken", mock.Anything, mock.MatchedBy(func(token *model.UserToken) bool {
+			tokenHash = token.TokenHash
+			return token.UserID == 1 && token.Purpose == model.TokenPurposePasswordReset
+		})).Return(nil)
+		mockMailer.On("Send", mock.Anything, mock.MatchedBy(func(msg mailer.Message) bool {
+			_, link, ok := strings.Cut(msg.Body, "https://example.com/reset-password?token=")
+			if !ok {
+				return false
+			}
+
+			token, _, _ := strings.Cut(link, "\n")
+
+			return msg.To == "test@test.com" && secret.Hash(token) == tokenHash
+		})).Return(nil)
+
+		err := authService.ForgotPassword(context.Background(), cfg, "test@test.com")
+		assert.NoError(t, err)
+
+		mockProvider.AssertExpectations(t)
+		mockSaver.AssertExpectations(t)
+		mockMailer.AssertExpectations(t)
+	})
+
+	t.Run("Unknown email", func(t *testing.T) {
+		mockProvider := new(MockUserProvider)
+		mockMailer := new(MockMailer)
+		authService := &AuthService{provider: mockProvider, mailer: mockMailer}
+
+		mockProvider.On("User", mock.Anything, "unknown@test.com").Return(nil, storage.ErrNotFound)
+
+		err := authService.ForgotPassword(context.Background(), cfg, "unknown@test.com")
+		assert.NoError(t, err)
+
+		mockProvider.AssertExpectations(t)
+		mockMailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	const operation = "service.ResetPassword"
+
+	tests := []struct {
+		name         string
+		consumeToken *model.UserToken
+		consumeError error
+		expectUpdate bool
+		expectedErr  error
+	}{
+		{
+			name:         "Success",
+			consumeToken: &model.UserToken{UserID: 1, Purpose: model.TokenPurposePasswordReset},
+			expectUpdate: true,
+		},
+		{
+			name:         "Invalid token",
+			consumeError: storage.ErrNotFound,
+			expectedErr:  fmt.Errorf("%s: %w", operation, ErrInvalidToken),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTokens := new(MockUserTokenProcessor)
+			mockProcessor := new(MockUserProcessor)
+			mockRevoker := new(MockTokenRevoker)
+			authService := &AuthService{
+				processor:          mockProcessor,
+				revoker:            mockRevoker,
+				userTokenProcessor: mockTokens,
+				hasher:             newTestHasher(t),
+			}
+
+			mockTokens.On("ConsumeUserToken", mock.Anything, model.TokenPurposePasswordReset, secret.Hash("token")).
+				Return(tt.consumeToken, tt.consumeError)
+
+			if tt.expectUpdate {
+				mockProcessor.On("UpdatePassword", mock.Anything, 1, mock.AnythingOfType("string")).Return(nil)
+				mockRevoker.On("RevokeUserTokens", mock.Anything, 1).Return(nil)
+			}
+
+			err := authService.ResetPassword(context.Background(), "token", "Password12345!")
+
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockTokens.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+			mockRevoker.AssertExpectations(t)
+		})
+	}
+}
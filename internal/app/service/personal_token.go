@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// personalTokenSize is the number of random bytes in a personal access token.
+const personalTokenSize = 32
+
+// personalTokenPrefixSize is the number of characters of the token kept for display.
+const personalTokenPrefixSize = 12
+
+type PersonalTokenSaver interface {
+	SavePersonalToken(ctx context.Context, token *model.PersonalAccessToken) error
+}
+
+type PersonalTokenProvider interface {
+	PersonalTokens(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error)
+	PersonalTokenByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error)
+}
+
+type PersonalTokenProcessor interface {
+	TouchPersonalToken(ctx context.Context, id int) error
+	RevokePersonalToken(ctx context.Context, userID, id int) error
+}
+
+// CreatePersonalToken issues a new personal access token.
+//
+// The token is returned only here, afterwards just its hash is known.
+func (as *AuthService) CreatePersonalToken(ctx context.Context, userID int, req *model.PersonalTokenRequest) (*model.PersonalTokenCreated, error) {
+	const operation = "service.CreatePersonalToken"
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidExpiry)
+	}
+
+	random, err := secret.Generate(personalTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	token := model.PersonalTokenPrefix + random
+
+	pat := model.PersonalAccessToken{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    token[:personalTokenPrefixSize],
+		TokenHash: secret.Hash(token),
+		Scopes:    req.Scopes,
+		ExpiresAt: utcTime(req.ExpiresAt),
+	}
+
+	if err := as.personalTokenSaver.SavePersonalToken(ctx, &pat); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &model.PersonalTokenCreated{PersonalAccessToken: pat, Token: token}, nil
+}
+
+// PersonalTokens returns the active personal access tokens of the user.
+func (as *AuthService) PersonalTokens(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error) {
+	const operation = "service.PersonalTokens"
+
+	tokens, err := as.personalTokenProvider.PersonalTokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return tokens, nil
+}
+
+// RevokePersonalToken revokes a personal access token of the user.
+func (as *AuthService) RevokePersonalToken(ctx context.Context, userID, id int) error {
+	const operation = "service.RevokePersonalToken"
+
+	if err := as.personalTokenProcessor.RevokePersonalToken(ctx, userID, id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// AuthenticatePersonalToken returns the owner of the token and the token itself.
+//
+// The owner is loaded on every request, so a role change applies to the
+// token right away.
+func (as *AuthService) AuthenticatePersonalToken(ctx context.Context, token string) (*model.User, *model.PersonalAccessToken, error) {
+	const operation = "service.AuthenticatePersonalToken"
+
+	pat, err := as.personalTokenProvider.PersonalTokenByHash(ctx, secret.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return nil, nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	user, err := as.provider.UserByID(ctx, pat.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return nil, nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.personalTokenProcessor.TouchPersonalToken(ctx, pat.ID); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return user, pat, nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPersonalTokenSaver struct{ mock.Mock }
+
+func (m *MockPersonalTokenSaver) SavePersonalToken(ctx context.Context, token *model.PersonalAccessToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+type MockPersonalTokenProvider struct{ mock.Mock }
+
+func (m *MockPersonalTokenProvider) PersonalTokens(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*model.PersonalAccessToken), args.Error(1)
+}
+
+func (m *MockPersonalTokenProvider) PersonalTokenByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	args := m.Called(ctx, tokenHash)
+	token := args.Get(0)
+	if token == nil {
+		return nil, args.Error(1)
+	}
+	return token.(*model.PersonalAccessToken), args.Error(1)
+}
+
+type MockPersonalTokenProcessor struct{ mock.Mock }
+
+func (m *MockPersonalTokenProcessor) TouchPersonalToken(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockPersonalTokenProcessor) RevokePersonalToken(ctx context.Context, userID, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func TestAuthService_CreatePersonalToken(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	futureInZone := future.In(time.FixedZone("UTC+2", 2*60*60))
+
+	tests := []struct {
+		name      string
+		expiresAt *time.Time
+		wantErr   error
+	}{
+		{name: "Without Expiry", expiresAt: nil},
+		{name: "With Expiry", expiresAt: &future},
+		{name: "Expiry With Offset", expiresAt: &futureInZone},
+		{name: "Expiry In The Past", expiresAt: &past, wantErr: ErrInvalidExpiry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSaver := new(MockPersonalTokenSaver)
+			authService := &AuthService{personalTokenSaver: mockSaver}
+
+			req := &model.PersonalTokenRequest{
+				Name:      "release script",
+				Scopes:    []model.Scope{model.ScopePostsWrite},
+				ExpiresAt: tt.expiresAt,
+			}
+
+			if tt.wantErr == nil {
+				mockSaver.On("SavePersonalToken", mock.Anything, mock.MatchedBy(func(token *model.PersonalAccessToken) bool {
+					if tt.expiresAt == nil {
+						return token.UserID == 1 && token.Name == req.Name && token.ExpiresAt == nil
+					}
+
+					return token.UserID == 1 && token.Name == req.Name && token.ExpiresAt != nil &&
+						token.ExpiresAt.Equal(*tt.expiresAt) && token.ExpiresAt.Location() == time.UTC
+				})).Return(nil)
+			}
+
+			created, err := authService.CreatePersonalToken(context.Background(), 1, req)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, strings.HasPrefix(created.Token, model.PersonalTokenPrefix))
+				assert.True(t, strings.HasPrefix(created.Token, created.Prefix))
+				assert.Equal(t, secret.Hash(created.Token), created.TokenHash)
+			}
+
+			mockSaver.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_AuthenticatePersonalToken(t *testing.T) {
+	user := &model.User{ID: 1, Username: "user", Email: "email@example.com", Role: model.RoleAuthor}
+	pat := &model.PersonalAccessToken{ID: 7, UserID: 1, Scopes: []model.Scope{model.ScopePostsWrite}}
+
+	tests := []struct {
+		name       string
+		tokenErr   error
+		wantUser   *model.User
+		wantErr    error
+		expectUser bool
+	}{
+		{name: "Success", wantUser: user, expectUser: true},
+		{name: "Unknown, Expired Or Revoked", tokenErr: storage.ErrNotFound, wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockUsers := new(MockUserProvider)
+			mockProvider := new(MockPersonalTokenProvider)
+			mockProcessor := new(MockPersonalTokenProcessor)
+			authService := &AuthService{
+				provider:               mockUsers,
+				personalTokenProvider:  mockProvider,
+				personalTokenProcessor: mockProcessor,
+			}
+
+			if tt.tokenErr != nil {
+				mockProvider.On("PersonalTokenByHash", mock.Anything, secret.Hash("sbp_token")).Return(nil, tt.tokenErr)
+			} else {
+				mockProvider.On("PersonalTokenByHash", mock.Anything, secret.Hash("sbp_token")).Return(pat, nil)
+			}
+
+			if tt.expectUser {
+				mockUsers.On("UserByID", mock.Anything, 1).Return(user, nil)
+				mockProcessor.On("TouchPersonalToken", mock.Anything, pat.ID).Return(nil)
+			}
+
+			gotUser, gotToken, err := authService.AuthenticatePersonalToken(context.Background(), "sbp_token")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, gotToken)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, pat, gotToken)
+			}
+
+			assert.Equal(t, tt.wantUser, gotUser)
+
+			mockUsers.AssertExpectations(t)
+			mockProvider.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+		})
+	}
+}
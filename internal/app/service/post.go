@@ -2,10 +2,14 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/slug"
 	"github.com/markraiter/simple-blog/internal/model"
 )
 
@@ -15,27 +19,59 @@ type PostSaver interface {
 
 type PostProvider interface {
 	Post(ctx context.Context, id int) (*model.Post, error)
-	Posts(ctx context.Context) ([]*model.Post, error)
+	Posts(ctx context.Context, query *model.PostQuery) ([]*model.Post, error)
+	Tags(ctx context.Context) ([]*model.Tag, error)
+	Tag(ctx context.Context, slug string) (*model.Tag, error)
+	Revisions(ctx context.Context, postID int) ([]*model.PostRevision, error)
+	Revision(ctx context.Context, postID, number int) (*model.PostRevision, error)
+	PostBySlug(ctx context.Context, slug string) (*model.Post, error)
+	RedirectedPost(ctx context.Context, slug string) (*model.Post, error)
 }
 
 type PostProcessor interface {
-	UpdatePost(ctx context.Context, post *model.Post) error
-	DeletePost(ctx context.Context, postID, userID int) error
+	UpdatePost(ctx context.Context, post *model.Post, editorID int) error
+	DeletePost(ctx context.Context, postID, userID, version int) error
 }
 
+const defaultPostsLimit = 20
+
 type PostService struct {
 	saver     PostSaver
 	provider  PostProvider
 	processor PostProcessor
+	verifier  UserVerifier
+	renderer  *renderCache
 }
 
+// SavePost creates a new post of the user.
+//
+// Users with unverified email addresses get ErrEmailNotVerified.
 func (ps *PostService) SavePost(ctx context.Context, userID int, postReq *model.PostRequest) (int, error) {
 	const operation = "service.SavePost"
 
+	if err := checkVerified(ctx, ps.verifier, userID); err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tags, err := normalizeTags(postReq.Tags)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	status, publishAt, err := publication(nil, postReq, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
 	postModel := model.Post{
-		Title:   postReq.Title,
-		Content: postReq.Content,
-		UserID:  userID,
+		Title:     postReq.Title,
+		Slug:      postSlug(postReq.Title),
+		Content:   postReq.Content,
+		Format:    contentFormat(postReq.Format, ""),
+		UserID:    userID,
+		Tags:      tags,
+		Status:    status,
+		PublishAt: publishAt,
 	}
 
 	id, err := ps.saver.SavePost(ctx, &postModel)
@@ -46,7 +82,12 @@ func (ps *PostService) SavePost(ctx context.Context, userID int, postReq *model.
 	return id, nil
 }
 
-func (ps *PostService) Post(ctx context.Context, id int) (*model.Post, error) {
+// Post returns the post by its ID.
+//
+// Posts that are not published are only shown to their author, for
+// others they give ErrNotFound. The post comes with its content rendered
+// to HTML.
+func (ps *PostService) Post(ctx context.Context, id, viewerID int) (*model.Post, error) {
 	const operation = "service.Post"
 
 	post, err := ps.provider.Post(ctx, id)
@@ -58,31 +99,161 @@ func (ps *PostService) Post(ctx context.Context, id int) (*model.Post, error) {
 		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
+	if !visible(post, viewerID) {
+		return nil, fmt.Errorf("%s: %w", operation, ErrNotFound)
+	}
+
+	if err := ps.renderer.renderPosts(post); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
 	return post, nil
 }
 
-func (ps *PostService) Posts(ctx context.Context) ([]*model.Post, error) {
+// Posts returns a page of posts matching the query.
+//
+// after is the NextCursor of the previous page, a cursor of another sort
+// order or a malformed one gives ErrInvalidCursor.
+func (ps *PostService) Posts(ctx context.Context, query *model.PostQuery, after string) (*model.PostPage, error) {
 	const operation = "service.Posts"
 
-	posts, err := ps.provider.Posts(ctx)
+	q := *query
+
+	if q.Limit == 0 {
+		q.Limit = defaultPostsLimit
+	}
+
+	if q.Sort == "" {
+		q.Sort = model.PostSortCreated
+	}
+
+	q.From = utcTime(q.From)
+	q.To = utcTime(q.To)
+
+	if q.Tag != "" {
+		if q.Tag = slug.Make(q.Tag); q.Tag == "" {
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidTag)
+		}
+	}
+
+	if after != "" {
+		cursor, err := decodePostCursor(after)
+		if err != nil || cursor.Sort != q.Sort {
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidCursor)
+		}
+
+		q.After = cursor
+	}
+
+	limit := q.Limit
+	// One more post tells whether there is a next page.
+	q.Limit++
+
+	posts, err := ps.provider.Posts(ctx, &q)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
-	return posts, nil
+	if err := ps.renderer.renderPosts(posts...); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	page := &model.PostPage{Posts: posts}
+
+	if len(posts) > limit {
+		page.Posts = posts[:limit]
+
+		page.NextCursor, err = encodePostCursor(q.Sort, page.Posts[limit-1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	return page, nil
 }
 
-func (ps *PostService) UpdatePost(ctx context.Context, postID, userID int, postReq *model.PostRequest) error {
+// encodePostCursor returns the opaque cursor of the post's position in the sort order.
+func encodePostCursor(sort model.PostSort, post *model.Post) (string, error) {
+	cursor := model.PostCursor{Sort: sort, ID: post.ID}
+
+	switch sort {
+	case model.PostSortCreated:
+		cursor.Time = post.CreatedAt
+	case model.PostSortUpdated:
+		cursor.Time = post.UpdatedAt
+	case model.PostSortComments:
+		cursor.CommentsCount = post.CommentsCount
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePostCursor(value string) (*model.PostCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &model.PostCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
+
+// UpdatePost updates the post on behalf of the user.
+//
+// Authors may update only their own posts, moderators may update any post.
+// The post is only updated at the version, otherwise it returns ErrConflict.
+func (ps *PostService) UpdatePost(ctx context.Context, postID, version, userID int, role model.Role, postReq *model.PostRequest) error {
 	const operation = "service.UpdatePost"
 
+	current, err := ps.provider.Post(ctx, postID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	// Moderators act on behalf of the author, the storage checks the
+	// ownership of everyone else.
+	ownerID := userID
+	if role.CanModerate() {
+		ownerID = current.UserID
+	}
+
+	tags, err := normalizeTags(postReq.Tags)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	status, publishAt, err := publication(current, postReq, time.Now())
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
 	postModel := model.Post{
-		ID:      postID,
-		Title:   postReq.Title,
-		Content: postReq.Content,
-        UserID:  userID,
+		ID:        postID,
+		Title:     postReq.Title,
+		Slug:      postSlug(postReq.Title),
+		Content:   postReq.Content,
+		Format:    contentFormat(postReq.Format, current.Format),
+        UserID:    ownerID,
+		Tags:      tags,
+		Status:    status,
+		PublishAt: publishAt,
+		Version:   version,
 	}
 
-    err := ps.processor.UpdatePost(ctx, &postModel)
+    err = ps.processor.UpdatePost(ctx, &postModel, userID)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return fmt.Errorf("%s: %w", operation, ErrNotFound)
@@ -92,16 +263,29 @@ func (ps *PostService) UpdatePost(ctx context.Context, postID, userID int, postR
             return fmt.Errorf("%s: %w", operation, ErrNotAllowed)
         }
 
+		if errors.Is(err, storage.ErrConflict) {
+			return fmt.Errorf("%s: %w", operation, ErrConflict)
+		}
+
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
 	return nil
 }
 
-func (ps *PostService) DeletePost(ctx context.Context, postID, userID int) error {
+// DeletePost deletes the post on behalf of the user.
+//
+// Authors may delete only their own posts, moderators may delete any post.
+// The post is only deleted at the version, otherwise it returns ErrConflict.
+func (ps *PostService) DeletePost(ctx context.Context, postID, version, userID int, role model.Role) error {
 	const operation = "service.DeletePost"
 
-    err := ps.processor.DeletePost(ctx, postID, userID)
+	ownerID, err := ps.ownerID(ctx, postID, userID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+    err = ps.processor.DeletePost(ctx, postID, ownerID, version)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
 			return fmt.Errorf("%s: %w", operation, ErrNotFound)
@@ -111,8 +295,105 @@ func (ps *PostService) DeletePost(ctx context.Context, postID, userID int) error
             return fmt.Errorf("%s: %w", operation, ErrNotAllowed)
         }
 
+		if errors.Is(err, storage.ErrConflict) {
+			return fmt.Errorf("%s: %w", operation, ErrConflict)
+		}
+
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
 	return nil
 }
+
+// publication returns the status and the publish time of a post after
+// the request, current is nil for a new post.
+//
+// A scheduled post needs a publish time in the future, other statuses do
+// not take one. A post keeps the time it was first published at.
+func publication(current *model.Post, postReq *model.PostRequest, now time.Time) (model.PostStatus, *time.Time, error) {
+	status := postReq.Status
+
+	if status == "" {
+		switch {
+		case current != nil:
+			status = current.Status
+		case postReq.PublishAt != nil:
+			status = model.PostStatusScheduled
+		default:
+			status = model.PostStatusPublished
+		}
+	}
+
+	switch status {
+	case model.PostStatusScheduled:
+		if postReq.PublishAt == nil {
+			if current == nil || current.Status != model.PostStatusScheduled {
+				return "", nil, ErrInvalidSchedule
+			}
+
+			return status, current.PublishAt, nil
+		}
+
+		if !postReq.PublishAt.After(now) {
+			return "", nil, ErrInvalidSchedule
+		}
+
+		return status, utcTime(postReq.PublishAt), nil
+	case model.PostStatusPublished, model.PostStatusArchived:
+		if postReq.PublishAt != nil {
+			return "", nil, ErrInvalidSchedule
+		}
+
+		if current != nil && (current.Status == model.PostStatusPublished || current.Status == model.PostStatusArchived) {
+			return status, current.PublishAt, nil
+		}
+
+		if status == model.PostStatusArchived {
+			return status, nil, nil
+		}
+
+		return status, &now, nil
+	default:
+		if postReq.PublishAt != nil {
+			return "", nil, ErrInvalidSchedule
+		}
+
+		return status, nil, nil
+	}
+}
+
+// utcTime returns the time in UTC, nil stays nil.
+//
+// Times are stored in TIMESTAMP columns which drop the offset, so every
+// time coming from a client is converted before it reaches the storage.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	utc := t.UTC()
+
+	return &utc
+}
+
+// ownerID returns the ID the storage checks the post ownership against.
+//
+// For moderators it is the actual author of the post, so the check always
+// passes. For everyone else it is the user ID itself, so the storage
+// answers with storage.ErrNotAllowed for posts of other users.
+func (ps *PostService) ownerID(ctx context.Context, postID, userID int, role model.Role) (int, error) {
+	if !role.CanModerate() {
+		return userID, nil
+	}
+
+	post, err := ps.provider.Post(ctx, postID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return 0, ErrNotFound
+		}
+
+		return 0, err
+	}
+
+	return post.UserID, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/markraiter/simple-blog/internal/app/storage"
 	"github.com/markraiter/simple-blog/internal/model"
@@ -31,35 +32,58 @@ func (m *MockPostProvider) Post(ctx context.Context, id int) (*model.Post, error
 	return post.(*model.Post), args.Error(1)
 }
 
-func (m *MockPostProvider) Posts(ctx context.Context) ([]*model.Post, error) {
-	args := m.Called(ctx)
+func (m *MockPostProvider) Posts(ctx context.Context, query *model.PostQuery) ([]*model.Post, error) {
+	args := m.Called(ctx, query)
 	return args.Get(0).([]*model.Post), args.Error(1)
 }
 
+func (m *MockPostProvider) Tags(ctx context.Context) ([]*model.Tag, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*model.Tag), args.Error(1)
+}
+
+func (m *MockPostProvider) Tag(ctx context.Context, slug string) (*model.Tag, error) {
+	args := m.Called(ctx, slug)
+	tag := args.Get(0)
+	if tag == nil {
+		return nil, args.Error(1)
+	}
+	return tag.(*model.Tag), args.Error(1)
+}
+
 type MockPostProcessor struct{ mock.Mock }
 
-func (m *MockPostProcessor) UpdatePost(ctx context.Context, post *model.Post) error {
-	args := m.Called(ctx, post)
+func (m *MockPostProcessor) UpdatePost(ctx context.Context, post *model.Post, editorID int) error {
+	args := m.Called(ctx, post, editorID)
 	return args.Error(0)
 }
 
-func (m *MockPostProcessor) DeletePost(ctx context.Context, postID, userID int) error {
-	args := m.Called(ctx, postID, userID)
+func (m *MockPostProcessor) DeletePost(ctx context.Context, postID, userID, version int) error {
+	args := m.Called(ctx, postID, userID, version)
 	return args.Error(0)
 }
 
+type MockUserVerifier struct{ mock.Mock }
+
+func (m *MockUserVerifier) EmailVerified(ctx context.Context, userID int) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
 // Tests
 func TestPostService_SavePost(t *testing.T) {
 	const operation = "service.SavePost"
 	var err = errors.New("error")
 
 	mockSaver := new(MockPostSaver)
-	postService := &PostService{saver: mockSaver}
+	mockVerifier := new(MockUserVerifier)
+	postService := &PostService{saver: mockSaver, verifier: mockVerifier}
 
 	tests := []struct {
 		name       string
 		postReq    *model.PostRequest
 		userID     int
+		verified   bool
 		mockReturn int
 		mockError  error
 		wantError  error
@@ -71,6 +95,7 @@ func TestPostService_SavePost(t *testing.T) {
 				Content: "Test Content",
 			},
 			userID:     1,
+			verified:   true,
 			mockReturn: 1,
 			mockError:  nil,
 			wantError:  nil,
@@ -82,19 +107,33 @@ func TestPostService_SavePost(t *testing.T) {
 				Content: "Test Content",
 			},
 			userID:     0,
+			verified:   true,
 			mockReturn: 0,
 			mockError:  err,
 			wantError:  fmt.Errorf("%s: %w", operation, err),
 		},
+		{
+			name: "Email Not Verified",
+			postReq: &model.PostRequest{
+				Title:   "Test Title",
+				Content: "Test Content",
+			},
+			userID:    2,
+			verified:  false,
+			wantError: fmt.Errorf("%s: %w", operation, ErrEmailNotVerified),
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockSaver.On("SavePost", mock.Anything, &model.Post{
-				Title:   tt.postReq.Title,
-				Content: tt.postReq.Content,
-				UserID:  tt.userID,
-			}).Return(tt.mockReturn, tt.mockError)
+			mockVerifier.On("EmailVerified", mock.Anything, tt.userID).Return(tt.verified, nil)
+
+			if tt.verified {
+				mockSaver.On("SavePost", mock.Anything, mock.MatchedBy(func(post *model.Post) bool {
+					return post.Title == tt.postReq.Title && post.Slug == "test-title" && post.Content == tt.postReq.Content &&
+						post.Format == model.ContentFormatPlain && post.UserID == tt.userID && post.Status == model.PostStatusPublished && post.PublishAt != nil
+				})).Return(tt.mockReturn, tt.mockError)
+			}
 
 			_, err := postService.SavePost(context.Background(), tt.userID, tt.postReq)
 
@@ -105,6 +144,7 @@ func TestPostService_SavePost(t *testing.T) {
 			}
 
 			mockSaver.AssertExpectations(t)
+			mockVerifier.AssertExpectations(t)
 		})
 	}
 }
@@ -119,6 +159,7 @@ func TestPostService_Post(t *testing.T) {
 	tests := []struct {
 		name         string
 		id           int
+		viewerID     int
 		mockReturn   *model.Post
 		mockError    error
 		expectedPost *model.Post
@@ -131,15 +172,57 @@ func TestPostService_Post(t *testing.T) {
 				ID:      1,
 				Title:   "Test Title",
 				Content: "Test Content",
+				Status:  model.PostStatusPublished,
 			},
 			mockError: nil,
 			expectedPost: &model.Post{
-				ID:      1,
-				Title:   "Test Title",
-				Content: "Test Content",
+				ID:          1,
+				Title:       "Test Title",
+				Content:     "Test Content",
+				ContentHTML: "<p>Test Content</p>\n",
+				Status:      model.PostStatusPublished,
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "Markdown",
+			id:   7,
+			mockReturn: &model.Post{
+				ID:      7,
+				Content: "# Title\n\n<a href=\"javascript:alert(1)\" onclick=\"alert(1)\">link</a>",
+				Format:  model.ContentFormatMarkdown,
+				Status:  model.PostStatusPublished,
+			},
+			expectedPost: &model.Post{
+				ID:          7,
+				Content:     "# Title\n\n<a href=\"javascript:alert(1)\" onclick=\"alert(1)\">link</a>",
+				Format:      model.ContentFormatMarkdown,
+				ContentHTML: "<h1>Title</h1>\n<p>link</p>\n",
+				Status:      model.PostStatusPublished,
+			},
+		},
+		{
+			name:         "Draft of the viewer",
+			id:           4,
+			viewerID:     7,
+			mockReturn:   &model.Post{ID: 4, UserID: 7, Status: model.PostStatusDraft},
+			expectedPost: &model.Post{ID: 4, UserID: 7, Status: model.PostStatusDraft},
+		},
+		{
+			name:         "Draft of another user",
+			id:           5,
+			viewerID:     8,
+			mockReturn:   &model.Post{ID: 5, UserID: 7, Status: model.PostStatusDraft},
+			expectedPost: nil,
+			expectedErr:  fmt.Errorf("%s: %w", operation, ErrNotFound),
+		},
+		{
+			name:         "Scheduled post without a viewer",
+			id:           6,
+			mockReturn:   &model.Post{ID: 6, UserID: 7, Status: model.PostStatusScheduled},
+			expectedPost: nil,
+			expectedErr:  fmt.Errorf("%s: %w", operation, ErrNotFound),
+		},
 		{
 			name:         "Post not found",
 			id:           2,
@@ -162,7 +245,7 @@ func TestPostService_Post(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockProvider.On("Post", mock.Anything, tt.id).Return(tt.mockReturn, tt.mockError)
 
-			post, err := postService.Post(context.Background(), tt.id)
+			post, err := postService.Post(context.Background(), tt.id, tt.viewerID)
 
 			if tt.expectedErr != nil {
 				assert.EqualError(t, err, tt.expectedErr.Error())
@@ -180,90 +263,153 @@ func TestPostService_Posts(t *testing.T) {
 	const operation = "service.Posts"
 	var err = errors.New("error")
 
-	mockProvider := new(MockPostProvider)
-	postService := &PostService{provider: mockProvider}
+	created := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	posts := []*model.Post{
+		{ID: 3, Title: "Test Title 3", CommentsCount: 5, CreatedAt: created.Add(time.Hour)},
+		{ID: 2, Title: "Test Title 2", CommentsCount: 2, CreatedAt: created},
+		{ID: 1, Title: "Test Title 1", CommentsCount: 2, CreatedAt: created},
+	}
+
+	createdCursor, cursorErr := encodePostCursor(model.PostSortCreated, posts[1])
+	assert.NoError(t, cursorErr)
+
+	from := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	fromInZone := from.In(time.FixedZone("UTC+2", 2*60*60))
+	toInZone := to.In(time.FixedZone("UTC+2", 2*60*60))
 
 	tests := []struct {
 		name          string
-		ctx           context.Context
+		query         *model.PostQuery
+		after         string
+		wantQuery     *model.PostQuery
 		mockReturn    []*model.Post
 		mockError     error
 		expectedPosts []*model.Post
+		expectedNext  bool
 		expectedErr   error
 	}{
 		{
-			name: "Success",
-			ctx:  context.Background(),
-			mockReturn: []*model.Post{
-				{
-					ID:      1,
-					Title:   "Test Title",
-					Content: "Test Content",
-				},
-				{
-					ID:      2,
-					Title:   "Test Title 2",
-					Content: "Test Content 2",
-				},
-			},
-			mockError: nil,
-			expectedPosts: []*model.Post{
-				{
-					ID:      1,
-					Title:   "Test Title",
-					Content: "Test Content",
-				},
-				{
-					ID:      2,
-					Title:   "Test Title 2",
-					Content: "Test Content 2",
-				},
+			name:          "Defaults",
+			query:         &model.PostQuery{},
+			wantQuery:     &model.PostQuery{Limit: defaultPostsLimit + 1, Sort: model.PostSortCreated},
+			mockReturn:    posts,
+			expectedPosts: posts,
+		},
+		{
+			name:          "Next Page",
+			query:         &model.PostQuery{Limit: 2, Sort: model.PostSortCreated, AuthorID: 1},
+			wantQuery:     &model.PostQuery{Limit: 3, Sort: model.PostSortCreated, AuthorID: 1},
+			mockReturn:    posts,
+			expectedPosts: posts[:2],
+			expectedNext:  true,
+		},
+		{
+			name:  "After Cursor",
+			query: &model.PostQuery{Limit: 2, Sort: model.PostSortCreated},
+			after: createdCursor,
+			wantQuery: &model.PostQuery{
+				Limit: 3,
+				Sort:  model.PostSortCreated,
+				After: &model.PostCursor{Sort: model.PostSortCreated, ID: 2, Time: created},
 			},
-			expectedErr: nil,
+			mockReturn:    posts[2:],
+			expectedPosts: posts[2:],
+		},
+		{
+			name:          "Bounds With An Offset",
+			query:         &model.PostQuery{From: &fromInZone, To: &toInZone},
+			wantQuery:     &model.PostQuery{Limit: defaultPostsLimit + 1, Sort: model.PostSortCreated, From: &from, To: &to},
+			mockReturn:    posts,
+			expectedPosts: posts,
+		},
+		{
+			name:        "Cursor Of Another Sort Order",
+			query:       &model.PostQuery{Sort: model.PostSortComments},
+			after:       createdCursor,
+			expectedErr: fmt.Errorf("%s: %w", operation, ErrInvalidCursor),
 		},
 		{
-			name:          "Error",
-			ctx:           nil,
-			mockReturn:    nil,
-			mockError:     err,
-			expectedPosts: nil,
-			expectedErr:   fmt.Errorf("%s: %w", operation, err),
+			name:        "Malformed Cursor",
+			query:       &model.PostQuery{},
+			after:       "not a cursor",
+			expectedErr: fmt.Errorf("%s: %w", operation, ErrInvalidCursor),
+		},
+		{
+			name:        "Error",
+			query:       &model.PostQuery{},
+			wantQuery:   &model.PostQuery{Limit: defaultPostsLimit + 1, Sort: model.PostSortCreated},
+			mockError:   err,
+			expectedErr: fmt.Errorf("%s: %w", operation, err),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockProvider.On("Posts", tt.ctx).Return(tt.mockReturn, tt.mockError)
+			mockProvider := new(MockPostProvider)
+			postService := &PostService{provider: mockProvider}
 
-			posts, err := postService.Posts(tt.ctx)
+			if tt.wantQuery != nil {
+				mockProvider.On("Posts", mock.Anything, tt.wantQuery).Return(tt.mockReturn, tt.mockError)
+			}
+
+			page, err := postService.Posts(context.Background(), tt.query, tt.after)
 
 			if tt.expectedErr != nil {
 				assert.EqualError(t, err, tt.expectedErr.Error())
+				assert.Nil(t, page)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedPosts, page.Posts)
+				assert.Equal(t, tt.expectedNext, page.NextCursor != "")
 			}
 
-			assert.Equal(t, tt.expectedPosts, posts)
 			mockProvider.AssertExpectations(t)
 		})
 	}
 }
 
+func TestPostService_PostCursor(t *testing.T) {
+	post := &model.Post{ID: 7, CommentsCount: 4, UpdatedAt: time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)}
+
+	for _, sort := range []model.PostSort{model.PostSortCreated, model.PostSortUpdated, model.PostSortComments} {
+		value, err := encodePostCursor(sort, post)
+		assert.NoError(t, err)
+
+		cursor, err := decodePostCursor(value)
+		assert.NoError(t, err)
+		assert.Equal(t, sort, cursor.Sort)
+		assert.Equal(t, 7, cursor.ID)
+
+		switch sort {
+		case model.PostSortUpdated:
+			assert.True(t, post.UpdatedAt.Equal(cursor.Time))
+		case model.PostSortComments:
+			assert.Equal(t, 4, cursor.CommentsCount)
+		}
+	}
+}
+
 func TestPostService_UpdatePost(t *testing.T) {
-	const operation = "service.UpdatePost"
+	const (
+		operation = "service.UpdatePost"
+		version   = 3
+	)
 	var err = errors.New("error")
 
+	mockProvider := new(MockPostProvider)
 	mockProcessor := new(MockPostProcessor)
-	postService := &PostService{processor: mockProcessor}
+	postService := &PostService{provider: mockProvider, processor: mockProcessor}
 
 	tests := []struct {
-		name        string
-		ctx         context.Context
-		postID      int
-		userID      int
-		postReq     *model.PostRequest
-		mockError   error
-		expectedErr error
+		name          string
+		ctx           context.Context
+		postID        int
+		userID        int
+		postReq       *model.PostRequest
+		providerError error
+		mockError     error
+		expectedErr   error
 	}{
 		{
 			name:   "Success",
@@ -289,6 +435,30 @@ func TestPostService_UpdatePost(t *testing.T) {
 			mockError:   storage.ErrNotFound,
 			expectedErr: fmt.Errorf("%s: %w", operation, ErrNotFound),
 		},
+		{
+			name:   "Post missing before update",
+			ctx:    context.Background(),
+			postID: 3,
+			userID: 1,
+			postReq: &model.PostRequest{
+				Title:   "Test Title",
+				Content: "Test Content",
+			},
+			providerError: storage.ErrNotFound,
+			expectedErr:   fmt.Errorf("%s: %w", operation, ErrNotFound),
+		},
+		{
+			name:   "Invalid schedule",
+			ctx:    context.Background(),
+			postID: 1,
+			userID: 1,
+			postReq: &model.PostRequest{
+				Title:   "Test Title",
+				Content: "Test Content",
+				Status:  model.PostStatusScheduled,
+			},
+			expectedErr: fmt.Errorf("%s: %w", operation, ErrInvalidSchedule),
+		},
 		{
 			name:   "Not allowed",
 			ctx:    context.Background(),
@@ -301,6 +471,18 @@ func TestPostService_UpdatePost(t *testing.T) {
 			mockError:   storage.ErrNotAllowed,
 			expectedErr: fmt.Errorf("%s: %w", operation, ErrNotAllowed),
 		},
+		{
+			name:   "Conflict",
+			ctx:    context.Background(),
+			postID: 4,
+			userID: 1,
+			postReq: &model.PostRequest{
+				Title:   "Test Title",
+				Content: "Test Content",
+			},
+			mockError:   storage.ErrConflict,
+			expectedErr: fmt.Errorf("%s: %w", operation, ErrConflict),
+		},
 		{
 			name:   "Error",
 			ctx:    nil,
@@ -317,11 +499,20 @@ func TestPostService_UpdatePost(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockProcessor.On("UpdatePost", tt.ctx, mock.MatchedBy(func(post *model.Post) bool {
-				return post.ID == tt.postID && post.UserID == tt.userID
-			})).Return(tt.mockError)
+			if tt.providerError != nil {
+				mockProvider.On("Post", mock.Anything, tt.postID).Return(nil, tt.providerError).Once()
+			} else {
+				mockProvider.On("Post", mock.Anything, tt.postID).Return(&model.Post{ID: tt.postID, UserID: 1, Status: model.PostStatusPublished}, nil).Once()
+			}
+
+			if tt.providerError == nil && !errors.Is(tt.expectedErr, ErrInvalidSchedule) {
+				mockProcessor.On("UpdatePost", tt.ctx, mock.MatchedBy(func(post *model.Post) bool {
+					return post.ID == tt.postID && post.UserID == tt.userID && post.Status == model.PostStatusPublished &&
+						post.Version == version && post.Slug == "test-title"
+				}), tt.userID).Return(tt.mockError).Once()
+			}
 
-			err := postService.UpdatePost(tt.ctx, tt.postID, tt.userID, tt.postReq)
+			err := postService.UpdatePost(tt.ctx, tt.postID, version, tt.userID, model.RoleAuthor, tt.postReq)
 
 			if tt.expectedErr != nil {
 				assert.EqualError(t, err, tt.expectedErr.Error())
@@ -329,13 +520,17 @@ func TestPostService_UpdatePost(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
+			mockProvider.AssertExpectations(t)
 			mockProcessor.AssertExpectations(t)
 		})
 	}
 }
 
 func TestPostService_DeletePost(t *testing.T) {
-	const operation = "service.DeletePost"
+	const (
+		operation = "service.DeletePost"
+		version   = 3
+	)
 	var err = errors.New("error")
 
 	mockProcessor := new(MockPostProcessor)
@@ -373,6 +568,14 @@ func TestPostService_DeletePost(t *testing.T) {
 			mockError:   storage.ErrNotAllowed,
 			expectedErr: fmt.Errorf("%s: %w", operation, ErrNotAllowed),
 		},
+		{
+			name:        "Conflict",
+			ctx:         context.Background(),
+			postID:      3,
+			userID:      1,
+			mockError:   storage.ErrConflict,
+			expectedErr: fmt.Errorf("%s: %w", operation, ErrConflict),
+		},
 		{
 			name:        "Error",
 			ctx:         nil,
@@ -385,9 +588,9 @@ func TestPostService_DeletePost(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockProcessor.On("DeletePost", tt.ctx, tt.postID, tt.userID).Return(tt.mockError)
+			mockProcessor.On("DeletePost", tt.ctx, tt.postID, tt.userID, version).Return(tt.mockError)
 
-			err := postService.DeletePost(tt.ctx, tt.postID, tt.userID)
+			err := postService.DeletePost(tt.ctx, tt.postID, version, tt.userID, model.RoleAuthor)
 
 			if tt.expectedErr != nil {
 				assert.EqualError(t, err, tt.expectedErr.Error())
@@ -399,3 +602,169 @@ func TestPostService_DeletePost(t *testing.T) {
 		})
 	}
 }
+
+func TestPostService_Moderation(t *testing.T) {
+	const (
+		postID      = 1
+		authorID    = 1
+		moderatorID = 2
+	)
+
+	postReq := &model.PostRequest{
+		Title:   "Test Title",
+		Content: "Test Content",
+	}
+
+	t.Run("Moderator updates post of another user", func(t *testing.T) {
+		mockProvider := new(MockPostProvider)
+		mockProcessor := new(MockPostProcessor)
+		postService := &PostService{provider: mockProvider, processor: mockProcessor}
+
+		mockProvider.On("Post", mock.Anything, postID).Return(&model.Post{ID: postID, UserID: authorID, Format: model.ContentFormatMarkdown}, nil)
+		mockProcessor.On("UpdatePost", mock.Anything, mock.MatchedBy(func(post *model.Post) bool {
+			// The request has no format, the post keeps its own.
+			return post.ID == postID && post.UserID == authorID && post.Format == model.ContentFormatMarkdown
+		}), moderatorID).Return(nil)
+
+		err := postService.UpdatePost(context.Background(), postID, 1, moderatorID, model.RoleModerator, postReq)
+		assert.NoError(t, err)
+
+		mockProvider.AssertExpectations(t)
+		mockProcessor.AssertExpectations(t)
+	})
+
+	t.Run("Admin deletes post of another user", func(t *testing.T) {
+		mockProvider := new(MockPostProvider)
+		mockProcessor := new(MockPostProcessor)
+		postService := &PostService{provider: mockProvider, processor: mockProcessor}
+
+		mockProvider.On("Post", mock.Anything, postID).Return(&model.Post{ID: postID, UserID: authorID}, nil)
+		mockProcessor.On("DeletePost", mock.Anything, postID, authorID, 1).Return(nil)
+
+		err := postService.DeletePost(context.Background(), postID, 1, moderatorID, model.RoleAdmin)
+		assert.NoError(t, err)
+
+		mockProvider.AssertExpectations(t)
+		mockProcessor.AssertExpectations(t)
+	})
+
+	t.Run("Moderator deletes missing post", func(t *testing.T) {
+		mockProvider := new(MockPostProvider)
+		postService := &PostService{provider: mockProvider}
+
+		mockProvider.On("Post", mock.Anything, postID).Return(nil, storage.ErrNotFound)
+
+		err := postService.DeletePost(context.Background(), postID, 1, moderatorID, model.RoleModerator)
+		assert.EqualError(t, err, fmt.Errorf("%s: %w", "service.DeletePost", ErrNotFound).Error())
+
+		mockProvider.AssertExpectations(t)
+	})
+
+	t.Run("Reader can not update post of another user", func(t *testing.T) {
+		mockProvider := new(MockPostProvider)
+		mockProcessor := new(MockPostProcessor)
+		postService := &PostService{provider: mockProvider, processor: mockProcessor}
+
+		mockProvider.On("Post", mock.Anything, postID).Return(&model.Post{ID: postID, UserID: authorID}, nil)
+		mockProcessor.On("UpdatePost", mock.Anything, mock.MatchedBy(func(post *model.Post) bool {
+			return post.ID == postID && post.UserID == moderatorID
+		}), moderatorID).Return(storage.ErrNotAllowed)
+
+		err := postService.UpdatePost(context.Background(), postID, 1, moderatorID, model.RoleReader, postReq)
+		assert.ErrorIs(t, err, ErrNotAllowed)
+
+		mockProcessor.AssertExpectations(t)
+	})
+}
+
+func TestPublication(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	futureInZone := future.In(time.FixedZone("UTC+2", 2*60*60))
+
+	tests := []struct {
+		name          string
+		current       *model.Post
+		postReq       *model.PostRequest
+		wantStatus    model.PostStatus
+		wantPublishAt *time.Time
+		wantErr       error
+	}{
+		{
+			name:          "New post is published now",
+			postReq:       &model.PostRequest{},
+			wantStatus:    model.PostStatusPublished,
+			wantPublishAt: &now,
+		},
+		{
+			name:       "New draft",
+			postReq:    &model.PostRequest{Status: model.PostStatusDraft},
+			wantStatus: model.PostStatusDraft,
+		},
+		{
+			name:          "Publish time schedules a new post",
+			postReq:       &model.PostRequest{PublishAt: &future},
+			wantStatus:    model.PostStatusScheduled,
+			wantPublishAt: &future,
+		},
+		{
+			name:          "Publish time with an offset is stored in UTC",
+			postReq:       &model.PostRequest{PublishAt: &futureInZone},
+			wantStatus:    model.PostStatusScheduled,
+			wantPublishAt: &future,
+		},
+		{
+			name:    "Schedule in the past",
+			postReq: &model.PostRequest{Status: model.PostStatusScheduled, PublishAt: &past},
+			wantErr: ErrInvalidSchedule,
+		},
+		{
+			name:    "Schedule without publish time",
+			postReq: &model.PostRequest{Status: model.PostStatusScheduled},
+			wantErr: ErrInvalidSchedule,
+		},
+		{
+			name:    "Draft with publish time",
+			postReq: &model.PostRequest{Status: model.PostStatusDraft, PublishAt: &future},
+			wantErr: ErrInvalidSchedule,
+		},
+		{
+			name:          "Update keeps the schedule",
+			current:       &model.Post{Status: model.PostStatusScheduled, PublishAt: &future},
+			postReq:       &model.PostRequest{},
+			wantStatus:    model.PostStatusScheduled,
+			wantPublishAt: &future,
+		},
+		{
+			name:          "Publishing a draft",
+			current:       &model.Post{Status: model.PostStatusDraft},
+			postReq:       &model.PostRequest{Status: model.PostStatusPublished},
+			wantStatus:    model.PostStatusPublished,
+			wantPublishAt: &now,
+		},
+		{
+			name:          "Archiving keeps the publish time",
+			current:       &model.Post{Status: model.PostStatusPublished, PublishAt: &past},
+			postReq:       &model.PostRequest{Status: model.PostStatusArchived},
+			wantStatus:    model.PostStatusArchived,
+			wantPublishAt: &past,
+		},
+		{
+			name:       "Unpublishing to a draft",
+			current:    &model.Post{Status: model.PostStatusPublished, PublishAt: &past},
+			postReq:    &model.PostRequest{Status: model.PostStatusDraft},
+			wantStatus: model.PostStatusDraft,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, publishAt, err := publication(tt.current, tt.postReq, now)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantPublishAt, publishAt)
+		})
+	}
+}
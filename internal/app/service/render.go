@@ -0,0 +1,134 @@
+package service
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/markraiter/simple-blog/internal/lib/markup"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// renderCacheSize is the number of rendered contents kept in memory.
+const renderCacheSize = 4096
+
+type renderKey struct {
+	format model.ContentFormat
+	sum    [sha256.Size]byte
+}
+
+type renderEntry struct {
+	key  renderKey
+	html string
+}
+
+// renderCache keeps the HTML of the most recently rendered contents.
+//
+// Entries are keyed by the format and a hash of the source, so every
+// revision of a post or comment is rendered once and an edit never shows
+// the HTML of the previous revision. A nil cache renders every time.
+type renderCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[renderKey]*list.Element
+	order   *list.List
+}
+
+func newRenderCache(size int) *renderCache {
+	return &renderCache{
+		size:    size,
+		entries: make(map[renderKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// render returns the sanitised HTML of the source in the format. Content
+// without a format is plain text.
+func (c *renderCache) render(format model.ContentFormat, source string) (string, error) {
+	if c == nil {
+		return renderContent(format, source)
+	}
+
+	key := renderKey{format: format, sum: sha256.Sum256([]byte(source))}
+
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.mu.Unlock()
+
+		return element.Value.(*renderEntry).html, nil
+	}
+	c.mu.Unlock()
+
+	html, err := renderContent(format, source)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another request may have rendered the same source meanwhile.
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(&renderEntry{key: key, html: html})
+
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*renderEntry).key)
+		}
+	}
+
+	return html, nil
+}
+
+// renderPosts sets the ContentHTML of the posts.
+func (c *renderCache) renderPosts(posts ...*model.Post) error {
+	for _, post := range posts {
+		html, err := c.render(post.Format, post.Content)
+		if err != nil {
+			return err
+		}
+
+		post.ContentHTML = html
+	}
+
+	return nil
+}
+
+// renderComments sets the ContentHTML of the comments.
+func (c *renderCache) renderComments(comments ...*model.Comment) error {
+	for _, comment := range comments {
+		html, err := c.render(comment.Format, comment.Content)
+		if err != nil {
+			return err
+		}
+
+		comment.ContentHTML = html
+	}
+
+	return nil
+}
+
+func renderContent(format model.ContentFormat, source string) (string, error) {
+	if format == model.ContentFormatMarkdown {
+		return markup.Markdown(source)
+	}
+
+	return markup.Plain(source), nil
+}
+
+// contentFormat returns the requested format, or the current one if none
+// is requested. New content defaults to plain text.
+func contentFormat(requested, current model.ContentFormat) model.ContentFormat {
+	if requested != "" {
+		return requested
+	}
+
+	if current != "" {
+		return current
+	}
+
+	return model.ContentFormatPlain
+}
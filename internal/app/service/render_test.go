@@ -0,0 +1,86 @@
+package service
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderCache(t *testing.T) {
+	cache := newRenderCache(2)
+
+	html, err := cache.render(model.ContentFormatMarkdown, "**one**")
+	assert.NoError(t, err)
+	assert.Equal(t, "<p><strong>one</strong></p>\n", html)
+
+	// The same source in another format is another entry.
+	html, err = cache.render(model.ContentFormatPlain, "**one**")
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>**one**</p>\n", html)
+	assert.Equal(t, 2, cache.order.Len())
+
+	// A hit makes the entry the most recent one, so the plain text is
+	// evicted by the next render.
+	_, err = cache.render(model.ContentFormatMarkdown, "**one**")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.order.Len())
+
+	_, err = cache.render(model.ContentFormatMarkdown, "two")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.order.Len())
+	assert.True(t, cached(cache, model.ContentFormatMarkdown, "two"))
+	assert.True(t, cached(cache, model.ContentFormatMarkdown, "**one**"))
+	assert.False(t, cached(cache, model.ContentFormatPlain, "**one**"))
+}
+
+func cached(cache *renderCache, format model.ContentFormat, source string) bool {
+	_, ok := cache.entries[renderKey{format: format, sum: sha256.Sum256([]byte(source))}]
+
+	return ok
+}
+
+func TestRenderCache_Nil(t *testing.T) {
+	var cache *renderCache
+
+	post := &model.Post{Content: "<b>bold</b>", Format: model.ContentFormatMarkdown}
+	comment := &model.Comment{Content: "<b>bold</b>"}
+
+	assert.NoError(t, cache.renderPosts(post))
+	assert.NoError(t, cache.renderComments(comment))
+
+	assert.Equal(t, "<p><b>bold</b></p>\n", post.ContentHTML)
+	assert.Equal(t, "<p>&lt;b&gt;bold&lt;/b&gt;</p>\n", comment.ContentHTML)
+}
+
+func TestContentFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested model.ContentFormat
+		current   model.ContentFormat
+		want      model.ContentFormat
+	}{
+		{
+			name: "New content is plain",
+			want: model.ContentFormatPlain,
+		},
+		{
+			name:      "Requested",
+			requested: model.ContentFormatMarkdown,
+			current:   model.ContentFormatPlain,
+			want:      model.ContentFormatMarkdown,
+		},
+		{
+			name:    "Kept",
+			current: model.ContentFormatMarkdown,
+			want:    model.ContentFormatMarkdown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, contentFormat(tt.requested, tt.current))
+		})
+	}
+}
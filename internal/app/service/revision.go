@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/diff"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// Revisions returns the revisions of the post, the newest first.
+//
+// Like updates, the revisions are open to the author of the post and to moderators.
+func (ps *PostService) Revisions(ctx context.Context, postID, userID int, role model.Role) ([]*model.PostRevision, error) {
+	const operation = "service.Revisions"
+
+	if _, err := ps.checkEditor(ctx, postID, userID, role); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	revisions, err := ps.provider.Revisions(ctx, postID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return revisions, nil
+}
+
+// RevisionDiff returns the unified diff between two revisions of the post.
+// A revision is compared as its title, a blank line and its content.
+//
+// If either revision does not exist it returns ErrNotFound.
+func (ps *PostService) RevisionDiff(ctx context.Context, postID, from, to, userID int, role model.Role) (*model.RevisionDiff, error) {
+	const operation = "service.RevisionDiff"
+
+	if _, err := ps.checkEditor(ctx, postID, userID, role); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	fromRevision, err := ps.revision(ctx, postID, from)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	toRevision, err := ps.revision(ctx, postID, to)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &model.RevisionDiff{
+		From: from,
+		To:   to,
+		Diff: diff.Unified(
+			fmt.Sprintf("revision %d", from),
+			fmt.Sprintf("revision %d", to),
+			revisionText(fromRevision),
+			revisionText(toRevision),
+		),
+	}, nil
+}
+
+// RestoreRevision updates the post back to the title, content and format
+// of the revision, which saves them as a new revision. The tags and the
+// status of the post are kept.
+//
+// The post is restored at the version it is read at, a concurrent update
+// makes it return ErrConflict.
+func (ps *PostService) RestoreRevision(ctx context.Context, postID, number, userID int, role model.Role) error {
+	const operation = "service.RestoreRevision"
+
+	post, err := ps.checkEditor(ctx, postID, userID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	revision, err := ps.revision(ctx, postID, number)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	postReq := &model.PostRequest{
+		Title:   revision.Title,
+		Content: revision.Content,
+		Format:  revision.Format,
+	}
+
+	if err := ps.UpdatePost(ctx, postID, post.Version, userID, role, postReq); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// checkEditor returns the post, or ErrNotAllowed unless the user may update it.
+func (ps *PostService) checkEditor(ctx context.Context, postID, userID int, role model.Role) (*model.Post, error) {
+	post, err := ps.provider.Post(ctx, postID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	if post.UserID != userID && !role.CanModerate() {
+		return nil, ErrNotAllowed
+	}
+
+	return post, nil
+}
+
+func (ps *PostService) revision(ctx context.Context, postID, number int) (*model.PostRevision, error) {
+	revision, err := ps.provider.Revision(ctx, postID, number)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+func revisionText(revision *model.PostRevision) string {
+	return revision.Title + "\n\n" + revision.Content
+}
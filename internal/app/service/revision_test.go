@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockPostProvider) Revisions(ctx context.Context, postID int) ([]*model.PostRevision, error) {
+	args := m.Called(ctx, postID)
+	revisions := args.Get(0)
+	if revisions == nil {
+		return nil, args.Error(1)
+	}
+	return revisions.([]*model.PostRevision), args.Error(1)
+}
+
+func (m *MockPostProvider) Revision(ctx context.Context, postID, number int) (*model.PostRevision, error) {
+	args := m.Called(ctx, postID, number)
+	revision := args.Get(0)
+	if revision == nil {
+		return nil, args.Error(1)
+	}
+	return revision.(*model.PostRevision), args.Error(1)
+}
+
+func TestPostService_Revisions(t *testing.T) {
+	const (
+		postID   = 1
+		authorID = 1
+		otherID  = 2
+	)
+
+	mockProvider := new(MockPostProvider)
+	postService := &PostService{provider: mockProvider}
+
+	revisions := []*model.PostRevision{{Number: 2, PostID: postID}, {Number: 1, PostID: postID}}
+
+	mockProvider.On("Post", mock.Anything, postID).Return(&model.Post{ID: postID, UserID: authorID}, nil)
+	mockProvider.On("Post", mock.Anything, 3).Return(nil, storage.ErrNotFound)
+	mockProvider.On("Revisions", mock.Anything, postID).Return(revisions, nil)
+
+	got, err := postService.Revisions(context.Background(), postID, authorID, model.RoleAuthor)
+	assert.NoError(t, err)
+	assert.Equal(t, revisions, got)
+
+	got, err = postService.Revisions(context.Background(), postID, otherID, model.RoleModerator)
+	assert.NoError(t, err)
+	assert.Equal(t, revisions, got)
+
+	_, err = postService.Revisions(context.Background(), postID, otherID, model.RoleAuthor)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	_, err = postService.Revisions(context.Background(), 3, authorID, model.RoleAuthor)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mockProvider.AssertExpectations(t)
+}
+
+func TestPostService_RevisionDiff(t *testing.T) {
+	mockProvider := new(MockPostProvider)
+	postService := &PostService{provider: mockProvider}
+
+	mockProvider.On("Post", mock.Anything, 1).Return(&model.Post{ID: 1, UserID: 1}, nil)
+	mockProvider.On("Revision", mock.Anything, 1, 1).Return(&model.PostRevision{Number: 1, Title: "Title", Content: "one\ntwo"}, nil)
+	mockProvider.On("Revision", mock.Anything, 1, 2).Return(&model.PostRevision{Number: 2, Title: "Title", Content: "one\n2"}, nil)
+	mockProvider.On("Revision", mock.Anything, 1, 9).Return(nil, storage.ErrNotFound)
+
+	revisionDiff, err := postService.RevisionDiff(context.Background(), 1, 1, 2, 1, model.RoleAuthor)
+	assert.NoError(t, err)
+	assert.Equal(t, &model.RevisionDiff{
+		From: 1,
+		To:   2,
+		Diff: "--- revision 1\n+++ revision 2\n@@ -1,4 +1,4 @@\n Title\n \n one\n-two\n+2\n",
+	}, revisionDiff)
+
+	_, err = postService.RevisionDiff(context.Background(), 1, 1, 9, 1, model.RoleAuthor)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = postService.RevisionDiff(context.Background(), 1, 1, 2, 2, model.RoleReader)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	mockProvider.AssertExpectations(t)
+}
+
+func TestPostService_RestoreRevision(t *testing.T) {
+	const (
+		postID      = 1
+		authorID    = 1
+		moderatorID = 2
+	)
+
+	mockProvider := new(MockPostProvider)
+	mockProcessor := new(MockPostProcessor)
+	postService := &PostService{provider: mockProvider, processor: mockProcessor}
+
+	mockProvider.On("Post", mock.Anything, postID).Return(&model.Post{ID: postID, UserID: authorID, Format: model.ContentFormatMarkdown, Status: model.PostStatusDraft, Version: 4}, nil)
+	mockProvider.On("Revision", mock.Anything, postID, 1).Return(&model.PostRevision{Number: 1, PostID: postID, Title: "Old Title", Content: "Old Content", Format: model.ContentFormatPlain}, nil)
+	mockProvider.On("Revision", mock.Anything, postID, 5).Return(nil, storage.ErrNotFound)
+
+	// A moderator restores on behalf of the author, the post gets back the
+	// format of the revision, stays a draft and keeps its tags. It is
+	// updated at the version it is read at.
+	mockProcessor.On("UpdatePost", mock.Anything, mock.MatchedBy(func(post *model.Post) bool {
+		return post.ID == postID && post.UserID == authorID && post.Title == "Old Title" && post.Content == "Old Content" &&
+			post.Format == model.ContentFormatPlain && post.Status == model.PostStatusDraft && post.Tags == nil && post.Version == 4
+	}), moderatorID).Return(nil).Once()
+
+	err := postService.RestoreRevision(context.Background(), postID, 1, moderatorID, model.RoleModerator)
+	assert.NoError(t, err)
+
+	err = postService.RestoreRevision(context.Background(), postID, 5, authorID, model.RoleAuthor)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = postService.RestoreRevision(context.Background(), postID, 1, moderatorID, model.RoleAuthor)
+	assert.ErrorIs(t, err, ErrNotAllowed)
+
+	mockProvider.AssertExpectations(t)
+	mockProcessor.AssertExpectations(t)
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+)
+
+type RevocationStorage interface {
+	RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error
+	TokenRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeUserTokens(ctx context.Context, userID int, revokedAt time.Time) error
+	UserTokensRevokedAt(ctx context.Context, userID int) (time.Time, error)
+	TouchSession(ctx context.Context, sessionID string) (bool, error)
+	RevokeSession(ctx context.Context, userID int, sessionID string) error
+	RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) ([]string, error)
+}
+
+type cachedToken struct {
+	revoked bool
+	until   time.Time
+}
+
+type cachedUser struct {
+	revokedAt time.Time
+	until     time.Time
+}
+
+// RevocationService keeps the list of revoked access tokens and sessions.
+//
+// The database is the source of truth, answers are cached in memory so
+// that not every authenticated request hits the database. Revocations made
+// through this instance are visible immediately, revocations made by other
+// instances become visible after the cache TTL. For the same reason the
+// last-seen time of a session is updated at most once per TTL.
+type RevocationService struct {
+	storage RevocationStorage
+	ttl     time.Duration
+
+	mu       sync.RWMutex
+	tokens   map[string]cachedToken
+	sessions map[string]cachedToken
+	users    map[int]cachedUser
+	prunedAt time.Time
+}
+
+func NewRevocationService(storage RevocationStorage, ttl time.Duration) *RevocationService {
+	return &RevocationService{
+		storage:  storage,
+		ttl:      ttl,
+		tokens:   make(map[string]cachedToken),
+		sessions: make(map[string]cachedToken),
+		users:    make(map[int]cachedUser),
+	}
+}
+
+// RevokeToken revokes a single access token until it expires.
+func (rs *RevocationService) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	const operation = "service.RevokeToken"
+
+	if err := rs.storage.RevokeToken(ctx, jti, userID, expiresAt); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	rs.mu.Lock()
+	rs.tokens[jti] = cachedToken{revoked: true, until: expiresAt}
+	rs.mu.Unlock()
+
+	return nil
+}
+
+// RevokeUserTokens revokes every token issued to the user so far.
+func (rs *RevocationService) RevokeUserTokens(ctx context.Context, userID int) error {
+	const operation = "service.RevokeUserTokens"
+
+	// The column has microsecond precision and no time zone.
+	revokedAt := time.Now().UTC().Truncate(time.Microsecond)
+
+	if err := rs.storage.RevokeUserTokens(ctx, userID, revokedAt); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	rs.mu.Lock()
+	rs.users[userID] = cachedUser{revokedAt: revokedAt, until: revokedAt.Add(rs.ttl)}
+	rs.mu.Unlock()
+
+	return nil
+}
+
+// RevokeSession ends a session of the user, its access tokens are rejected
+// and its refresh tokens are revoked.
+func (rs *RevocationService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	const operation = "service.RevokeSession"
+
+	if err := rs.storage.RevokeSession(ctx, userID, sessionID); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	rs.mu.Lock()
+	rs.sessions[sessionID] = cachedToken{revoked: true, until: time.Now().Add(rs.ttl)}
+	rs.mu.Unlock()
+
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user except the kept one
+// and revokes the user's personal access tokens.
+//
+// Access tokens issued without a session are not affected, they stay
+// valid until they expire.
+func (rs *RevocationService) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	const operation = "service.RevokeOtherSessions"
+
+	sessionIDs, err := rs.storage.RevokeOtherSessions(ctx, userID, keepSessionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	until := time.Now().Add(rs.ttl)
+
+	rs.mu.Lock()
+	for _, sessionID := range sessionIDs {
+		rs.sessions[sessionID] = cachedToken{revoked: true, until: until}
+	}
+	rs.mu.Unlock()
+
+	return nil
+}
+
+// IsRevoked reports whether the token described by claims has been revoked,
+// either by itself, by ending its session or by logging the user out from
+// all devices.
+func (rs *RevocationService) IsRevoked(ctx context.Context, claims *jwt.TokenClaims) (bool, error) {
+	const operation = "service.IsRevoked"
+
+	userID, err := strconv.Atoi(claims.UID)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	revokedAt, err := rs.userTokensRevokedAt(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return true, nil
+		}
+
+		return false, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !revokedAt.IsZero() && !claims.IssuedAt.After(revokedAt) {
+		return true, nil
+	}
+
+	if claims.SessionID != "" {
+		revoked, err := rs.sessionRevoked(ctx, claims.SessionID, time.Unix(claims.Exp, 0))
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		if revoked {
+			return true, nil
+		}
+	}
+
+	if claims.ID == "" {
+		return false, nil
+	}
+
+	revoked, err := rs.tokenRevoked(ctx, claims.ID, time.Unix(claims.Exp, 0))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return revoked, nil
+}
+
+func (rs *RevocationService) sessionRevoked(ctx context.Context, sessionID string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+
+	rs.mu.RLock()
+	cached, ok := rs.sessions[sessionID]
+	rs.mu.RUnlock()
+
+	if ok && now.Before(cached.until) {
+		return cached.revoked, nil
+	}
+
+	revoked, err := rs.storage.TouchSession(ctx, sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	until := now.Add(rs.ttl)
+	if revoked && expiresAt.After(until) {
+		until = expiresAt
+	}
+
+	rs.mu.Lock()
+	rs.pruneLocked(now)
+	rs.sessions[sessionID] = cachedToken{revoked: revoked, until: until}
+	rs.mu.Unlock()
+
+	return revoked, nil
+}
+
+func (rs *RevocationService) tokenRevoked(ctx context.Context, jti string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+
+	rs.mu.RLock()
+	cached, ok := rs.tokens[jti]
+	rs.mu.RUnlock()
+
+	if ok && now.Before(cached.until) {
+		return cached.revoked, nil
+	}
+
+	revoked, err := rs.storage.TokenRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	until := now.Add(rs.ttl)
+	if revoked || expiresAt.Before(until) {
+		until = expiresAt
+	}
+
+	rs.mu.Lock()
+	rs.pruneLocked(now)
+	rs.tokens[jti] = cachedToken{revoked: revoked, until: until}
+	rs.mu.Unlock()
+
+	return revoked, nil
+}
+
+func (rs *RevocationService) userTokensRevokedAt(ctx context.Context, userID int) (time.Time, error) {
+	now := time.Now()
+
+	rs.mu.RLock()
+	cached, ok := rs.users[userID]
+	rs.mu.RUnlock()
+
+	if ok && now.Before(cached.until) {
+		return cached.revokedAt, nil
+	}
+
+	revokedAt, err := rs.storage.UserTokensRevokedAt(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	rs.mu.Lock()
+	rs.pruneLocked(now)
+	rs.users[userID] = cachedUser{revokedAt: revokedAt, until: now.Add(rs.ttl)}
+	rs.mu.Unlock()
+
+	return revokedAt, nil
+}
+
+// pruneLocked drops stale cache entries so the cache does not grow with
+// every token ever seen. It runs at most once per TTL.
+// The caller must hold the write lock.
+func (rs *RevocationService) pruneLocked(now time.Time) {
+	if now.Sub(rs.prunedAt) < rs.ttl {
+		return
+	}
+
+	rs.prunedAt = now
+
+	for jti, cached := range rs.tokens {
+		if now.After(cached.until) {
+			delete(rs.tokens, jti)
+		}
+	}
+
+	for sessionID, cached := range rs.sessions {
+		if now.After(cached.until) {
+			delete(rs.sessions, sessionID)
+		}
+	}
+
+	for userID, cached := range rs.users {
+		if now.After(cached.until) {
+			delete(rs.users, userID)
+		}
+	}
+}
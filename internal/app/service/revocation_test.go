@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mocks
+type MockRevocationStorage struct{ mock.Mock }
+
+func (m *MockRevocationStorage) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	args := m.Called(ctx, jti, userID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRevocationStorage) TokenRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRevocationStorage) RevokeUserTokens(ctx context.Context, userID int, revokedAt time.Time) error {
+	args := m.Called(ctx, userID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockRevocationStorage) UserTokensRevokedAt(ctx context.Context, userID int) (time.Time, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockRevocationStorage) TouchSession(ctx context.Context, sessionID string) (bool, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRevocationStorage) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
+func (m *MockRevocationStorage) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) ([]string, error) {
+	args := m.Called(ctx, userID, keepSessionID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// Tests
+func TestRevocationService_IsRevoked(t *testing.T) {
+	now := time.Now()
+	second := now.Truncate(time.Second)
+
+	tests := []struct {
+		name           string
+		claims         *jwt.TokenClaims
+		userRevoked    time.Time
+		userErr        error
+		sessionRevoked bool
+		expectSession  bool
+		tokenRevoked   bool
+		expectToken    bool
+		want           bool
+	}{
+		{
+			name:         "Not revoked",
+			claims:       &jwt.TokenClaims{ID: "jti", UID: "1", IssuedAt: now, Exp: now.Add(time.Hour).Unix()},
+			tokenRevoked: false,
+			expectToken:  true,
+			want:         false,
+		},
+		{
+			name:         "Token revoked",
+			claims:       &jwt.TokenClaims{ID: "jti", UID: "1", IssuedAt: now, Exp: now.Add(time.Hour).Unix()},
+			tokenRevoked: true,
+			expectToken:  true,
+			want:         true,
+		},
+		{
+			name:        "Issued before logout from all devices",
+			claims:      &jwt.TokenClaims{ID: "jti", UID: "1", IssuedAt: now.Add(-time.Minute), Exp: now.Add(time.Hour).Unix()},
+			userRevoked: now,
+			want:        true,
+		},
+		{
+			name:         "Issued after logout from all devices",
+			claims:       &jwt.TokenClaims{ID: "jti", UID: "1", IssuedAt: now.Add(time.Minute), Exp: now.Add(time.Hour).Unix()},
+			userRevoked:  now,
+			tokenRevoked: false,
+			expectToken:  true,
+			want:         false,
+		},
+		{
+			name:        "Issued earlier in the second of the logout",
+			claims:      &jwt.TokenClaims{ID: "jti", UID: "1", IssuedAt: second.Add(300 * time.Millisecond), Exp: now.Add(time.Hour).Unix()},
+			userRevoked: second.Add(500 * time.Millisecond),
+			want:        true,
+		},
+		{
+			name:         "Issued later in the second of the logout",
+			claims:       &jwt.TokenClaims{ID: "jti", UID: "1", IssuedAt: second.Add(700 * time.Millisecond), Exp: now.Add(time.Hour).Unix()},
+			userRevoked:  second.Add(500 * time.Millisecond),
+			tokenRevoked: false,
+			expectToken:  true,
+			want:         false,
+		},
+		{
+			name:          "Session active",
+			claims:        &jwt.TokenClaims{ID: "jti", SessionID: "sid", UID: "1", IssuedAt: now, Exp: now.Add(time.Hour).Unix()},
+			expectSession: true,
+			expectToken:   true,
+			want:          false,
+		},
+		{
+			name:           "Session revoked",
+			claims:         &jwt.TokenClaims{ID: "jti", SessionID: "sid", UID: "1", IssuedAt: now, Exp: now.Add(time.Hour).Unix()},
+			sessionRevoked: true,
+			expectSession:  true,
+			want:           true,
+		},
+		{
+			name:    "User deleted",
+			claims:  &jwt.TokenClaims{ID: "jti", UID: "1", IssuedAt: now, Exp: now.Add(time.Hour).Unix()},
+			userErr: storage.ErrNotFound,
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockRevocationStorage)
+			rs := NewRevocationService(mockStorage, time.Minute)
+
+			// Errors are not cached, so a deleted user is looked up every time.
+			lookups := 1
+			if tt.userErr != nil {
+				lookups = 2
+			}
+
+			mockStorage.On("UserTokensRevokedAt", mock.Anything, 1).Return(tt.userRevoked, tt.userErr).Times(lookups)
+
+			if tt.expectSession {
+				mockStorage.On("TouchSession", mock.Anything, "sid").Return(tt.sessionRevoked, nil).Once()
+			}
+
+			if tt.expectToken {
+				mockStorage.On("TokenRevoked", mock.Anything, "jti").Return(tt.tokenRevoked, nil).Once()
+			}
+
+			revoked, err := rs.IsRevoked(context.Background(), tt.claims)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, revoked)
+
+			// The second check must be answered from the cache.
+			revoked, err = rs.IsRevoked(context.Background(), tt.claims)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, revoked)
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevocationService_RevokeToken(t *testing.T) {
+	mockStorage := new(MockRevocationStorage)
+	rs := NewRevocationService(mockStorage, time.Minute)
+
+	exp := time.Now().Add(time.Hour)
+	claims := &jwt.TokenClaims{ID: "jti", UID: "1", IssuedAt: time.Now(), Exp: exp.Unix()}
+
+	mockStorage.On("RevokeToken", mock.Anything, "jti", 1, exp).Return(nil).Once()
+	mockStorage.On("UserTokensRevokedAt", mock.Anything, 1).Return(time.Time{}, nil).Once()
+
+	err := rs.RevokeToken(context.Background(), "jti", 1, exp)
+	assert.NoError(t, err)
+
+	revoked, err := rs.IsRevoked(context.Background(), claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRevocationService_RevokeSession(t *testing.T) {
+	mockStorage := new(MockRevocationStorage)
+	rs := NewRevocationService(mockStorage, time.Minute)
+
+	claims := &jwt.TokenClaims{ID: "jti", SessionID: "sid", UID: "1", IssuedAt: time.Now(), Exp: time.Now().Add(time.Hour).Unix()}
+
+	mockStorage.On("RevokeSession", mock.Anything, 1, "sid").Return(nil).Once()
+	mockStorage.On("UserTokensRevokedAt", mock.Anything, 1).Return(time.Time{}, nil).Once()
+
+	err := rs.RevokeSession(context.Background(), 1, "sid")
+	assert.NoError(t, err)
+
+	revoked, err := rs.IsRevoked(context.Background(), claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRevocationService_RevokeOtherSessions(t *testing.T) {
+	mockStorage := new(MockRevocationStorage)
+	rs := NewRevocationService(mockStorage, time.Minute)
+
+	now := time.Now()
+	other := &jwt.TokenClaims{ID: "jti-1", SessionID: "other", UID: "1", IssuedAt: now, Exp: now.Add(time.Hour).Unix()}
+	current := &jwt.TokenClaims{ID: "jti-2", SessionID: "current", UID: "1", IssuedAt: now, Exp: now.Add(time.Hour).Unix()}
+
+	mockStorage.On("RevokeOtherSessions", mock.Anything, 1, "current").Return([]string{"other"}, nil).Once()
+	mockStorage.On("UserTokensRevokedAt", mock.Anything, 1).Return(time.Time{}, nil).Once()
+	mockStorage.On("TouchSession", mock.Anything, "current").Return(false, nil).Once()
+	mockStorage.On("TokenRevoked", mock.Anything, "jti-2").Return(false, nil).Once()
+
+	err := rs.RevokeOtherSessions(context.Background(), 1, "current")
+	assert.NoError(t, err)
+
+	revoked, err := rs.IsRevoked(context.Background(), other)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = rs.IsRevoked(context.Background(), current)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestRevocationService_RevokeUserTokens(t *testing.T) {
+	mockStorage := new(MockRevocationStorage)
+	rs := NewRevocationService(mockStorage, time.Minute)
+
+	var revokedAt time.Time
+
+	mockStorage.On("RevokeUserTokens", mock.Anything, 1, mock.MatchedBy(func(at time.Time) bool {
+		return at.Location() == time.UTC && at.Equal(at.Truncate(time.Microsecond))
+	})).Run(func(args mock.Arguments) { revokedAt = args.Get(2).(time.Time) }).Return(nil)
+
+	assert.NoError(t, rs.RevokeUserTokens(context.Background(), 1))
+
+	// Tokens of the same second are told apart, the one issued right
+	// after the revocation, like the session of a password reset, stays valid.
+	revoked, err := rs.IsRevoked(context.Background(), &jwt.TokenClaims{UID: "1", IssuedAt: revokedAt})
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = rs.IsRevoked(context.Background(), &jwt.TokenClaims{UID: "1", IssuedAt: revokedAt.Add(time.Microsecond)})
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/lib/sl"
+)
+
+type PostPublisher interface {
+	PublishDuePosts(ctx context.Context) (int, error)
+}
+
+// Scheduler publishes scheduled posts once their publish time has come.
+type Scheduler struct {
+	publisher PostPublisher
+	interval  time.Duration
+	log       *slog.Logger
+}
+
+func NewScheduler(p PostPublisher, cfg config.Scheduler, log *slog.Logger) *Scheduler {
+	return &Scheduler{
+		publisher: p,
+		interval:  cfg.PublishInterval,
+		log:       log,
+	}
+}
+
+// Run publishes the due posts right away and then every interval until
+// the context is done. A post is published at most one interval late.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.publish(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) publish(ctx context.Context) {
+	const operation = "service.Scheduler.publish"
+
+	log := s.log.With(slog.String("operation", operation))
+
+	published, err := s.publisher.PublishDuePosts(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error("error publishing scheduled posts", sl.Err(err))
+		}
+
+		return
+	}
+
+	if published > 0 {
+		log.Info("published scheduled posts", slog.Int("count", published))
+	}
+}
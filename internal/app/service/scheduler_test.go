@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPostPublisher struct{ mock.Mock }
+
+func (m *MockPostPublisher) PublishDuePosts(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	mockPublisher := new(MockPostPublisher)
+	scheduler := NewScheduler(mockPublisher, config.Scheduler{PublishInterval: time.Millisecond}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// A failed run does not stop the scheduler, the next tick retries.
+	mockPublisher.On("PublishDuePosts", mock.Anything).Return(0, errors.New("error")).Once()
+	mockPublisher.On("PublishDuePosts", mock.Anything).Return(2, nil).Once()
+	mockPublisher.On("PublishDuePosts", mock.Anything).Return(0, nil).Run(func(mock.Arguments) { cancel() })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		scheduler.Run(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after the context was cancelled")
+	}
+
+	mockPublisher.AssertExpectations(t)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+const defaultSearchLimit = 20
+
+// SearchProvider finds the posts and comments matching a text, best
+// matches first. It is implemented by the Postgres storage with its
+// full-text search, but any search engine can take its place.
+type SearchProvider interface {
+	Search(ctx context.Context, query *model.SearchQuery) ([]*model.SearchResult, error)
+}
+
+type SearchService struct {
+	provider SearchProvider
+}
+
+// searchCursor is the position of the next page of search results.
+// Results are ranked, so unlike posts they are paged by offset.
+type searchCursor struct {
+	Offset int `json:"o"`
+}
+
+// Search returns a page of results matching the query.
+//
+// after is the NextCursor of the previous page, a malformed one gives ErrInvalidCursor.
+func (ss *SearchService) Search(ctx context.Context, query *model.SearchQuery, after string) (*model.SearchPage, error) {
+	const operation = "service.Search"
+
+	q := *query
+
+	if q.Limit == 0 {
+		q.Limit = defaultSearchLimit
+	}
+
+	if q.Type == "" {
+		q.Type = model.SearchAll
+	}
+
+	if after != "" {
+		cursor, err := decodeSearchCursor(after)
+		if err != nil || cursor.Offset <= 0 {
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidCursor)
+		}
+
+		q.Offset = cursor.Offset
+	}
+
+	limit := q.Limit
+	// One more result tells whether there is a next page.
+	q.Limit++
+
+	results, err := ss.provider.Search(ctx, &q)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	page := &model.SearchPage{Results: results}
+
+	if len(results) > limit {
+		page.Results = results[:limit]
+
+		page.NextCursor, err = encodeSearchCursor(searchCursor{Offset: q.Offset + limit})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	return page, nil
+}
+
+func encodeSearchCursor(cursor searchCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeSearchCursor(value string) (*searchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &searchCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSearchProvider struct{ mock.Mock }
+
+func (m *MockSearchProvider) Search(ctx context.Context, query *model.SearchQuery) ([]*model.SearchResult, error) {
+	args := m.Called(ctx, query)
+	results := args.Get(0)
+	if results == nil {
+		return nil, args.Error(1)
+	}
+	return results.([]*model.SearchResult), args.Error(1)
+}
+
+func TestSearchService_Search(t *testing.T) {
+	results := []*model.SearchResult{
+		{Type: model.SearchResultPost, ID: 1, PostID: 1},
+		{Type: model.SearchResultComment, ID: 2, PostID: 1},
+		{Type: model.SearchResultComment, ID: 3, PostID: 1},
+	}
+
+	secondPage, err := encodeSearchCursor(searchCursor{Offset: 2})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		query       *model.SearchQuery
+		after       string
+		wantQuery   *model.SearchQuery
+		mockReturn  []*model.SearchResult
+		mockErr     error
+		wantResults []*model.SearchResult
+		wantNext    string
+		wantErr     error
+	}{
+		{
+			name:        "Defaults",
+			query:       &model.SearchQuery{Text: "golang"},
+			wantQuery:   &model.SearchQuery{Text: "golang", Type: model.SearchAll, Limit: defaultSearchLimit + 1},
+			mockReturn:  results,
+			wantResults: results,
+		},
+		{
+			name:        "First Page",
+			query:       &model.SearchQuery{Text: "golang", Type: model.SearchComments, Limit: 2},
+			wantQuery:   &model.SearchQuery{Text: "golang", Type: model.SearchComments, Limit: 3},
+			mockReturn:  results,
+			wantResults: results[:2],
+			wantNext:    secondPage,
+		},
+		{
+			name:        "Second Page",
+			query:       &model.SearchQuery{Text: "golang", Limit: 2},
+			after:       secondPage,
+			wantQuery:   &model.SearchQuery{Text: "golang", Type: model.SearchAll, Limit: 3, Offset: 2},
+			mockReturn:  results[2:],
+			wantResults: results[2:],
+		},
+		{
+			name:    "Malformed Cursor",
+			query:   &model.SearchQuery{Text: "golang"},
+			after:   "offset=2",
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:      "Error",
+			query:     &model.SearchQuery{Text: "golang"},
+			wantQuery: &model.SearchQuery{Text: "golang", Type: model.SearchAll, Limit: defaultSearchLimit + 1},
+			mockErr:   errors.New("error"),
+			wantErr:   errors.New("error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockSearchProvider)
+			searchService := &SearchService{provider: mockProvider}
+
+			if tt.wantQuery != nil {
+				mockProvider.On("Search", mock.Anything, tt.wantQuery).Return(tt.mockReturn, tt.mockErr)
+			}
+
+			page, err := searchService.Search(context.Background(), tt.query, tt.after)
+
+			if tt.wantErr != nil {
+				assert.ErrorContains(t, err, tt.wantErr.Error())
+				assert.Nil(t, page)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantResults, page.Results)
+				assert.Equal(t, tt.wantNext, page.NextCursor)
+			}
+
+			mockProvider.AssertExpectations(t)
+		})
+	}
+}
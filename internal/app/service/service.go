@@ -2,58 +2,153 @@ package service
 
 import (
 	"errors"
+	"log/slog"
+
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
 )
 
 var (
-	ErrAlreadyExists      = errors.New("already exists")
-	ErrNotFound           = errors.New("not found")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrNotAllowed         = errors.New("user is not allowed to perform this operation")
-	ErrPostNotExists      = errors.New("post with such ID does not exist")
+	ErrAlreadyExists        = errors.New("already exists")
+	ErrNotFound             = errors.New("not found")
+	ErrInvalidCredentials   = errors.New("invalid credentials")
+	ErrNotAllowed           = errors.New("user is not allowed to perform this operation")
+	ErrPostNotExists        = errors.New("post with such ID does not exist")
+	ErrInvalidToken         = errors.New("invalid or expired token")
+	ErrTokenReused          = errors.New("refresh token reuse detected")
+	ErrEmailNotVerified     = errors.New("email address is not verified")
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+	ErrInvalidCode          = errors.New("invalid two-factor code")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled  = errors.New("two-factor authentication is not enabled")
+	ErrTooManyAttempts      = errors.New("too many login attempts, try again later")
+	ErrInvalidExpiry        = errors.New("expiry must be in the future")
+	ErrOIDCDisabled         = errors.New("login with an identity provider is not configured")
+	ErrInvalidUsername      = errors.New("username must be at least 3 characters long")
+	ErrInvalidParent        = errors.New("parent comment does not exist in this post")
+	ErrThreadTooDeep        = errors.New("comment thread is too deep")
+	ErrInvalidCursor        = errors.New("invalid pagination cursor")
+	ErrInvalidTag           = errors.New("tag must contain letters or digits")
+	ErrInvalidSchedule      = errors.New("only scheduled posts take a publish time, which must be in the future")
+	ErrConflict             = errors.New("resource has been modified since it was read")
+	ErrMoved                = errors.New("post has moved to another slug")
 )
 
 type AuthStorage interface {
 	UserSaver
 	UserProvider
+	UserProcessor
+	RefreshTokenSaver
+	RefreshTokenProcessor
+	UserTokenSaver
+	UserTokenProcessor
+	TwoFactorProvider
+	TwoFactorProcessor
+	PersonalTokenSaver
+	PersonalTokenProvider
+	PersonalTokenProcessor
+	UserIdentitySaver
+	UserIdentityProvider
+	SessionSaver
+	SessionProvider
 }
 
 type PostStorage interface {
 	PostSaver
 	PostProvider
 	PostProcessor
+	UserVerifier
 }
 
 type CommentStorage interface {
 	CommentSaver
 	CommentProvider
 	CommentProcessor
+	UserVerifier
+}
+
+type UserStorage interface {
+	UserProfileProvider
+	UserProfileProcessor
 }
 
 type Service struct {
 	AuthService
 	PostService
 	CommentService
+	UserService
+	SearchService
 }
 
 func New(
 	a AuthStorage,
 	p PostStorage,
 	c CommentStorage,
+	u UserStorage,
+	s SearchProvider,
+	r TokenRevoker,
+	m mailer.Mailer,
+	l LoginLimiter,
+	k *jwt.KeySet,
+	o OIDCClient,
+	h PasswordHasher,
+	log *slog.Logger,
 ) *Service {
+	renderer := newRenderCache(renderCacheSize)
+
 	return &Service{
 		AuthService{
-			saver:    a,
-			provider: a,
+			saver:          a,
+			provider:       a,
+			processor:      a,
+			tokenSaver:     a,
+			tokenProcessor: a,
+			revoker:        r,
+			limiter:        l,
+			keys:           k,
+			hasher:         h,
+
+			sessionSaver:    a,
+			sessionProvider: a,
+
+			userTokenSaver:     a,
+			userTokenProcessor: a,
+			mailer:             m,
+
+			twoFactorProvider:  a,
+			twoFactorProcessor: a,
+
+			personalTokenSaver:     a,
+			personalTokenProvider:  a,
+			personalTokenProcessor: a,
+
+			oidcClient:       o,
+			identitySaver:    a,
+			identityProvider: a,
+
+			log: log,
 		},
 		PostService{
 			saver:     p,
 			provider:  p,
 			processor: p,
+			verifier:  p,
+			renderer:  renderer,
 		},
 		CommentService{
 			saver:     c,
 			provider:  c,
 			processor: c,
+			verifier:  c,
+			renderer:  renderer,
+		},
+		UserService{
+			provider:  u,
+			processor: u,
+			renderer:  renderer,
+		},
+		SearchService{
+			provider: s,
 		},
 	}
 }
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/useragent"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// maxUserAgentLength limits how much of the User-Agent header is stored.
+const maxUserAgentLength = 512
+
+type SessionSaver interface {
+	SaveSession(ctx context.Context, session *model.Session) error
+}
+
+type SessionProvider interface {
+	Sessions(ctx context.Context, userID int) ([]*model.Session, error)
+}
+
+// Sessions returns the active sessions of the user.
+//
+// The session the request was made with is marked as current.
+func (as *AuthService) Sessions(ctx context.Context, userID int, currentSessionID string) ([]*model.Session, error) {
+	const operation = "service.Sessions"
+
+	sessions, err := as.sessionProvider.Sessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends a session of the user.
+//
+// Access tokens of the session are rejected right away and it can not be
+// continued by refreshing.
+func (as *AuthService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	const operation = "service.RevokeSession"
+
+	if err := as.revoker.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// newSession describes the session of a login or a refresh made by the client.
+func newSession(sessionID string, userID int, client model.ClientInfo) *model.Session {
+	userAgent := strings.ToValidUTF8(client.UserAgent, "\uFFFD")
+	if len(userAgent) > maxUserAgentLength {
+		// Cut on a rune boundary so the stored value stays valid UTF-8.
+		end := maxUserAgentLength
+		for end > 0 && !utf8.RuneStart(userAgent[end]) {
+			end--
+		}
+
+		userAgent = userAgent[:end]
+	}
+
+	return &model.Session{
+		ID:        sessionID,
+		UserID:    userID,
+		Device:    useragent.Describe(client.UserAgent),
+		UserAgent: userAgent,
+		IP:        client.IP,
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSessionStorage struct{ mock.Mock }
+
+func (m *MockSessionStorage) SaveSession(ctx context.Context, session *model.Session) error {
+	args := m.Called(ctx, session)
+	return args.Error(0)
+}
+
+func (m *MockSessionStorage) Sessions(ctx context.Context, userID int) ([]*model.Session, error) {
+	args := m.Called(ctx, userID)
+	sessions := args.Get(0)
+	if sessions == nil {
+		return nil, args.Error(1)
+	}
+	return sessions.([]*model.Session), args.Error(1)
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	mockSessions := new(MockSessionStorage)
+	authService := &AuthService{sessionProvider: mockSessions}
+
+	mockSessions.On("Sessions", mock.Anything, 1).Return([]*model.Session{{ID: "laptop"}, {ID: "phone"}}, nil)
+
+	sessions, err := authService.Sessions(context.Background(), 1, "phone")
+	assert.NoError(t, err)
+
+	assert.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+
+	mockSessions.AssertExpectations(t)
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		revokeErr error
+		wantErr   error
+	}{
+		{name: "Success"},
+		{name: "Not Found", revokeErr: storage.ErrNotFound, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRevoker := new(MockTokenRevoker)
+			authService := &AuthService{revoker: mockRevoker}
+
+			mockRevoker.On("RevokeSession", mock.Anything, 1, "session").Return(tt.revokeErr)
+
+			err := authService.RevokeSession(context.Background(), 1, "session")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockRevoker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNewSession(t *testing.T) {
+	userAgent := "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"
+
+	session := newSession("session", 1, model.ClientInfo{IP: "10.0.0.1", UserAgent: userAgent + string(make([]byte, maxUserAgentLength))})
+
+	assert.Equal(t, "session", session.ID)
+	assert.Equal(t, 1, session.UserID)
+	assert.Equal(t, "Firefox on Linux", session.Device)
+	assert.Equal(t, "10.0.0.1", session.IP)
+	assert.Len(t, session.UserAgent, maxUserAgentLength)
+}
+
+func TestNewSession_NonASCIIUserAgent(t *testing.T) {
+	// "ж" takes two bytes, so the limit falls in the middle of a rune.
+	userAgent := "a" + strings.Repeat("ж", maxUserAgentLength)
+
+	session := newSession("session", 1, model.ClientInfo{UserAgent: userAgent})
+
+	assert.True(t, utf8.ValidString(session.UserAgent))
+	assert.Len(t, session.UserAgent, maxUserAgentLength-1)
+	assert.True(t, strings.HasPrefix(userAgent, session.UserAgent))
+
+	session = newSession("session", 1, model.ClientInfo{UserAgent: "Firefox\xff"})
+
+	assert.Equal(t, "Firefox\uFFFD", session.UserAgent)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/slug"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// defaultPostSlug is the slug of posts without letters or digits in the title.
+const defaultPostSlug = "post"
+
+// MovedError is returned when a post is looked up by a former slug.
+type MovedError struct {
+	Slug string
+}
+
+func (e *MovedError) Error() string {
+	return ErrMoved.Error()
+}
+
+func (e *MovedError) Unwrap() error {
+	return ErrMoved
+}
+
+// PostBySlug returns the post by its slug if the viewer may see it.
+//
+// If the slug is a former slug of the post it returns a MovedError with
+// the current one.
+func (ps *PostService) PostBySlug(ctx context.Context, slug string, viewerID int) (*model.Post, error) {
+	const operation = "service.PostBySlug"
+
+	post, err := ps.provider.PostBySlug(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", operation, ps.redirect(ctx, slug, viewerID))
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !visible(post, viewerID) {
+		return nil, fmt.Errorf("%s: %w", operation, ErrNotFound)
+	}
+
+	if err := ps.renderer.renderPosts(post); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return post, nil
+}
+
+// redirect returns a MovedError if the slug is a former slug of a post the
+// viewer may see, and ErrNotFound otherwise.
+func (ps *PostService) redirect(ctx context.Context, slug string, viewerID int) error {
+	post, err := ps.provider.RedirectedPost(ctx, slug)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	if !visible(post, viewerID) {
+		return ErrNotFound
+	}
+
+	return &MovedError{Slug: post.Slug}
+}
+
+// postSlug returns the slug a post with the title is given, the storage
+// adds a number suffix if another post has it.
+func postSlug(title string) string {
+	if s := slug.MakeASCII(title); s != "" {
+		return s
+	}
+
+	return defaultPostSlug
+}
+
+// visible reports whether the viewer may see the post.
+func visible(post *model.Post, viewerID int) bool {
+	return post.Status == model.PostStatusPublished || post.UserID == viewerID
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func (m *MockPostProvider) PostBySlug(ctx context.Context, slug string) (*model.Post, error) {
+	args := m.Called(ctx, slug)
+	post := args.Get(0)
+	if post == nil {
+		return nil, args.Error(1)
+	}
+	return post.(*model.Post), args.Error(1)
+}
+
+func (m *MockPostProvider) RedirectedPost(ctx context.Context, slug string) (*model.Post, error) {
+	args := m.Called(ctx, slug)
+	post := args.Get(0)
+	if post == nil {
+		return nil, args.Error(1)
+	}
+	return post.(*model.Post), args.Error(1)
+}
+
+func TestPostService_PostBySlug(t *testing.T) {
+	const (
+		authorID = 1
+		otherID  = 2
+	)
+
+	mockProvider := new(MockPostProvider)
+	postService := &PostService{provider: mockProvider}
+
+	published := &model.Post{ID: 1, Slug: "hello-world", UserID: authorID, Status: model.PostStatusPublished}
+	draft := &model.Post{ID: 2, Slug: "draft", UserID: authorID, Status: model.PostStatusDraft}
+
+	mockProvider.On("PostBySlug", mock.Anything, "hello-world").Return(published, nil)
+	mockProvider.On("PostBySlug", mock.Anything, "draft").Return(draft, nil)
+	mockProvider.On("PostBySlug", mock.Anything, "hello").Return(nil, storage.ErrNotFound)
+	mockProvider.On("PostBySlug", mock.Anything, "old-draft").Return(nil, storage.ErrNotFound)
+	mockProvider.On("PostBySlug", mock.Anything, "missing").Return(nil, storage.ErrNotFound)
+	mockProvider.On("RedirectedPost", mock.Anything, "hello").Return(published, nil)
+	mockProvider.On("RedirectedPost", mock.Anything, "old-draft").Return(draft, nil)
+	mockProvider.On("RedirectedPost", mock.Anything, "missing").Return(nil, storage.ErrNotFound)
+
+	post, err := postService.PostBySlug(context.Background(), "hello-world", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, published, post)
+
+	post, err = postService.PostBySlug(context.Background(), "draft", authorID)
+	assert.NoError(t, err)
+	assert.Equal(t, draft, post)
+
+	_, err = postService.PostBySlug(context.Background(), "draft", otherID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = postService.PostBySlug(context.Background(), "hello", otherID)
+	var moved *MovedError
+	assert.True(t, errors.As(err, &moved))
+	assert.Equal(t, "hello-world", moved.Slug)
+	assert.ErrorIs(t, err, ErrMoved)
+
+	// The former slug of a draft does not tell others its current slug.
+	_, err = postService.PostBySlug(context.Background(), "old-draft", otherID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = postService.PostBySlug(context.Background(), "old-draft", authorID)
+	assert.ErrorIs(t, err, ErrMoved)
+
+	_, err = postService.PostBySlug(context.Background(), "missing", 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mockProvider.AssertExpectations(t)
+}
+
+func TestPostSlug(t *testing.T) {
+	assert.Equal(t, "privet-mir", postSlug("Привет, мир!"))
+	assert.Equal(t, "post", postSlug("?!"))
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/slug"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// Tags returns the tags in use with the number of their posts, the most used first.
+func (ps *PostService) Tags(ctx context.Context) ([]*model.Tag, error) {
+	const operation = "service.Tags"
+
+	tags, err := ps.provider.Tags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return tags, nil
+}
+
+// TagPosts returns a page of the posts with the tag, see Posts.
+//
+// A tag no post uses gives ErrNotFound.
+func (ps *PostService) TagPosts(ctx context.Context, tag string, query *model.PostQuery, after string) (*model.PostPage, error) {
+	const operation = "service.TagPosts"
+
+	if _, err := ps.provider.Tag(ctx, slug.Make(tag)); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	q := *query
+	q.Tag = tag
+
+	page, err := ps.Posts(ctx, &q, after)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return page, nil
+}
+
+// normalizeTags returns the distinct slugs of the tags in their order.
+// Nil tags stay nil, so an update keeps the tags of the post.
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	slugs := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		s := slug.Make(tag)
+		if s == "" {
+			return nil, ErrInvalidTag
+		}
+
+		if !seen[s] {
+			seen[s] = true
+			slugs = append(slugs, s)
+		}
+	}
+
+	return slugs, nil
+}
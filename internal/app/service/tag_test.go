@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{"Go", "Web Dev", "go", "web-dev"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"go", "web-dev"}, tags)
+
+	// Nil keeps the tags of the post on update, empty removes them.
+	tags, err = normalizeTags(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, tags)
+
+	tags, err = normalizeTags([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, tags)
+
+	_, err = normalizeTags([]string{"go", "#!"})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestPostService_SavePostTags(t *testing.T) {
+	mockSaver := new(MockPostSaver)
+	mockVerifier := new(MockUserVerifier)
+	postService := &PostService{saver: mockSaver, verifier: mockVerifier}
+
+	mockVerifier.On("EmailVerified", mock.Anything, 1).Return(true, nil)
+	mockSaver.On("SavePost", mock.Anything, &model.Post{
+		Title:   "Test Title",
+		Slug:    "test-title",
+		Content: "Test Content",
+		Format:  model.ContentFormatPlain,
+		UserID:  1,
+		Tags:    []string{"golang", "web-dev"},
+		Status:  model.PostStatusDraft,
+	}).Return(1, nil)
+
+	_, err := postService.SavePost(context.Background(), 1, &model.PostRequest{
+		Title:   "Test Title",
+		Content: "Test Content",
+		Tags:    []string{"Golang", "Web Dev"},
+		Status:  model.PostStatusDraft,
+	})
+	assert.NoError(t, err)
+
+	_, err = postService.SavePost(context.Background(), 1, &model.PostRequest{
+		Title:   "Test Title",
+		Content: "Test Content",
+		Tags:    []string{"..."},
+	})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	mockSaver.AssertExpectations(t)
+}
+
+func TestPostService_TagPosts(t *testing.T) {
+	mockProvider := new(MockPostProvider)
+	postService := &PostService{provider: mockProvider}
+
+	mockProvider.On("Tag", mock.Anything, "web-dev").Return(&model.Tag{Slug: "web-dev", PostsCount: 1}, nil)
+	mockProvider.On("Posts", mock.Anything, &model.PostQuery{
+		Limit: defaultPostsLimit + 1,
+		Sort:  model.PostSortCreated,
+		Tag:   "web-dev",
+	}).Return([]*model.Post{{ID: 1, Tags: []string{"web-dev"}}}, nil)
+	mockProvider.On("Tag", mock.Anything, "unused").Return(nil, storage.ErrNotFound)
+
+	page, err := postService.TagPosts(context.Background(), "Web Dev", &model.PostQuery{}, "")
+	assert.NoError(t, err)
+	assert.Len(t, page.Posts, 1)
+
+	_, err = postService.TagPosts(context.Background(), "unused", &model.PostQuery{}, "")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mockProvider.AssertExpectations(t)
+}
+
+func TestPostService_PostsInvalidTag(t *testing.T) {
+	postService := &PostService{provider: new(MockPostProvider)}
+
+	_, err := postService.Posts(context.Background(), &model.PostQuery{Tag: "?"}, "")
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
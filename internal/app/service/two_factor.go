@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/lib/totp"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// recoveryCodeCount is the number of recovery codes issued at once.
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TwoFactorProvider interface {
+	TwoFactor(ctx context.Context, userID int) (*model.TwoFactor, error)
+}
+
+type TwoFactorProcessor interface {
+	SaveTOTPSecret(ctx context.Context, userID int, secret string) error
+	EnableTOTP(ctx context.Context, userID int, codeHashes []string) error
+	DisableTOTP(ctx context.Context, userID int) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+}
+
+// EnrollTwoFactor generates a new TOTP secret for the user.
+//
+// The second factor is not required until the enrollment is confirmed,
+// so a user who never finishes the setup is not locked out.
+func (as *AuthService) EnrollTwoFactor(ctx context.Context, cfg config.Auth, userID int) (*model.TwoFactorEnrollment, error) {
+	const operation = "service.EnrollTwoFactor"
+
+	user, err := as.provider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	totpSecret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.twoFactorProcessor.SaveTOTPSecret(ctx, userID, totpSecret); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrTwoFactorEnabled)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &model.TwoFactorEnrollment{
+		Secret: totpSecret,
+		URI:    totp.URI(cfg.TwoFactorIssuer, user.Email, totpSecret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables the second factor once the user proves the
+// authenticator app is set up, and returns the initial recovery codes.
+func (as *AuthService) ConfirmTwoFactor(ctx context.Context, userID int, code string) ([]string, error) {
+	const operation = "service.ConfirmTwoFactor"
+
+	tf, err := as.twoFactor(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if tf.Enabled() {
+		return nil, fmt.Errorf("%s: %w", operation, ErrTwoFactorEnabled)
+	}
+
+	if tf.Secret == "" {
+		return nil, fmt.Errorf("%s: %w", operation, ErrTwoFactorNotEnabled)
+	}
+
+	step, ok := totp.Step(tf.Secret, code, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidCode)
+	}
+
+	if err := as.useTOTPStep(ctx, userID, tf, step); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.twoFactorProcessor.EnableTOTP(ctx, userID, hashes); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrTwoFactorNotEnabled)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns the second factor off. It requires a valid TOTP or recovery code.
+func (as *AuthService) DisableTwoFactor(ctx context.Context, userID int, code string) error {
+	const operation = "service.DisableTwoFactor"
+
+	tf, err := as.twoFactor(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !tf.Enabled() {
+		return fmt.Errorf("%s: %w", operation, ErrTwoFactorNotEnabled)
+	}
+
+	if err := as.checkSecondFactor(ctx, userID, tf, code); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.twoFactorProcessor.DisableTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes issues new recovery codes, the old ones stop working.
+//
+// It requires a valid TOTP code, so a leaked recovery code can not be
+// used to get a fresh set.
+func (as *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	const operation = "service.RegenerateRecoveryCodes"
+
+	tf, err := as.twoFactor(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !tf.Enabled() {
+		return nil, fmt.Errorf("%s: %w", operation, ErrTwoFactorNotEnabled)
+	}
+
+	step, ok := totp.Step(tf.Secret, code, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidCode)
+	}
+
+	if err := as.useTOTPStep(ctx, userID, tf, step); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.twoFactorProcessor.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return codes, nil
+}
+
+// LoginTwoFactor finishes the login of an account with the second factor enabled.
+//
+// It exchanges the challenge token returned by Login and a TOTP or
+// recovery code for a new access/refresh token pair. Wrong codes count
+// as failed login attempts of the account.
+func (as *AuthService) LoginTwoFactor(ctx context.Context, cfg config.Auth, challengeToken, code string, client model.ClientInfo) (*model.TokenPair, error) {
+	const operation = "service.LoginTwoFactor"
+
+	userID, err := jwt.ParseChallengeToken(challengeToken, as.keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+	}
+
+	user, err := as.provider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tf, err := as.twoFactor(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	// The second factor was disabled after the challenge was issued.
+	if !tf.Enabled() {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+	}
+
+	if wait := as.limiter.Check(user.Email, client.IP); wait > 0 {
+		return nil, fmt.Errorf("%s: %w", operation, &LockedError{RetryAfter: wait})
+	}
+
+	if err := as.checkSecondFactor(ctx, userID, tf, code); err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			as.limiter.Fail(user.Email, client.IP)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	sessionID, err := secret.Generate(16)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tokens, err := as.issueTokens(ctx, cfg, user, sessionID, client)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	as.limiter.Succeed(user.Email)
+
+	return tokens, nil
+}
+
+func (as *AuthService) twoFactor(ctx context.Context, userID int) (*model.TwoFactor, error) {
+	tf, err := as.twoFactorProvider.TwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return tf, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code.
+func (as *AuthService) checkSecondFactor(ctx context.Context, userID int, tf *model.TwoFactor, code string) error {
+	if step, ok := totp.Step(tf.Secret, code, time.Now()); ok {
+		return as.useTOTPStep(ctx, userID, tf, step)
+	}
+
+	err := as.twoFactorProcessor.UseRecoveryCode(ctx, userID, secret.Hash(normalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// useTOTPStep accepts a TOTP code of the time step only once.
+//
+// A code stays valid for a while, so a code seen by someone else must
+// not let them in again. Codes of the last accepted step or an earlier
+// one give ErrInvalidCode.
+func (as *AuthService) useTOTPStep(ctx context.Context, userID int, tf *model.TwoFactor, step int64) error {
+	if step <= tf.LastStep {
+		return ErrInvalidCode
+	}
+
+	if err := as.twoFactorProcessor.UseTOTPStep(ctx, userID, step); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrInvalidCode
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// newRecoveryCodes returns recovery codes to show to the user and their hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for range recoveryCodeCount {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		code = code[:4] + "-" + code[4:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, secret.Hash(normalizeRecoveryCode(code)))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/jwt"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/lib/totp"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockTwoFactorProvider struct{ mock.Mock }
+
+func (m *MockTwoFactorProvider) TwoFactor(ctx context.Context, userID int) (*model.TwoFactor, error) {
+	args := m.Called(ctx, userID)
+	tf := args.Get(0)
+	if tf == nil {
+		return nil, args.Error(1)
+	}
+	return tf.(*model.TwoFactor), args.Error(1)
+}
+
+type MockTwoFactorProcessor struct{ mock.Mock }
+
+func (m *MockTwoFactorProcessor) SaveTOTPSecret(ctx context.Context, userID int, secret string) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorProcessor) EnableTOTP(ctx context.Context, userID int, codeHashes []string) error {
+	args := m.Called(ctx, userID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorProcessor) DisableTOTP(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorProcessor) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	args := m.Called(ctx, userID, codeHashes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorProcessor) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorProcessor) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func TestAuthService_LoginWithTwoFactor(t *testing.T) {
+	cfg := config.Auth{SigningKey: "testKey", AccessTTL: time.Minute, RefreshTTL: time.Hour, TwoFactorTTL: time.Minute}
+	keys := newTestKeySet(t, cfg)
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte("Password12345!"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	user := &model.User{ID: 1, Username: "user", Email: "email@example.com", Password: string(passHash), Role: model.RoleAuthor}
+	enabledAt := time.Now()
+
+	tests := []struct {
+		name          string
+		twoFactor     *model.TwoFactor
+		wantChallenge bool
+	}{
+		{
+			name:          "Without Second Factor",
+			twoFactor:     &model.TwoFactor{},
+			wantChallenge: false,
+		},
+		{
+			name:          "Enrolled But Not Confirmed",
+			twoFactor:     &model.TwoFactor{Secret: "JBSWY3DPEHPK3PXP"},
+			wantChallenge: false,
+		},
+		{
+			name:          "With Second Factor",
+			twoFactor:     &model.TwoFactor{Secret: "JBSWY3DPEHPK3PXP", EnabledAt: &enabledAt},
+			wantChallenge: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockUserProvider)
+			mockTwoFactor := new(MockTwoFactorProvider)
+			mockTokenSaver := new(MockRefreshTokenSaver)
+			mockSessions := new(MockSessionStorage)
+			authService := &AuthService{provider: mockProvider, twoFactorProvider: mockTwoFactor, tokenSaver: mockTokenSaver, sessionSaver: mockSessions, limiter: newTestLoginGuard(), hasher: newTestHasher(t), keys: keys}
+
+			mockProvider.On("User", mock.Anything, user.Email).Return(user, nil)
+			mockTwoFactor.On("TwoFactor", mock.Anything, user.ID).Return(tt.twoFactor, nil)
+
+			if !tt.wantChallenge {
+				mockSessions.On("SaveSession", mock.Anything, mock.AnythingOfType("*model.Session")).Return(nil)
+				mockTokenSaver.On("SaveRefreshToken", mock.Anything, mock.AnythingOfType("*model.RefreshToken")).Return(nil)
+			}
+
+			resp, err := authService.Login(context.Background(), cfg, user.Email, "Password12345!", model.ClientInfo{IP: "127.0.0.1"})
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.wantChallenge, resp.TwoFactorRequired)
+
+			if tt.wantChallenge {
+				assert.Nil(t, resp.TokenPair)
+
+				userID, err := jwt.ParseChallengeToken(resp.ChallengeToken, keys)
+				assert.NoError(t, err)
+				assert.Equal(t, user.ID, userID)
+			} else {
+				assert.NotEmpty(t, resp.AccessToken)
+				assert.Empty(t, resp.ChallengeToken)
+			}
+
+			mockProvider.AssertExpectations(t)
+			mockTwoFactor.AssertExpectations(t)
+			mockTokenSaver.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_LoginTwoFactor(t *testing.T) {
+	cfg := config.Auth{SigningKey: "testKey", AccessTTL: time.Minute, RefreshTTL: time.Hour}
+	keys := newTestKeySet(t, cfg)
+
+	totpSecret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	validCode, err := totp.Code(totpSecret, time.Now())
+	assert.NoError(t, err)
+
+	challenge, err := jwt.NewChallengeToken(keys, 1, time.Minute)
+	assert.NoError(t, err)
+
+	user := &model.User{ID: 1, Username: "user", Email: "email@example.com", Role: model.RoleAuthor}
+	enabledAt := time.Now()
+	enabled := &model.TwoFactor{Secret: totpSecret, EnabledAt: &enabledAt}
+	// The step of the next period, so the current code has already been used.
+	usedStep := time.Now().Unix()/totp.Period + 1
+
+	tests := []struct {
+		name         string
+		challenge    string
+		code         string
+		twoFactor    *model.TwoFactor
+		totpCode     bool
+		totpErr      error
+		recoveryCode bool
+		recoveryErr  error
+		wantErr      error
+	}{
+		{
+			name:      "TOTP Code",
+			challenge: challenge,
+			code:      validCode,
+			twoFactor: enabled,
+			totpCode:  true,
+		},
+		{
+			name:      "Replayed TOTP Code",
+			challenge: challenge,
+			code:      validCode,
+			twoFactor: &model.TwoFactor{Secret: totpSecret, EnabledAt: &enabledAt, LastStep: usedStep},
+			wantErr:   ErrInvalidCode,
+		},
+		{
+			name:      "TOTP Code Used Meanwhile",
+			challenge: challenge,
+			code:      validCode,
+			twoFactor: enabled,
+			totpCode:  true,
+			totpErr:   storage.ErrNotFound,
+			wantErr:   ErrInvalidCode,
+		},
+		{
+			name:         "Recovery Code",
+			challenge:    challenge,
+			code:         "ABCD-EFGH",
+			twoFactor:    enabled,
+			recoveryCode: true,
+		},
+		{
+			name:         "Used Recovery Code",
+			challenge:    challenge,
+			code:         "abcd-efgh",
+			twoFactor:    enabled,
+			recoveryCode: true,
+			recoveryErr:  storage.ErrNotFound,
+			wantErr:      ErrInvalidCode,
+		},
+		{
+			name:      "Invalid Challenge",
+			challenge: "invalid",
+			code:      validCode,
+			wantErr:   ErrInvalidToken,
+		},
+		{
+			name:      "Second Factor Disabled Meanwhile",
+			challenge: challenge,
+			code:      validCode,
+			twoFactor: &model.TwoFactor{},
+			wantErr:   ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockUserProvider)
+			mockTwoFactor := new(MockTwoFactorProvider)
+			mockProcessor := new(MockTwoFactorProcessor)
+			mockTokenSaver := new(MockRefreshTokenSaver)
+			mockSessions := new(MockSessionStorage)
+			authService := &AuthService{
+				provider:           mockProvider,
+				twoFactorProvider:  mockTwoFactor,
+				twoFactorProcessor: mockProcessor,
+				tokenSaver:         mockTokenSaver,
+				sessionSaver:       mockSessions,
+				limiter:            newTestLoginGuard(),
+				hasher:             newTestHasher(t),
+				keys:               keys,
+			}
+
+			if tt.twoFactor != nil {
+				mockProvider.On("UserByID", mock.Anything, user.ID).Return(user, nil)
+				mockTwoFactor.On("TwoFactor", mock.Anything, user.ID).Return(tt.twoFactor, nil)
+			}
+
+			if tt.totpCode {
+				mockProcessor.On("UseTOTPStep", mock.Anything, user.ID, mock.AnythingOfType("int64")).Return(tt.totpErr)
+			}
+
+			if tt.recoveryCode {
+				mockProcessor.On("UseRecoveryCode", mock.Anything, user.ID, secret.Hash("abcdefgh")).Return(tt.recoveryErr)
+			}
+
+			if tt.wantErr == nil {
+				mockSessions.On("SaveSession", mock.Anything, mock.AnythingOfType("*model.Session")).Return(nil)
+				mockTokenSaver.On("SaveRefreshToken", mock.Anything, mock.AnythingOfType("*model.RefreshToken")).Return(nil)
+			}
+
+			tokens, err := authService.LoginTwoFactor(context.Background(), cfg, tt.challenge, tt.code, model.ClientInfo{IP: "127.0.0.1"})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, tokens)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, tokens.AccessToken)
+			}
+
+			mockProvider.AssertExpectations(t)
+			mockTwoFactor.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+			mockTokenSaver.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_ConfirmTwoFactor(t *testing.T) {
+	totpSecret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	validCode, err := totp.Code(totpSecret, time.Now())
+	assert.NoError(t, err)
+
+	enabledAt := time.Now()
+
+	tests := []struct {
+		name      string
+		twoFactor *model.TwoFactor
+		code      string
+		wantErr   error
+	}{
+		{
+			name:      "Success",
+			twoFactor: &model.TwoFactor{Secret: totpSecret},
+			code:      validCode,
+		},
+		{
+			name:      "Replayed Code",
+			twoFactor: &model.TwoFactor{Secret: totpSecret, LastStep: time.Now().Unix()/totp.Period + 1},
+			code:      validCode,
+			wantErr:   ErrInvalidCode,
+		},
+		{
+			name:      "Invalid Code",
+			twoFactor: &model.TwoFactor{Secret: totpSecret},
+			code:      "000000",
+			wantErr:   ErrInvalidCode,
+		},
+		{
+			name:      "Not Enrolled",
+			twoFactor: &model.TwoFactor{},
+			code:      validCode,
+			wantErr:   ErrTwoFactorNotEnabled,
+		},
+		{
+			name:      "Already Enabled",
+			twoFactor: &model.TwoFactor{Secret: totpSecret, EnabledAt: &enabledAt},
+			code:      validCode,
+			wantErr:   ErrTwoFactorEnabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTwoFactor := new(MockTwoFactorProvider)
+			mockProcessor := new(MockTwoFactorProcessor)
+			authService := &AuthService{twoFactorProvider: mockTwoFactor, twoFactorProcessor: mockProcessor}
+
+			mockTwoFactor.On("TwoFactor", mock.Anything, 1).Return(tt.twoFactor, nil)
+
+			if tt.wantErr == nil {
+				mockProcessor.On("UseTOTPStep", mock.Anything, 1, mock.AnythingOfType("int64")).Return(nil)
+				mockProcessor.On("EnableTOTP", mock.Anything, 1, mock.MatchedBy(func(hashes []string) bool {
+					return len(hashes) == recoveryCodeCount
+				})).Return(nil)
+			}
+
+			codes, err := authService.ConfirmTwoFactor(context.Background(), 1, tt.code)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, codes, recoveryCodeCount)
+			}
+
+			mockTwoFactor.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthService_DisableTwoFactor(t *testing.T) {
+	totpSecret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	validCode, err := totp.Code(totpSecret, time.Now())
+	assert.NoError(t, err)
+
+	enabledAt := time.Now()
+
+	tests := []struct {
+		name      string
+		twoFactor *model.TwoFactor
+		wantErr   error
+	}{
+		{
+			name:      "Success",
+			twoFactor: &model.TwoFactor{Secret: totpSecret, EnabledAt: &enabledAt},
+		},
+		{
+			name:      "Replayed Code",
+			twoFactor: &model.TwoFactor{Secret: totpSecret, EnabledAt: &enabledAt, LastStep: time.Now().Unix()/totp.Period + 1},
+			wantErr:   ErrInvalidCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockTwoFactor := new(MockTwoFactorProvider)
+			mockProcessor := new(MockTwoFactorProcessor)
+			authService := &AuthService{twoFactorProvider: mockTwoFactor, twoFactorProcessor: mockProcessor}
+
+			mockTwoFactor.On("TwoFactor", mock.Anything, 1).Return(tt.twoFactor, nil)
+
+			if tt.wantErr == nil {
+				mockProcessor.On("UseTOTPStep", mock.Anything, 1, mock.AnythingOfType("int64")).Return(nil)
+				mockProcessor.On("DisableTOTP", mock.Anything, 1).Return(nil)
+			}
+
+			err := authService.DisableTwoFactor(context.Background(), 1, validCode)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockTwoFactor.AssertExpectations(t)
+			mockProcessor.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+type UserProfileProvider interface {
+	Account(ctx context.Context, userID int) (*model.Account, error)
+	UserPosts(ctx context.Context, userID int, all bool) ([]*model.Post, error)
+	UserComments(ctx context.Context, userID int) ([]*model.Comment, error)
+}
+
+type UserProfileProcessor interface {
+	UpdateProfile(ctx context.Context, userID int, profile *model.ProfileRequest) error
+}
+
+type UserService struct {
+	provider  UserProfileProvider
+	processor UserProfileProcessor
+	renderer  *renderCache
+}
+
+// Profile returns the public profile of the user.
+func (us *UserService) Profile(ctx context.Context, userID int) (*model.Profile, error) {
+	const operation = "service.Profile"
+
+	account, err := us.account(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &account.Profile, nil
+}
+
+// Account returns the profile of the current user with its private details.
+func (us *UserService) Account(ctx context.Context, userID int) (*model.Account, error) {
+	const operation = "service.Account"
+
+	account, err := us.account(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return account, nil
+}
+
+// UpdateProfile changes the fields present in the request and returns
+// the updated account.
+//
+// Surrounding whitespace is dropped, a username must still be long
+// enough after that.
+func (us *UserService) UpdateProfile(ctx context.Context, userID int, profileReq *model.ProfileRequest) (*model.Account, error) {
+	const operation = "service.UpdateProfile"
+
+	profile := model.ProfileRequest{
+		Username:    trimmed(profileReq.Username),
+		DisplayName: trimmed(profileReq.DisplayName),
+		Bio:         trimmed(profileReq.Bio),
+		Website:     trimmed(profileReq.Website),
+		AvatarURL:   trimmed(profileReq.AvatarURL),
+	}
+
+	if profile.Username != nil && len([]rune(*profile.Username)) < 3 {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidUsername)
+	}
+
+	if err := us.processor.UpdateProfile(ctx, userID, &profile); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	account, err := us.account(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return account, nil
+}
+
+// UserPosts returns the published posts of the user, or all of them if
+// the viewer is the user.
+//
+// If the user does not exist it returns ErrNotFound, so an author
+// without posts can be told apart from a missing one.
+func (us *UserService) UserPosts(ctx context.Context, userID, viewerID int) ([]*model.Post, error) {
+	const operation = "service.UserPosts"
+
+	if _, err := us.account(ctx, userID); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	posts, err := us.provider.UserPosts(ctx, userID, userID == viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := us.renderer.renderPosts(posts...); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return posts, nil
+}
+
+// ExportAccount collects the personal data of the user: the account,
+// the posts and the comments.
+func (us *UserService) ExportAccount(ctx context.Context, userID int) (*model.AccountExport, error) {
+	const operation = "service.ExportAccount"
+
+	account, err := us.account(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	posts, err := us.provider.UserPosts(ctx, userID, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	comments, err := us.provider.UserComments(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &model.AccountExport{Account: account, Posts: posts, Comments: comments}, nil
+}
+
+func (us *UserService) account(ctx context.Context, userID int) (*model.Account, error) {
+	account, err := us.provider.Account(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// trimmed returns the value without surrounding whitespace,
+// keeping nil for absent fields.
+func trimmed(value *string) *string {
+	if value == nil {
+		return nil
+	}
+
+	trimmedValue := strings.TrimSpace(*value)
+
+	return &trimmedValue
+}
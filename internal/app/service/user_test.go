@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockUserProfileStorage struct{ mock.Mock }
+
+func (m *MockUserProfileStorage) Account(ctx context.Context, userID int) (*model.Account, error) {
+	args := m.Called(ctx, userID)
+	account := args.Get(0)
+	if account == nil {
+		return nil, args.Error(1)
+	}
+	return account.(*model.Account), args.Error(1)
+}
+
+func (m *MockUserProfileStorage) UserPosts(ctx context.Context, userID int, all bool) ([]*model.Post, error) {
+	args := m.Called(ctx, userID, all)
+	posts := args.Get(0)
+	if posts == nil {
+		return nil, args.Error(1)
+	}
+	return posts.([]*model.Post), args.Error(1)
+}
+
+func (m *MockUserProfileStorage) UserComments(ctx context.Context, userID int) ([]*model.Comment, error) {
+	args := m.Called(ctx, userID)
+	comments := args.Get(0)
+	if comments == nil {
+		return nil, args.Error(1)
+	}
+	return comments.([]*model.Comment), args.Error(1)
+}
+
+func (m *MockUserProfileStorage) UpdateProfile(ctx context.Context, userID int, profile *model.ProfileRequest) error {
+	args := m.Called(ctx, userID, profile)
+	return args.Error(0)
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func TestUserService_Profile(t *testing.T) {
+	account := &model.Account{
+		Profile: model.Profile{ID: 1, Username: "user", PostsCount: 2},
+		Email:   "user@example.com",
+		Role:    model.RoleAuthor,
+	}
+
+	tests := []struct {
+		name        string
+		account     *model.Account
+		accountErr  error
+		wantProfile *model.Profile
+		wantErr     error
+	}{
+		{name: "Success", account: account, wantProfile: &account.Profile},
+		{name: "Not Found", accountErr: storage.ErrNotFound, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockUserProfileStorage)
+			userService := &UserService{provider: mockStorage, processor: mockStorage}
+
+			mockStorage.On("Account", mock.Anything, 1).Return(tt.account, tt.accountErr)
+
+			profile, err := userService.Profile(context.Background(), 1)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, profile)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantProfile, profile)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_UpdateProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		profileReq *model.ProfileRequest
+		mock       func(mockStorage *MockUserProfileStorage)
+		wantErr    error
+	}{
+		{
+			name:       "Success",
+			profileReq: &model.ProfileRequest{Username: stringPtr(" new-name "), Website: stringPtr("")},
+			mock: func(mockStorage *MockUserProfileStorage) {
+				mockStorage.On("UpdateProfile", mock.Anything, 1, &model.ProfileRequest{Username: stringPtr("new-name"), Website: stringPtr("")}).Return(nil)
+				mockStorage.On("Account", mock.Anything, 1).Return(&model.Account{Profile: model.Profile{ID: 1, Username: "new-name"}}, nil)
+			},
+		},
+		{
+			name:       "Username Too Short After Trimming",
+			profileReq: &model.ProfileRequest{Username: stringPtr("  ab  ")},
+			mock:       func(*MockUserProfileStorage) {},
+			wantErr:    ErrInvalidUsername,
+		},
+		{
+			name:       "Not Found",
+			profileReq: &model.ProfileRequest{Bio: stringPtr("bio")},
+			mock: func(mockStorage *MockUserProfileStorage) {
+				mockStorage.On("UpdateProfile", mock.Anything, 1, mock.Anything).Return(storage.ErrNotFound)
+			},
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStorage := new(MockUserProfileStorage)
+			userService := &UserService{provider: mockStorage, processor: mockStorage}
+
+			tt.mock(mockStorage)
+
+			account, err := userService.UpdateProfile(context.Background(), 1, tt.profileReq)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, account)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "new-name", account.Username)
+			}
+
+			mockStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_UserPosts(t *testing.T) {
+	mockStorage := new(MockUserProfileStorage)
+	userService := &UserService{provider: mockStorage, processor: mockStorage}
+
+	mockStorage.On("Account", mock.Anything, 1).Return(&model.Account{Profile: model.Profile{ID: 1}}, nil)
+	mockStorage.On("UserPosts", mock.Anything, 1, false).Return([]*model.Post{{ID: 3, UserID: 1}}, nil).Once()
+	mockStorage.On("UserPosts", mock.Anything, 1, true).Return([]*model.Post{{ID: 3, UserID: 1}, {ID: 4, UserID: 1, Status: model.PostStatusDraft}}, nil).Once()
+	mockStorage.On("Account", mock.Anything, 2).Return(nil, storage.ErrNotFound)
+
+	posts, err := userService.UserPosts(context.Background(), 1, 0)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+
+	// The author also sees their drafts and scheduled posts.
+	posts, err = userService.UserPosts(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, posts, 2)
+
+	// A missing user is not an author without posts.
+	_, err = userService.UserPosts(context.Background(), 2, 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mockStorage.AssertExpectations(t)
+}
+
+func TestUserService_ExportAccount(t *testing.T) {
+	mockStorage := new(MockUserProfileStorage)
+	userService := &UserService{provider: mockStorage, processor: mockStorage}
+
+	mockStorage.On("Account", mock.Anything, 1).Return(&model.Account{Profile: model.Profile{ID: 1}, Email: "user@example.com"}, nil)
+	mockStorage.On("UserPosts", mock.Anything, 1, true).Return([]*model.Post{{ID: 3, UserID: 1}}, nil)
+	mockStorage.On("UserComments", mock.Anything, 1).Return([]*model.Comment{{ID: 5, PostID: 4, UserID: 1}}, nil)
+	mockStorage.On("Account", mock.Anything, 2).Return(nil, storage.ErrNotFound)
+
+	export, err := userService.ExportAccount(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.com", export.Account.Email)
+	assert.Len(t, export.Posts, 1)
+	assert.Len(t, export.Comments, 1)
+
+	_, err = userService.ExportAccount(context.Background(), 2)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mockStorage.AssertExpectations(t)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/lib/mailer"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// UserVerifier is used by the content services to refuse publishing
+// for accounts with unverified email addresses.
+type UserVerifier interface {
+	EmailVerified(ctx context.Context, userID int) (bool, error)
+}
+
+// VerifyEmail activates the account using the emailed token.
+func (as *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	const operation = "service.VerifyEmail"
+
+	userToken, err := as.userTokenProcessor.ConsumeUserToken(ctx, model.TokenPurposeEmailVerification, secret.Hash(token))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := as.processor.MarkEmailVerified(ctx, userToken.UserID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// ResendVerification emails a new verification link to the user.
+//
+// Links sent earlier stop working.
+func (as *AuthService) ResendVerification(ctx context.Context, cfg config.Auth, userID int) error {
+	const operation = "service.ResendVerification"
+
+	user, err := as.provider.UserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("%s: %w", operation, ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if user.EmailVerifiedAt != nil {
+		return fmt.Errorf("%s: %w", operation, ErrEmailAlreadyVerified)
+	}
+
+	if err := as.sendVerification(ctx, cfg, user); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+func (as *AuthService) sendVerification(ctx context.Context, cfg config.Auth, user *model.User) error {
+	const operation = "service.sendVerification"
+
+	token, err := as.newUserToken(ctx, user.ID, model.TokenPurposeEmailVerification, cfg.EmailVerifyTTL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nthanks for signing up. To activate your account follow the link below:\n\n%s\n\n"+
+				"The link is valid for %s. Until the address is confirmed you can not publish posts and comments.\n",
+			user.Username,
+			cfg.BaseURL+"/api/auth/verify?token="+url.QueryEscape(token),
+			cfg.EmailVerifyTTL,
+		),
+	}
+
+	if err := as.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// checkVerified returns ErrEmailNotVerified if the user may not publish yet.
+func checkVerified(ctx context.Context, verifier UserVerifier, userID int) error {
+	verified, err := verifier.EmailVerified(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	if !verified {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
@@ -11,11 +11,40 @@ import (
 	"github.com/markraiter/simple-blog/internal/model"
 )
 
+// userColumns is the list of columns scanned by scanUser.
+const userColumns = "id, username, password, email, role, email_verified_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// rowQuerier queries a row from the database or from a transaction.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanUser(row rowScanner) (*model.User, error) {
+	user := &model.User{}
+
+	var emailVerifiedAt sql.NullTime
+
+	err := row.Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.Role, &emailVerifiedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+
+	return user, nil
+}
+
 func (s *Storage) SaveUser(ctx context.Context, user *model.User) (int, error) {
 	const operation = "storage.SaveUser"
 
-	query := "INSERT INTO users (username, password, email) VALUES ($1, $2, $3) RETURNING id"
-	err := s.PostgresDB.QueryRow(query, user.Username, user.Password, user.Email).Scan(&user.ID)
+	query := "INSERT INTO users (username, password, email, role) VALUES ($1, $2, $3, $4) RETURNING id"
+	err := s.PostgresDB.QueryRow(query, user.Username, user.Password, user.Email, user.Role).Scan(&user.ID)
 	if err != nil {
 		var pgErr *pq.Error
 
@@ -32,16 +61,28 @@ func (s *Storage) SaveUser(ctx context.Context, user *model.User) (int, error) {
 func (s *Storage) User(ctx context.Context, email string) (*model.User, error) {
 	const operation = "storage.UserByEmail"
 
-	query, err := s.PostgresDB.Prepare("SELECT id, username, password, email FROM users WHERE email = $1")
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1"
+
+	row := s.PostgresDB.QueryRowContext(ctx, query, email)
+
+	user, err := scanUser(row)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
 		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
-	row := query.QueryRowContext(ctx, email)
+	return user, nil
+}
 
-	user := &model.User{}
-	err = row.Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+func (s *Storage) UserByID(ctx context.Context, id int) (*model.User, error) {
+	const operation = "storage.UserByID"
 
+	query := "SELECT " + userColumns + " FROM users WHERE id = $1"
+
+	user, err := scanUser(s.PostgresDB.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
@@ -52,3 +93,120 @@ func (s *Storage) User(ctx context.Context, email string) (*model.User, error) {
 
 	return user, nil
 }
+
+// UpdateUserRole sets the role of the user.
+//
+// If the user does not exist it returns storage.ErrNotFound.
+func (s *Storage) UpdateUserRole(ctx context.Context, userID int, role model.Role) error {
+	const operation = "storage.UpdateUserRole"
+
+	query := "UPDATE users SET role = $1 WHERE id = $2 RETURNING id"
+
+	var updatedUserID int
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, role, userID).Scan(&updatedUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// UpdatePassword replaces the password hash of the user.
+//
+// If the user does not exist it returns storage.ErrNotFound.
+func (s *Storage) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	const operation = "storage.UpdatePassword"
+
+	query := "UPDATE users SET password = $1 WHERE id = $2 RETURNING id"
+
+	var updatedUserID int
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, passwordHash, userID).Scan(&updatedUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// UpdateEmail replaces the email address of the user with a confirmed one.
+//
+// If the address belongs to another user it returns storage.ErrAlreadyExists,
+// if the user does not exist storage.ErrNotFound.
+func (s *Storage) UpdateEmail(ctx context.Context, userID int, email string) error {
+	const operation = "storage.UpdateEmail"
+
+	query := "UPDATE users SET email = $1, email_verified_at = NOW() WHERE id = $2 RETURNING id"
+
+	var updatedUserID int
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, email, userID).Scan(&updatedUserID)
+	if err != nil {
+		var pgErr *pq.Error
+
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%s: %w", operation, storage.ErrAlreadyExists)
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified marks the email address of the user as verified.
+//
+// If the user does not exist it returns storage.ErrNotFound.
+func (s *Storage) MarkEmailVerified(ctx context.Context, userID int) error {
+	const operation = "storage.MarkEmailVerified"
+
+	query := "UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1 RETURNING id"
+
+	var updatedUserID int
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, userID).Scan(&updatedUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// EmailVerified reports whether the user has verified the email address.
+//
+// If the user does not exist it returns storage.ErrNotFound.
+func (s *Storage) EmailVerified(ctx context.Context, userID int) (bool, error) {
+	const operation = "storage.EmailVerified"
+
+	query := "SELECT email_verified_at IS NOT NULL FROM users WHERE id = $1"
+
+	var verified bool
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, userID).Scan(&verified)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return false, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return verified, nil
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthStorage_UserByID(t *testing.T) {
+	const operation = "storage.UserByID"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	tests := []struct {
+		name    string
+		mock    func()
+		want    *model.User
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectQuery("SELECT id, username, password, email, role, email_verified_at FROM users WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role", "email_verified_at"}).
+						AddRow(1, "user", "hash", "email@example.com", "author", nil))
+			},
+			want: &model.User{ID: 1, Username: "user", Password: "hash", Email: "email@example.com", Role: model.RoleAuthor},
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectQuery("SELECT id, username, password, email, role, email_verified_at FROM users WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			user, err := storage.UserByID(context.Background(), 1)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, user)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
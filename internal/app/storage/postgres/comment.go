@@ -10,6 +10,32 @@ import (
 	"github.com/markraiter/simple-blog/internal/model"
 )
 
+// commentColumns is the list of columns scanned by scanComment.
+const commentColumns = "id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version"
+
+// scanComment scans a comment, the author of a deleted placeholder is not exposed.
+func scanComment(row rowScanner) (*model.Comment, error) {
+	comment := &model.Comment{}
+
+	var parentID sql.NullInt64
+
+	err := row.Scan(&comment.ID, &comment.Content, &comment.Format, &comment.PostID, &comment.UserID, &parentID, &comment.Depth, &comment.Deleted, &comment.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		comment.ParentID = &id
+	}
+
+	if comment.Deleted {
+		comment.UserID = 0
+	}
+
+	return comment, nil
+}
+
 // SaveComment saves a new comment to the database and increments the comments_count of the post.
 //
 // If the post does not exist it returns storage.ErrNotFound.
@@ -30,8 +56,8 @@ func (s *Storage) SaveComment(ctx context.Context, comment *model.Comment) (int,
 		return 0, fmt.Errorf("%s: %w", operation, err)
 	}
 
-	query := "INSERT INTO comments (content, post_id, user_id) VALUES ($1, $2, $3) RETURNING id"
-	err = tx.QueryRowContext(ctx, query, comment.Content, comment.PostID, comment.UserID).Scan(&comment.ID)
+	query := "INSERT INTO comments (content, format, post_id, user_id, parent_id, depth) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"
+	err = tx.QueryRowContext(ctx, query, comment.Content, comment.Format, comment.PostID, comment.UserID, comment.ParentID, comment.Depth).Scan(&comment.ID)
 	if err != nil {
 		tx.Rollback()
 		return 0, fmt.Errorf("%s: %w", operation, err)
@@ -51,21 +77,15 @@ func (s *Storage) SaveComment(ctx context.Context, comment *model.Comment) (int,
 	return comment.ID, nil
 }
 
-// Comment returns any comment by its ID.
+// Comment returns any comment by its ID, deleted placeholders included.
 //
 // If the comment does not exist it returns storage.ErrNotFound.
 func (s *Storage) Comment(ctx context.Context, id int) (*model.Comment, error) {
 	const operation = "storage.Comment"
 
-	query, err := s.PostgresDB.Prepare("SELECT id, content, post_id, user_id FROM comments WHERE id = $1")
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", operation, err)
-	}
-
-	row := query.QueryRowContext(ctx, id)
+	query := "SELECT " + commentColumns + " FROM comments WHERE id = $1"
 
-	comment := &model.Comment{}
-	err = row.Scan(&comment.ID, &comment.Content, &comment.PostID, &comment.UserID)
+	comment, err := scanComment(s.PostgresDB.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
@@ -77,18 +97,16 @@ func (s *Storage) Comment(ctx context.Context, id int) (*model.Comment, error) {
 	return comment, nil
 }
 
-// CommentsByPost returns all comments for provided post.
+// CommentsByPost returns all comments for provided post, newest first,
+// as a flat list. Replies refer to their parent by ParentID.
 //
 // If the post does not exist it returns storage.ErrNotFound.
 func (s *Storage) CommentsByPost(ctx context.Context, postID int) ([]*model.Comment, error) {
 	const operation = "storage.CommentsByPost"
 
-	query, err := s.PostgresDB.Prepare("SELECT id, content, post_id, user_id FROM comments WHERE post_id = $1 ORDER BY created_at DESC")
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", operation, err)
-	}
+	query := "SELECT " + commentColumns + " FROM comments WHERE post_id = $1 ORDER BY created_at DESC"
 
-	rows, err := query.QueryContext(ctx, postID)
+	rows, err := s.PostgresDB.QueryContext(ctx, query, postID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
@@ -100,8 +118,7 @@ func (s *Storage) CommentsByPost(ctx context.Context, postID int) ([]*model.Comm
 
 	comments := make([]*model.Comment, 0)
 	for rows.Next() {
-		comment := &model.Comment{}
-		err = rows.Scan(&comment.ID, &comment.Content, &comment.PostID, &comment.UserID)
+		comment, err := scanComment(rows)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", operation, err)
 		}
@@ -109,37 +126,61 @@ func (s *Storage) CommentsByPost(ctx context.Context, postID int) ([]*model.Comm
 		comments = append(comments, comment)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	// A post without comments and a missing post both give no rows.
+	if len(comments) == 0 {
+		var exists bool
+
+		err := s.PostgresDB.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1)", postID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		if !exists {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+	}
+
 	return comments, nil
 }
 
 // UpdateComment updates a comment by its ID.
 //
-// If the comment does not exist it returns storage.ErrNotFound.
+// The comment is only updated at comment.Version, which is then increased.
+// An empty comment.Format keeps the format of the comment.
+// If the comment does not exist or is deleted it returns storage.ErrNotFound.
 // If the user is not the author of the comment it returns storage.ErrNotAllowed.
+// If the comment has another version it returns storage.ErrConflict.
 func (s *Storage) UpdateComment(ctx context.Context, comment *model.Comment) error {
 	const operation = "storage.UpdateComment"
 
 	query := `
         UPDATE comments 
-        SET content = $1 
-        WHERE id = $2 AND user_id = $3
+        SET content = $1, format = COALESCE(NULLIF($5, ''), format), version = version + 1
+        WHERE id = $2 AND user_id = $3 AND version = $4 AND deleted_at IS NULL
         RETURNING id
     `
 
 	var updatedCommentID int
-	err := s.PostgresDB.QueryRowContext(ctx, query, comment.Content, comment.ID, comment.UserID).Scan(&updatedCommentID)
+	err := s.PostgresDB.QueryRowContext(ctx, query, comment.Content, comment.ID, comment.UserID, comment.Version, comment.Format).Scan(&updatedCommentID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			commentExistsQuery := "SELECT id FROM comments WHERE id = $1"
-			var existsCommentID int
-			err = s.PostgresDB.QueryRowContext(ctx, commentExistsQuery, comment.ID).Scan(&existsCommentID)
+			commentExistsQuery := "SELECT user_id FROM comments WHERE id = $1 AND deleted_at IS NULL"
+			var authorID int
+			err = s.PostgresDB.QueryRowContext(ctx, commentExistsQuery, comment.ID).Scan(&authorID)
 			if err != nil {
 				if errors.Is(err, sql.ErrNoRows) {
 					return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
 				}
 				return fmt.Errorf("%s: %w", operation, err)
 			}
-			return fmt.Errorf("%s: %w", operation, storage.ErrNotAllowed)
+			if authorID != comment.UserID {
+				return fmt.Errorf("%s: %w", operation, storage.ErrNotAllowed)
+			}
+			return fmt.Errorf("%s: %w", operation, storage.ErrConflict)
 		}
 		return fmt.Errorf("%s: %w", operation, err)
 	}
@@ -149,9 +190,12 @@ func (s *Storage) UpdateComment(ctx context.Context, comment *model.Comment) err
 
 // DeleteComment deletes a comment by its ID and decrements the comments_count of the post.
 //
-// If the comment does not exist it returns storage.ErrNotFound.
+// A comment with replies is replaced by a placeholder, so the thread stays
+// readable. Placeholders are removed once their last reply is deleted.
+// If the comment does not exist or is deleted it returns storage.ErrNotFound.
 // If the user is not the author of the comment it returns storage.ErrNotAllowed.
-func (s *Storage) DeleteComment(ctx context.Context, commentID, userID int) error {
+// If the comment has another version it returns storage.ErrConflict.
+func (s *Storage) DeleteComment(ctx context.Context, commentID, userID, version int) error {
 	const operation = "storage.DeleteComment"
 
 	tx, err := s.PostgresDB.BeginTx(ctx, nil)
@@ -159,49 +203,89 @@ func (s *Storage) DeleteComment(ctx context.Context, commentID, userID int) erro
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
+	if err := deleteComment(ctx, tx, commentID, userID, version); err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+func deleteComment(ctx context.Context, tx *sql.Tx, commentID, userID, version int) error {
 	query := `
-		DELETE FROM comments
-		WHERE id = $1 AND user_id = $2
-		RETURNING id
+		SELECT post_id, user_id, version, parent_id, EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id)
+		FROM comments c
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
 	`
-	var deletedCommentID int
-	err = tx.QueryRowContext(ctx, query, commentID, userID).Scan(&deletedCommentID)
+
+	var (
+		postID, authorID, currentVersion int
+		parentID                         sql.NullInt64
+		hasReplies                       bool
+	)
+
+	err := tx.QueryRowContext(ctx, query, commentID).Scan(&postID, &authorID, &currentVersion, &parentID, &hasReplies)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			commentExistsQuery := "SELECT id FROM comments WHERE id = $1"
-			var existsCommentID int
+			return storage.ErrNotFound
+		}
 
-			err = s.PostgresDB.QueryRowContext(ctx, commentExistsQuery, commentID).Scan(&existsCommentID)
-			if err != nil {
-				tx.Rollback()
-				if errors.Is(err, sql.ErrNoRows) {
-					tx.Rollback()
+		return err
+	}
 
-					return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
-				}
-				tx.Rollback()
+	if authorID != userID {
+		return storage.ErrNotAllowed
+	}
 
-				return fmt.Errorf("%s: %w", operation, err)
-			}
-			tx.Rollback()
+	if currentVersion != version {
+		return storage.ErrConflict
+	}
 
-			return fmt.Errorf("%s: %w", operation, storage.ErrNotAllowed)
+	if hasReplies {
+		query = "UPDATE comments SET content = $2, format = 'plain', deleted_at = NOW() WHERE id = $1"
+		if _, err := tx.ExecContext(ctx, query, commentID, model.DeletedCommentContent); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM comments WHERE id = $1", commentID); err != nil {
+			return err
 		}
-		tx.Rollback()
 
-		return fmt.Errorf("%s: %w", operation, err)
+		if err := pruneDeletedComments(ctx, tx, parentID); err != nil {
+			return err
+		}
 	}
 
-	updateQuery := "UPDATE posts SET comments_count = comments_count - 1 WHERE id = (SELECT post_id FROM comments WHERE id = $1)"
-	_, err = tx.ExecContext(ctx, updateQuery, commentID)
-	if err != nil {
-		tx.Rollback()
+	_, err = tx.ExecContext(ctx, "UPDATE posts SET comments_count = comments_count - 1 WHERE id = $1", postID)
 
-		return fmt.Errorf("%s: %w", operation, err)
-	}
+	return err
+}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%s: %w", operation, err)
+// pruneDeletedComments removes the deleted placeholders up the thread
+// starting at parentID which have no replies left.
+func pruneDeletedComments(ctx context.Context, tx *sql.Tx, parentID sql.NullInt64) error {
+	query := `
+		DELETE FROM comments c
+		WHERE id = $1 AND deleted_at IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id)
+		RETURNING parent_id
+	`
+
+	for parentID.Valid {
+		err := tx.QueryRowContext(ctx, query, parentID.Int64).Scan(&parentID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
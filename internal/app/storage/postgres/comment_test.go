@@ -31,16 +31,17 @@ func TestCommentStorage_SaveComment(t *testing.T) {
 			name: "Success",
 			comment: &model.Comment{
 				Content: "content",
+				Format:  model.ContentFormatPlain,
 				PostID:  1,
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1\\)").
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1 AND status = 'published'\\)").
 					WithArgs(1).
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO comments").
-					WithArgs("content", 1, 1).
+					WithArgs("content", "plain", 1, 1, nil, 0).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 				mock.ExpectExec("UPDATE posts SET comments_count = comments_count \\+ 1 WHERE id = \\$1").
 					WithArgs(1).
@@ -58,7 +59,7 @@ func TestCommentStorage_SaveComment(t *testing.T) {
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1\\)").
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1 AND status = 'published'\\)").
 					WithArgs(2).
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
 			},
@@ -69,15 +70,16 @@ func TestCommentStorage_SaveComment(t *testing.T) {
 			name: "Null value for user_id",
 			comment: &model.Comment{
 				Content: "content",
+				Format:  model.ContentFormatPlain,
 				PostID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1\\)").
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1 AND status = 'published'\\)").
 					WithArgs(1).
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO comments").
-					WithArgs("content", 1, 0).
+					WithArgs("content", "plain", 1, 0, nil, 0).
 					WillReturnError(sql.ErrNoRows)
 				mock.ExpectRollback()
 			},
@@ -87,16 +89,17 @@ func TestCommentStorage_SaveComment(t *testing.T) {
 		{
 			name: "Null value for content",
 			comment: &model.Comment{
+				Format: model.ContentFormatPlain,
 				PostID: 1,
 				UserID: 1,
 			},
 			mock: func() {
-				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1\\)").
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1 AND status = 'published'\\)").
 					WithArgs(1).
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO comments").
-					WithArgs("", 1, 1).
+					WithArgs("", "plain", 1, 1, nil, 0).
 					WillReturnError(sql.ErrNoRows)
 				mock.ExpectRollback()
 			},
@@ -107,16 +110,17 @@ func TestCommentStorage_SaveComment(t *testing.T) {
 			name: "Error",
 			comment: &model.Comment{
 				Content: "content",
+				Format:  model.ContentFormatPlain,
 				PostID:  1,
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1\\)").
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1 AND status = 'published'\\)").
 					WithArgs(1).
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO comments").
-					WithArgs("content", 1, 1).
+					WithArgs("content", "plain", 1, 1, nil, 0).
 					WillReturnError(err)
 				mock.ExpectRollback()
 			},
@@ -127,16 +131,17 @@ func TestCommentStorage_SaveComment(t *testing.T) {
 			name: "Error on update",
 			comment: &model.Comment{
 				Content: "content",
+				Format:  model.ContentFormatPlain,
 				PostID:  1,
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1\\)").
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1 AND status = 'published'\\)").
 					WithArgs(1).
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO comments").
-					WithArgs("content", 1, 1).
+					WithArgs("content", "plain", 1, 1, nil, 0).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 				mock.ExpectExec("UPDATE posts SET comments_count = comments_count \\+ 1 WHERE id = \\$1").
 					WithArgs(1).
@@ -150,16 +155,17 @@ func TestCommentStorage_SaveComment(t *testing.T) {
 			name: "Error on commit",
 			comment: &model.Comment{
 				Content: "content",
+				Format:  model.ContentFormatPlain,
 				PostID:  1,
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1\\)").
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1 AND status = 'published'\\)").
 					WithArgs(1).
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 				mock.ExpectBegin()
 				mock.ExpectQuery("INSERT INTO comments").
-					WithArgs("content", 1, 1).
+					WithArgs("content", "plain", 1, 1, nil, 0).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 				mock.ExpectExec("UPDATE posts SET comments_count = comments_count \\+ 1 WHERE id = \\$1").
 					WithArgs(1).
@@ -173,11 +179,12 @@ func TestCommentStorage_SaveComment(t *testing.T) {
 			name: "Error on begin",
 			comment: &model.Comment{
 				Content: "content",
+				Format:  model.ContentFormatPlain,
 				PostID:  1,
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1\\)").
+				mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM posts WHERE id = \\$1 AND status = 'published'\\)").
 					WithArgs(1).
 					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 				mock.ExpectBegin().WillReturnError(err)
@@ -226,17 +233,18 @@ func TestCommentStorage_Comment(t *testing.T) {
 			name:      "Success",
 			commentID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE id = \\$1").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE id = \\$1").
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "post_id", "user_id"}).
-						AddRow(1, "Test Content", 1, 1))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "format", "post_id", "user_id", "parent_id", "depth", "deleted", "version"}).
+						AddRow(1, "Test Content", "plain", 1, 1, nil, 0, false, 1))
 			},
 			wantComment: &model.Comment{
 				ID:      1,
 				Content: "Test Content",
+				Format:  model.ContentFormatPlain,
 				PostID:  1,
 				UserID:  1,
+				Version: 1,
 			},
 			wantErr: nil,
 		},
@@ -244,8 +252,7 @@ func TestCommentStorage_Comment(t *testing.T) {
 			name:      "Comment not found",
 			commentID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE id = \\$1").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE id = \\$1").
 					WithArgs(1).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -256,23 +263,13 @@ func TestCommentStorage_Comment(t *testing.T) {
 			name:      "Error",
 			commentID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE id = \\$1").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE id = \\$1").
 					WithArgs(1).
 					WillReturnError(err)
 			},
 			wantComment: nil,
 			wantErr:     fmt.Errorf("%s: %w", operation, err),
 		},
-		{
-			name: "Error on prepare",
-			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE id = \\$1").
-					WillReturnError(err)
-			},
-			wantComment: nil,
-			wantErr:     fmt.Errorf("%s: %w", operation, err),
-		},
 	}
 
 	for _, tt := range tests {
@@ -316,25 +313,28 @@ func TestCommentStorage_CommentsByPost(t *testing.T) {
 			name:   "Success",
 			postID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "post_id", "user_id"}).
-						AddRow(1, "Test Content", 1, 1).
-						AddRow(2, "Test Content 2", 1, 1))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "format", "post_id", "user_id", "parent_id", "depth", "deleted", "version"}).
+						AddRow(1, "Test Content", "plain", 1, 1, nil, 0, false, 1).
+						AddRow(2, "Test Content 2", "plain", 1, 1, nil, 0, false, 1))
 			},
 			wantComments: []*model.Comment{
 				{
 					ID:      1,
 					Content: "Test Content",
+					Format:  model.ContentFormatPlain,
 					PostID:  1,
 					UserID:  1,
+					Version: 1,
 				},
 				{
 					ID:      2,
 					Content: "Test Content 2",
+					Format:  model.ContentFormatPlain,
 					PostID:  1,
 					UserID:  1,
+					Version: 1,
 				},
 			},
 			wantErr: nil,
@@ -343,20 +343,35 @@ func TestCommentStorage_CommentsByPost(t *testing.T) {
 			name:   "No comments found",
 			postID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "format", "post_id", "user_id", "parent_id", "depth", "deleted", "version"}))
+				mock.ExpectQuery("SELECT EXISTS").
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "post_id", "user_id"}))
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 			},
 			wantComments: []*model.Comment{},
 			wantErr:      nil,
 		},
+		{
+			name:   "Post does not exist",
+			postID: 2,
+			mock: func() {
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "format", "post_id", "user_id", "parent_id", "depth", "deleted", "version"}))
+				mock.ExpectQuery("SELECT EXISTS").
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			},
+			wantComments: nil,
+			wantErr:      fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
 		{
 			name:   "No post found",
 			postID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
 					WithArgs(1).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -367,8 +382,7 @@ func TestCommentStorage_CommentsByPost(t *testing.T) {
 			name:   "No postID",
 			postID: 0,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
 					WithArgs(0).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -379,32 +393,21 @@ func TestCommentStorage_CommentsByPost(t *testing.T) {
 			name:   "Error",
 			postID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
 					WithArgs(1).
 					WillReturnError(err)
 			},
 			wantComments: nil,
 			wantErr:      fmt.Errorf("%s: %w", operation, err),
 		},
-		{
-			name: "Error on prepare",
-			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
-					WillReturnError(err)
-			},
-			wantComments: nil,
-			wantErr:      fmt.Errorf("%s: %w", operation, err),
-		},
 		{
 			name:   "Error on scan",
 			postID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, content, post_id, user_id FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, content, format, post_id, user_id, parent_id, depth, deleted_at IS NOT NULL, version FROM comments WHERE post_id = \\$1 ORDER BY created_at DESC").
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "post_id", "user_id"}).
-						AddRow("invalid_id", "Test Content", 1, 1))
+					WillReturnRows(sqlmock.NewRows([]string{"id", "content", "format", "post_id", "user_id", "parent_id", "depth", "deleted", "version"}).
+						AddRow("invalid_id", "Test Content", "plain", 1, 1, nil, 0, false, 1))
 			},
 			wantComments: nil,
 			wantErr:      fmt.Errorf("%s: %w", operation, scanErr),
@@ -454,10 +457,11 @@ func TestCommentStorage_UpdateComment(t *testing.T) {
 				ID:      1,
 				Content: "Updated Content",
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE comments SET content = \\$1 WHERE id = \\$2 AND user_id = \\$3 RETURNING id").
-					WithArgs("Updated Content", 1, 1).
+				mock.ExpectQuery("UPDATE comments SET content = \\$1, format = COALESCE\\(NULLIF\\(\\$5, ''\\), format\\), version = version \\+ 1 WHERE id = \\$2 AND user_id = \\$3 AND version = \\$4 AND deleted_at IS NULL RETURNING id").
+					WithArgs("Updated Content", 1, 1, 2, "").
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 			},
 			wantErr: nil,
@@ -468,13 +472,14 @@ func TestCommentStorage_UpdateComment(t *testing.T) {
 				ID:      1,
 				Content: "Updated Content",
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE comments SET content = \\$1 WHERE id = \\$2 AND user_id = \\$3 RETURNING id").
-					WithArgs("Updated Content", 1, 1).
+				mock.ExpectQuery("UPDATE comments SET content = \\$1, format = COALESCE\\(NULLIF\\(\\$5, ''\\), format\\), version = version \\+ 1 WHERE id = \\$2 AND user_id = \\$3 AND version = \\$4 AND deleted_at IS NULL RETURNING id").
+					WithArgs("Updated Content", 1, 1, 2, "").
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM comments WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM comments WHERE id = \\$1 AND deleted_at IS NULL").
 					WithArgs(1).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -486,13 +491,14 @@ func TestCommentStorage_UpdateComment(t *testing.T) {
 				ID:      1,
 				Content: "Updated Content",
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE comments SET content = \\$1 WHERE id = \\$2 AND user_id = \\$3 RETURNING id").
-					WithArgs("Updated Content", 1, 1).
+				mock.ExpectQuery("UPDATE comments SET content = \\$1, format = COALESCE\\(NULLIF\\(\\$5, ''\\), format\\), version = version \\+ 1 WHERE id = \\$2 AND user_id = \\$3 AND version = \\$4 AND deleted_at IS NULL RETURNING id").
+					WithArgs("Updated Content", 1, 1, 2, "").
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM comments WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM comments WHERE id = \\$1 AND deleted_at IS NULL").
 					WithArgs(1).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -504,28 +510,49 @@ func TestCommentStorage_UpdateComment(t *testing.T) {
 				ID:      1,
 				Content: "Updated Content",
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE comments SET content = \\$1 WHERE id = \\$2 AND user_id = \\$3 RETURNING id").
-					WithArgs("Updated Content", 1, 1).
+				mock.ExpectQuery("UPDATE comments SET content = \\$1, format = COALESCE\\(NULLIF\\(\\$5, ''\\), format\\), version = version \\+ 1 WHERE id = \\$2 AND user_id = \\$3 AND version = \\$4 AND deleted_at IS NULL RETURNING id").
+					WithArgs("Updated Content", 1, 1, 2, "").
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM comments WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM comments WHERE id = \\$1 AND deleted_at IS NULL").
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotAllowed),
 		},
+		{
+			name: "Version conflict",
+			comment: &model.Comment{
+				ID:      1,
+				Content: "Updated Content",
+				UserID:  1,
+				Version: 2,
+			},
+			mock: func() {
+				mock.ExpectQuery("UPDATE comments SET content = \\$1, format = COALESCE\\(NULLIF\\(\\$5, ''\\), format\\), version = version \\+ 1 WHERE id = \\$2 AND user_id = \\$3 AND version = \\$4 AND deleted_at IS NULL RETURNING id").
+					WithArgs("Updated Content", 1, 1, 2, "").
+					WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectQuery("SELECT user_id FROM comments WHERE id = \\$1 AND deleted_at IS NULL").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrConflict),
+		},
 		{
 			name: "Error",
 			comment: &model.Comment{
 				ID:      1,
 				Content: "Updated Content",
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE comments SET content = \\$1 WHERE id = \\$2 AND user_id = \\$3 RETURNING id").
-					WithArgs("Updated Content", 1, 1).
+				mock.ExpectQuery("UPDATE comments SET content = \\$1, format = COALESCE\\(NULLIF\\(\\$5, ''\\), format\\), version = version \\+ 1 WHERE id = \\$2 AND user_id = \\$3 AND version = \\$4 AND deleted_at IS NULL RETURNING id").
+					WithArgs("Updated Content", 1, 1, 2, "").
 					WillReturnError(err)
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, err),
@@ -536,13 +563,14 @@ func TestCommentStorage_UpdateComment(t *testing.T) {
 				ID:      1,
 				Content: "Updated Content",
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE comments SET content = \\$1 WHERE id = \\$2 AND user_id = \\$3 RETURNING id").
-					WithArgs("Updated Content", 1, 1).
+				mock.ExpectQuery("UPDATE comments SET content = \\$1, format = COALESCE\\(NULLIF\\(\\$5, ''\\), format\\), version = version \\+ 1 WHERE id = \\$2 AND user_id = \\$3 AND version = \\$4 AND deleted_at IS NULL RETURNING id").
+					WithArgs("Updated Content", 1, 1, 2, "").
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM comments WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM comments WHERE id = \\$1 AND deleted_at IS NULL").
 					WithArgs(1).
 					WillReturnError(err)
 			},
@@ -577,6 +605,9 @@ func TestDeleteComment(t *testing.T) {
 	storage, mock, closeDB := prepareStorage(t)
 	defer closeDB()
 
+	selectQuery := "SELECT post_id, user_id, version, parent_id, EXISTS"
+	selectColumns := []string{"post_id", "user_id", "version", "parent_id", "exists"}
+
 	tests := []struct {
 		name      string
 		commentID int
@@ -590,11 +621,58 @@ func TestDeleteComment(t *testing.T) {
 			userID:    1,
 			mock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("DELETE FROM comments WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-				mock.ExpectExec("UPDATE posts SET comments_count = comments_count - 1 WHERE id = \\(SELECT post_id FROM comments WHERE id = \\$1\\)").
+				mock.ExpectQuery(selectQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(selectColumns).AddRow(3, 1, 2, nil, false))
+				mock.ExpectExec("DELETE FROM comments WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE posts SET comments_count = comments_count - 1 WHERE id = \\$1").
+					WithArgs(3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Comment with replies becomes a placeholder",
+			commentID: 1,
+			userID:    1,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectQuery).
 					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(selectColumns).AddRow(3, 1, 2, nil, true))
+				mock.ExpectExec("UPDATE comments SET content = \\$2, format = 'plain', deleted_at = NOW\\(\\) WHERE id = \\$1").
+					WithArgs(1, model.DeletedCommentContent).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE posts SET comments_count = comments_count - 1 WHERE id = \\$1").
+					WithArgs(3).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+		{
+			name:      "Last reply removes deleted parents",
+			commentID: 4,
+			userID:    1,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectQuery).
+					WithArgs(4).
+					WillReturnRows(sqlmock.NewRows(selectColumns).AddRow(3, 1, 2, 2, false))
+				mock.ExpectExec("DELETE FROM comments WHERE id = \\$1").
+					WithArgs(4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("DELETE FROM comments c WHERE id = \\$1 AND deleted_at IS NOT NULL").
+					WithArgs(int64(2)).
+					WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(1))
+				mock.ExpectQuery("DELETE FROM comments c WHERE id = \\$1 AND deleted_at IS NOT NULL").
+					WithArgs(int64(1)).
+					WillReturnRows(sqlmock.NewRows([]string{"parent_id"}))
+				mock.ExpectExec("UPDATE posts SET comments_count = comments_count - 1 WHERE id = \\$1").
+					WithArgs(3).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectCommit()
 			},
@@ -606,10 +684,7 @@ func TestDeleteComment(t *testing.T) {
 			userID:    1,
 			mock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("DELETE FROM comments WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
-					WillReturnError(sql.ErrNoRows)
-				mock.ExpectQuery("SELECT id FROM comments WHERE id = \\$1").
+				mock.ExpectQuery(selectQuery).
 					WithArgs(1).
 					WillReturnError(sql.ErrNoRows)
 				mock.ExpectRollback()
@@ -622,24 +697,34 @@ func TestDeleteComment(t *testing.T) {
 			userID:    1,
 			mock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("DELETE FROM comments WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
-					WillReturnError(sql.ErrNoRows)
-				mock.ExpectQuery("SELECT id FROM comments WHERE id = \\$1").
+				mock.ExpectQuery(selectQuery).
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+					WillReturnRows(sqlmock.NewRows(selectColumns).AddRow(3, 2, 2, nil, false))
 				mock.ExpectRollback()
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotAllowed),
 		},
+		{
+			name:      "Version conflict",
+			commentID: 1,
+			userID:    1,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(selectQuery).
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(selectColumns).AddRow(3, 1, 3, nil, false))
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrConflict),
+		},
 		{
 			name:      "Error",
 			commentID: 1,
 			userID:    1,
 			mock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("DELETE FROM comments WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
+				mock.ExpectQuery(selectQuery).
+					WithArgs(1).
 					WillReturnError(err)
 				mock.ExpectRollback()
 			},
@@ -651,11 +736,14 @@ func TestDeleteComment(t *testing.T) {
 			userID:    1,
 			mock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("DELETE FROM comments WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-				mock.ExpectExec("UPDATE posts SET comments_count = comments_count - 1 WHERE id = \\(SELECT post_id FROM comments WHERE id = \\$1\\)").
+				mock.ExpectQuery(selectQuery).
 					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(selectColumns).AddRow(3, 1, 2, nil, false))
+				mock.ExpectExec("DELETE FROM comments WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE posts SET comments_count = comments_count - 1 WHERE id = \\$1").
+					WithArgs(3).
 					WillReturnResult(sqlmock.NewResult(0, 1))
 				mock.ExpectCommit().WillReturnError(err)
 			},
@@ -670,33 +758,20 @@ func TestDeleteComment(t *testing.T) {
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, err),
 		},
-		{
-			name:      "Error QueryRawContext",
-			commentID: 1,
-			userID:    1,
-			mock: func() {
-				mock.ExpectBegin()
-				mock.ExpectQuery("DELETE FROM comments WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
-					WillReturnError(sql.ErrNoRows)
-				mock.ExpectQuery("SELECT id FROM comments WHERE id = \\$1").
-					WithArgs(1).
-					WillReturnError(err)
-				mock.ExpectRollback()
-			},
-			wantErr: fmt.Errorf("%s: %w", operation, err),
-		},
 		{
 			name:      "Error ExecContext",
 			commentID: 1,
 			userID:    1,
 			mock: func() {
 				mock.ExpectBegin()
-				mock.ExpectQuery("DELETE FROM comments WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-				mock.ExpectExec("UPDATE posts SET comments_count = comments_count - 1 WHERE id = \\(SELECT post_id FROM comments WHERE id = \\$1\\)").
+				mock.ExpectQuery(selectQuery).
 					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(selectColumns).AddRow(3, 1, 2, nil, false))
+				mock.ExpectExec("DELETE FROM comments WHERE id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("UPDATE posts SET comments_count = comments_count - 1 WHERE id = \\$1").
+					WithArgs(3).
 					WillReturnError(err)
 				mock.ExpectRollback()
 			},
@@ -707,9 +782,8 @@ func TestDeleteComment(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			ctx := context.Background()
 
-			err := storage.DeleteComment(ctx, tt.commentID, tt.userID)
+			err := storage.DeleteComment(context.Background(), tt.commentID, tt.userID, 2)
 
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// UserByIdentity returns the user linked to the account at the identity provider.
+//
+// If no user is linked it returns storage.ErrNotFound.
+func (s *Storage) UserByIdentity(ctx context.Context, issuer, subject string) (*model.User, error) {
+	const operation = "storage.UserByIdentity"
+
+	query := `
+        SELECT u.id, u.username, u.password, u.email, u.role, u.email_verified_at
+        FROM users u
+        JOIN user_identities i ON i.user_id = u.id
+        WHERE i.issuer = $1 AND i.subject = $2
+    `
+
+	user, err := scanUser(s.PostgresDB.QueryRowContext(ctx, query, issuer, subject))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return user, nil
+}
+
+// SaveUserIdentity links the account at the identity provider to an existing user.
+//
+// If the account is already linked it returns storage.ErrAlreadyExists.
+func (s *Storage) SaveUserIdentity(ctx context.Context, identity *model.UserIdentity) error {
+	const operation = "storage.SaveUserIdentity"
+
+	if err := saveUserIdentity(ctx, s.PostgresDB, identity); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// SaveUserWithIdentity creates a new user linked to the account at the
+// identity provider.
+//
+// If the email is taken it returns storage.ErrAlreadyExists.
+func (s *Storage) SaveUserWithIdentity(ctx context.Context, user *model.User, identity *model.UserIdentity) (int, error) {
+	const operation = "storage.SaveUserWithIdentity"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	query := "INSERT INTO users (username, password, email, role, email_verified_at) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+
+	err = tx.QueryRowContext(ctx, query, user.Username, user.Password, user.Email, user.Role, user.EmailVerifiedAt).Scan(&user.ID)
+	if err != nil {
+		tx.Rollback()
+
+		var pgErr *pq.Error
+
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return 0, fmt.Errorf("%s: %w", operation, storage.ErrAlreadyExists)
+		}
+
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	identity.UserID = user.ID
+
+	if err := saveUserIdentity(ctx, tx, identity); err != nil {
+		tx.Rollback()
+
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return user.ID, nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func saveUserIdentity(ctx context.Context, db queryRower, identity *model.UserIdentity) error {
+	query := "INSERT INTO user_identities (user_id, issuer, subject, email) VALUES ($1, $2, $3, $4) RETURNING id"
+
+	err := db.QueryRowContext(ctx, query, identity.UserID, identity.Issuer, identity.Subject, identity.Email).Scan(&identity.ID)
+	if err != nil {
+		var pgErr *pq.Error
+
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return storage.ErrAlreadyExists
+		}
+
+		return err
+	}
+
+	return nil
+}
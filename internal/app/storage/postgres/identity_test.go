@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityStorage_SaveUserWithIdentity(t *testing.T) {
+	const operation = "storage.SaveUserWithIdentity"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantID  int
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO users").
+					WithArgs("user", "hash", "user@example.com", model.RoleAuthor, nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("INSERT INTO user_identities").
+					WithArgs(1, "https://idp.example.com", "42", "user@example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				mock.ExpectCommit()
+			},
+			wantID: 1,
+		},
+		{
+			name: "Email Taken",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO users").
+					WithArgs("user", "hash", "user@example.com", model.RoleAuthor, nil).
+					WillReturnError(&pq.Error{Code: "23505"})
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrAlreadyExists),
+		},
+		{
+			name: "Identity Linked",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("INSERT INTO users").
+					WithArgs("user", "hash", "user@example.com", model.RoleAuthor, nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery("INSERT INTO user_identities").
+					WithArgs(1, "https://idp.example.com", "42", "user@example.com").
+					WillReturnError(&pq.Error{Code: "23505"})
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrAlreadyExists),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			user := &model.User{Username: "user", Password: "hash", Email: "user@example.com", Role: model.RoleAuthor}
+			identity := &model.UserIdentity{Issuer: "https://idp.example.com", Subject: "42", Email: "user@example.com"}
+
+			id, err := storage.SaveUserWithIdentity(context.Background(), user, identity)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantID, id)
+				assert.Equal(t, tt.wantID, identity.UserID)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
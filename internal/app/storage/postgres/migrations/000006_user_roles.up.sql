@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'author'
+    CHECK (role IN ('reader', 'author', 'moderator', 'admin'));
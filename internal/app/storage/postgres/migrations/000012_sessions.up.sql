@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id           VARCHAR(64) PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device       VARCHAR(100) NOT NULL,
+    user_agent   TEXT NOT NULL,
+    ip           VARCHAR(45) NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
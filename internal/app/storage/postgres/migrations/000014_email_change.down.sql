@@ -0,0 +1,3 @@
+DELETE FROM user_tokens WHERE purpose = 'email_change';
+
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS new_email;
@@ -0,0 +1 @@
+ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS new_email VARCHAR(255);
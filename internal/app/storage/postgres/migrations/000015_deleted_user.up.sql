@@ -0,0 +1,5 @@
+-- Posts and comments of deleted accounts are moved to this user when the
+-- content is anonymised. Nobody can log in as it, '!' is never a valid hash.
+INSERT INTO users (username, email, password, role)
+VALUES ('deleted user', 'deleted-user@invalid', '!', 'reader')
+ON CONFLICT (email) DO NOTHING;
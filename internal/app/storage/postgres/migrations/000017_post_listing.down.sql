@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS idx_posts_user_id_created_at;
+DROP INDEX IF EXISTS idx_posts_comments_count;
+DROP INDEX IF EXISTS idx_posts_updated_at;
+DROP INDEX IF EXISTS idx_posts_created_at;
+
+CREATE OR REPLACE FUNCTION set_posts_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.updated_at IS NULL THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE posts ALTER COLUMN updated_at DROP NOT NULL;
+ALTER TABLE posts ALTER COLUMN updated_at DROP DEFAULT;
+ALTER TABLE posts ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE posts ALTER COLUMN comments_count DROP NOT NULL;
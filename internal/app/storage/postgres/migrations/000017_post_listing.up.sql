@@ -0,0 +1,24 @@
+UPDATE posts SET comments_count = 0 WHERE comments_count IS NULL;
+UPDATE posts SET created_at = CURRENT_TIMESTAMP WHERE created_at IS NULL;
+UPDATE posts SET updated_at = created_at WHERE updated_at IS NULL;
+
+ALTER TABLE posts ALTER COLUMN comments_count SET NOT NULL;
+ALTER TABLE posts ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE posts ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE posts ALTER COLUMN updated_at SET NOT NULL;
+
+-- Only edits of the post itself count as updates, not its comment counter.
+CREATE OR REPLACE FUNCTION set_posts_updated_at()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.title IS DISTINCT FROM OLD.title OR NEW.content IS DISTINCT FROM OLD.content THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_updated_at ON posts (updated_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_comments_count ON posts (comments_count DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_created_at ON posts (user_id, created_at DESC, id DESC);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_comments_search_vector;
+DROP INDEX IF EXISTS idx_posts_search_vector;
+
+DROP TRIGGER IF EXISTS set_comments_search_vector_trigger ON comments;
+DROP TRIGGER IF EXISTS set_posts_search_vector_trigger ON posts;
+
+DROP FUNCTION IF EXISTS set_comments_search_vector();
+DROP FUNCTION IF EXISTS set_posts_search_vector();
+
+ALTER TABLE comments DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,39 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+-- Matches in the title rank higher than matches in the content.
+CREATE OR REPLACE FUNCTION set_posts_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector =
+        setweight(to_tsvector('english', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(NEW.content, '')), 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_comments_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector = to_tsvector('english', COALESCE(NEW.content, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_posts_search_vector_trigger
+BEFORE INSERT OR UPDATE OF title, content ON posts
+FOR EACH ROW
+EXECUTE FUNCTION set_posts_search_vector();
+
+CREATE TRIGGER set_comments_search_vector_trigger
+BEFORE INSERT OR UPDATE OF content ON comments
+FOR EACH ROW
+EXECUTE FUNCTION set_comments_search_vector();
+
+UPDATE posts SET search_vector =
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', content), 'B');
+UPDATE comments SET search_vector = to_tsvector('english', content);
+
+CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_comments_search_vector ON comments USING GIN (search_vector);
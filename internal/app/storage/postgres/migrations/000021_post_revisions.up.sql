@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id         SERIAL PRIMARY KEY,
+    post_id    INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    number     INTEGER NOT NULL,
+    editor_id  INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    title      VARCHAR(255) NOT NULL,
+    content    TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (post_id, number)
+);
+
+-- The current version of an existing post is its first revision.
+INSERT INTO post_revisions (post_id, number, editor_id, title, content, created_at)
+SELECT id, 1, user_id, title, content, updated_at FROM posts
+ON CONFLICT (post_id, number) DO NOTHING;
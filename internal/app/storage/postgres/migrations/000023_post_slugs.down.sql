@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS post_slug_redirects;
+
+DROP INDEX IF EXISTS posts_slug_idx;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS slug;
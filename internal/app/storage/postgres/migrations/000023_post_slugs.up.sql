@@ -0,0 +1,25 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS slug VARCHAR(255);
+
+-- Existing posts get the ASCII letters and digits of their title, the
+-- later of two posts with the same slug is told apart by its ID.
+UPDATE posts
+SET slug = COALESCE(NULLIF(TRIM(BOTH '-' FROM REGEXP_REPLACE(LOWER(title), '[^a-z0-9]+', '-', 'g')), ''), 'post')
+WHERE slug IS NULL;
+
+UPDATE posts p
+SET slug = p.slug || '-' || p.id
+WHERE EXISTS (SELECT 1 FROM posts o WHERE o.slug = p.slug AND o.id < p.id);
+
+ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS posts_slug_idx ON posts (slug);
+
+-- The former slugs of posts whose title changed, they redirect to the
+-- current slug.
+CREATE TABLE IF NOT EXISTS post_slug_redirects (
+    slug       VARCHAR(255) PRIMARY KEY,
+    post_id    INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS post_slug_redirects_post_id_idx ON post_slug_redirects (post_id);
@@ -0,0 +1,5 @@
+ALTER TABLE comments DROP COLUMN IF EXISTS format;
+
+ALTER TABLE post_revisions DROP COLUMN IF EXISTS format;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS format;
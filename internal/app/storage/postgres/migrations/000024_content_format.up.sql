@@ -0,0 +1,10 @@
+-- The format the content of posts, their revisions and comments is
+-- written in. Everything written so far is plain text.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS format VARCHAR(16) NOT NULL DEFAULT 'plain'
+    CHECK (format IN ('plain', 'markdown'));
+
+ALTER TABLE post_revisions ADD COLUMN IF NOT EXISTS format VARCHAR(16) NOT NULL DEFAULT 'plain'
+    CHECK (format IN ('plain', 'markdown'));
+
+ALTER TABLE comments ADD COLUMN IF NOT EXISTS format VARCHAR(16) NOT NULL DEFAULT 'plain'
+    CHECK (format IN ('plain', 'markdown'));
@@ -0,0 +1,3 @@
+-- The time step of the last accepted TOTP code, so that a code can not
+-- be used a second time while it is still valid.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
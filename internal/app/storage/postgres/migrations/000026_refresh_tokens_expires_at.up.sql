@@ -0,0 +1,2 @@
+-- Expired refresh tokens are removed periodically.
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+const personalTokenColumns = "id, user_id, name, prefix, token_hash, scopes, expires_at, last_used_at, created_at"
+
+// SavePersonalToken saves a new personal access token and sets its ID and creation time.
+func (s *Storage) SavePersonalToken(ctx context.Context, token *model.PersonalAccessToken) error {
+	const operation = "storage.SavePersonalToken"
+
+	query := `
+        INSERT INTO personal_access_tokens (user_id, name, prefix, token_hash, scopes, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `
+
+	err := s.PostgresDB.QueryRowContext(ctx, query,
+		token.UserID,
+		token.Name,
+		token.Prefix,
+		token.TokenHash,
+		pq.Array(scopesToStrings(token.Scopes)),
+		token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// PersonalTokens returns the tokens of the user which have not been revoked, newest first.
+func (s *Storage) PersonalTokens(ctx context.Context, userID int) ([]*model.PersonalAccessToken, error) {
+	const operation = "storage.PersonalTokens"
+
+	query := "SELECT " + personalTokenColumns + " FROM personal_access_tokens WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC"
+
+	rows, err := s.PostgresDB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*model.PersonalAccessToken, 0)
+	for rows.Next() {
+		token, err := scanPersonalToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return tokens, nil
+}
+
+// PersonalTokenByHash returns a token which is neither revoked nor expired.
+//
+// Otherwise it returns storage.ErrNotFound.
+func (s *Storage) PersonalTokenByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	const operation = "storage.PersonalTokenByHash"
+
+	query := "SELECT " + personalTokenColumns + " FROM personal_access_tokens WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())"
+
+	token, err := scanPersonalToken(s.PostgresDB.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return token, nil
+}
+
+// TouchPersonalToken records that the token has just been used.
+func (s *Storage) TouchPersonalToken(ctx context.Context, id int) error {
+	const operation = "storage.TouchPersonalToken"
+
+	_, err := s.PostgresDB.ExecContext(ctx, "UPDATE personal_access_tokens SET last_used_at = NOW() WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// RevokePersonalToken revokes the token of the user.
+//
+// If the user has no such active token it returns storage.ErrNotFound.
+func (s *Storage) RevokePersonalToken(ctx context.Context, userID, id int) error {
+	const operation = "storage.RevokePersonalToken"
+
+	query := "UPDATE personal_access_tokens SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL"
+
+	result, err := s.PostgresDB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+func scanPersonalToken(row rowScanner) (*model.PersonalAccessToken, error) {
+	var (
+		token      model.PersonalAccessToken
+		scopes     []string
+		expiresAt  sql.NullTime
+		lastUsedAt sql.NullTime
+	)
+
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.Prefix,
+		&token.TokenHash,
+		pq.Array(&scopes),
+		&expiresAt,
+		&lastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = make([]model.Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		token.Scopes = append(token.Scopes, model.Scope(scope))
+	}
+
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &token, nil
+}
+
+func scopesToStrings(scopes []model.Scope) []string {
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		result = append(result, string(scope))
+	}
+
+	return result
+}
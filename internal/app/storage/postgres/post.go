@@ -5,18 +5,78 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/lib/pq"
 	"github.com/markraiter/simple-blog/internal/app/storage"
 	"github.com/markraiter/simple-blog/internal/model"
 )
 
+// postColumns is the list of columns scanned by scanPost, the tags are
+// selected as a sorted array of slugs.
+const postColumns = `id, title, slug, content, format, user_id, comments_count, status, publish_at, version, created_at, updated_at,
+        ARRAY(SELECT t.slug FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE pt.post_id = posts.id ORDER BY t.slug)`
+
+// postSortColumns maps the listing orders to the columns they sort by.
+var postSortColumns = map[model.PostSort]string{
+	model.PostSortCreated:  "created_at",
+	model.PostSortUpdated:  "updated_at",
+	model.PostSortComments: "comments_count",
+}
+
+func scanPost(row rowScanner) (*model.Post, error) {
+	post := &model.Post{}
+
+	var publishAt sql.NullTime
+
+	err := row.Scan(&post.ID, &post.Title, &post.Slug, &post.Content, &post.Format, &post.UserID, &post.CommentsCount, &post.Status, &publishAt, &post.Version, &post.CreatedAt, &post.UpdatedAt, pq.Array(&post.Tags))
+	if err != nil {
+		return nil, err
+	}
+
+	if publishAt.Valid {
+		post.PublishAt = &publishAt.Time
+	}
+
+	return post, nil
+}
+
 func (s *Storage) SavePost(ctx context.Context, post *model.Post) (int, error) {
 	const operation = "storage.SavePost"
 
-	query := "INSERT INTO posts (title, content, user_id) VALUES ($1, $2, $3) RETURNING id"
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	post.Slug, err = uniquePostSlug(ctx, tx, post.Slug, 0)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
 
-	err := s.PostgresDB.QueryRow(query, post.Title, post.Content, post.UserID).Scan(&post.ID)
+	query := "INSERT INTO posts (title, slug, content, format, user_id, status, publish_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id"
+
+	err = tx.QueryRowContext(ctx, query, post.Title, post.Slug, post.Content, post.Format, post.UserID, post.Status, post.PublishAt).Scan(&post.ID)
 	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := saveRevision(ctx, tx, post, post.UserID); err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if len(post.Tags) > 0 {
+		if err := setPostTags(ctx, tx, post.ID, post.Tags); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
 		return 0, fmt.Errorf("%s: %w", operation, err)
 	}
 
@@ -26,16 +86,9 @@ func (s *Storage) SavePost(ctx context.Context, post *model.Post) (int, error) {
 func (s *Storage) Post(ctx context.Context, id int) (*model.Post, error) {
 	const operation = "storage.Post"
 
-	query, err := s.PostgresDB.Prepare("SELECT id, title, content, user_id, comments_count FROM posts WHERE id = $1")
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", operation, err)
-	}
-
-	row := query.QueryRowContext(ctx, id)
+	query := "SELECT " + postColumns + " FROM posts WHERE id = $1"
 
-	post := &model.Post{}
-
-	err = row.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CommentsCount)
+	post, err := scanPost(s.PostgresDB.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
@@ -47,24 +100,74 @@ func (s *Storage) Post(ctx context.Context, id int) (*model.Post, error) {
 	return post, nil
 }
 
-func (s *Storage) Posts(ctx context.Context) ([]*model.Post, error) {
+// Posts returns up to query.Limit visible posts in the order of
+// query.Sort, starting after query.After if it is set.
+//
+// Posts with the same sort value are ordered by ID so every post has a
+// unique position to continue from.
+func (s *Storage) Posts(ctx context.Context, query *model.PostQuery) ([]*model.Post, error) {
 	const operation = "storage.Posts"
 
-	query, err := s.PostgresDB.Prepare("SELECT id, title, content, user_id, comments_count FROM posts ORDER BY created_at DESC")
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", operation, err)
+	column, ok := postSortColumns[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown sort order %q", operation, query.Sort)
+	}
+
+	var (
+		conditions []string
+		args       []any
+	)
+
+	arg := func(value any) string {
+		args = append(args, value)
+
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if query.ViewerID != 0 {
+		conditions = append(conditions, "(status = 'published' OR user_id = "+arg(query.ViewerID)+")")
+	} else {
+		conditions = append(conditions, "status = 'published'")
+	}
+
+	if query.AuthorID != 0 {
+		conditions = append(conditions, "user_id = "+arg(query.AuthorID))
+	}
+
+	if query.Tag != "" {
+		conditions = append(conditions, "id IN (SELECT pt.post_id FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE t.slug = "+arg(query.Tag)+")")
+	}
+
+	if query.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*query.From))
+	}
+
+	if query.To != nil {
+		conditions = append(conditions, "created_at < "+arg(*query.To))
+	}
+
+	if after := query.After; after != nil {
+		var value any = after.Time
+		if query.Sort == model.PostSortComments {
+			value = after.CommentsCount
+		}
+
+		conditions = append(conditions, fmt.Sprintf("(%s, id) < (%s, %s)", column, arg(value), arg(after.ID)))
 	}
 
-	rows, err := query.QueryContext(ctx)
+	statement := "SELECT " + postColumns + " FROM posts WHERE " + strings.Join(conditions, " AND ")
+
+	statement += fmt.Sprintf(" ORDER BY %s DESC, id DESC LIMIT %s", column, arg(query.Limit))
+
+	rows, err := s.PostgresDB.QueryContext(ctx, statement, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 	defer rows.Close()
 
-	posts := make([]*model.Post, 0)
+	posts := make([]*model.Post, 0, query.Limit)
 	for rows.Next() {
-		post := &model.Post{}
-		err = rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CommentsCount)
+		post, err := scanPost(rows)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", operation, err)
 		}
@@ -72,73 +175,94 @@ func (s *Storage) Posts(ctx context.Context) ([]*model.Post, error) {
 		posts = append(posts, post)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
 	return posts, nil
 }
 
-// UpdatePost updates post by its ID.
+// UpdatePost updates post by its ID, the tags are replaced unless they are nil.
+// The new title, content and format are saved as a revision by the editor.
 //
+// The post is only updated at post.Version, which is then increased.
 // If the post does not exist it returns storage.ErrNotFound.
 // If the post does not belong to the user it returns storage.ErrNotAllowed.
-func (s *Storage) UpdatePost(ctx context.Context, post *model.Post) error {
+// If the post has another version it returns storage.ErrConflict.
+func (s *Storage) UpdatePost(ctx context.Context, post *model.Post, editorID int) error {
 	const operation = "storage.UpdatePost"
 
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := updatePost(ctx, tx, post, editorID); err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+func updatePost(ctx context.Context, tx *sql.Tx, post *model.Post, editorID int) error {
 	query := `
         UPDATE posts 
-        SET title = $1, content = $2 
-        WHERE id = $3 AND user_id = $4
-        RETURNING id
+        SET title = $1, content = $2, status = $5, publish_at = $6, format = $8, version = version + 1
+        WHERE id = $3 AND user_id = $4 AND version = $7
+        RETURNING slug
     `
 
-	var updatedPostID int
+	var currentSlug string
 
-	err := s.PostgresDB.QueryRowContext(ctx, query, post.Title, post.Content, post.ID, post.UserID).Scan(&updatedPostID)
+	err := tx.QueryRowContext(ctx, query, post.Title, post.Content, post.ID, post.UserID, post.Status, post.PublishAt, post.Version, post.Format).Scan(&currentSlug)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			postExistsQuery := "SELECT id FROM posts WHERE id = $1"
-
-			var existsPostID int
-
-			err := s.PostgresDB.QueryRowContext(ctx, postExistsQuery, post.ID).Scan(&existsPostID)
-			if err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
-					return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
-				}
-				return fmt.Errorf("%s: %w", operation, err)
-			}
-			return fmt.Errorf("%s: %w", operation, storage.ErrNotAllowed)
+			return postWriteError(ctx, tx, post.ID, post.UserID)
 		}
-		return fmt.Errorf("%s: %w", operation, err)
+		return err
+	}
+
+	if err := changePostSlug(ctx, tx, post, currentSlug); err != nil {
+		return err
+	}
+
+	if err := saveRevision(ctx, tx, post, editorID); err != nil {
+		return err
+	}
+
+	if post.Tags != nil {
+		return setPostTags(ctx, tx, post.ID, post.Tags)
 	}
 
 	return nil
 }
 
-func (s *Storage) DeletePost(ctx context.Context, postID, userID int) error {
+// DeletePost deletes the post at the version by its ID.
+//
+// If the post does not exist it returns storage.ErrNotFound.
+// If the post does not belong to the user it returns storage.ErrNotAllowed.
+// If the post has another version it returns storage.ErrConflict.
+func (s *Storage) DeletePost(ctx context.Context, postID, userID, version int) error {
 	const operation = "storage.DeletePost"
 
 	query := `
         DELETE FROM posts 
-        WHERE id = $1 AND user_id = $2 
+        WHERE id = $1 AND user_id = $2 AND version = $3
         RETURNING id
     `
 
 	var deletedPostID int
 
-	err := s.PostgresDB.QueryRowContext(ctx, query, postID, userID).Scan(&deletedPostID)
+	err := s.PostgresDB.QueryRowContext(ctx, query, postID, userID, version).Scan(&deletedPostID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			postExistsQuery := "SELECT id FROM posts WHERE id = $1"
-
-			var existsPostID int
-
-			err := s.PostgresDB.QueryRowContext(ctx, postExistsQuery, postID).Scan(&existsPostID)
-			if err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
-					return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
-				}
-				return fmt.Errorf("%s: %w", operation, err)
-			}
-			return fmt.Errorf("%s: %w", operation, storage.ErrNotAllowed)
+			return fmt.Errorf("%s: %w", operation, postWriteError(ctx, s.PostgresDB, postID, userID))
 		}
 		return fmt.Errorf("%s: %w", operation, err)
 	}
@@ -146,10 +270,53 @@ func (s *Storage) DeletePost(ctx context.Context, postID, userID int) error {
 	return nil
 }
 
+// postWriteError tells why a write of the post by the user at a version
+// matched no row: the post is missing, belongs to someone else or has
+// another version.
+func postWriteError(ctx context.Context, db rowQuerier, postID, userID int) error {
+	var authorID int
+
+	err := db.QueryRowContext(ctx, "SELECT user_id FROM posts WHERE id = $1", postID).Scan(&authorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrNotFound
+		}
+		return err
+	}
+
+	if authorID != userID {
+		return storage.ErrNotAllowed
+	}
+
+	return storage.ErrConflict
+}
+
+// PublishDuePosts publishes the scheduled posts whose time has come and
+// returns how many there were.
+func (s *Storage) PublishDuePosts(ctx context.Context) (int, error) {
+	const operation = "storage.PublishDuePosts"
+
+	query := "UPDATE posts SET status = 'published', version = version + 1 WHERE status = 'scheduled' AND publish_at <= NOW()"
+
+	result, err := s.PostgresDB.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	published, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return int(published), nil
+}
+
+// postExists reports whether the post exists and is published, others
+// can not be commented on.
 func (s *Storage) postExists(ctx context.Context, postID int) (bool, error) {
 	const operation = "storage.postExists"
 
-	query := "SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1)"
+	query := "SELECT EXISTS (SELECT 1 FROM posts WHERE id = $1 AND status = 'published')"
 
 	var exists bool
 
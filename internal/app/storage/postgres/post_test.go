@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	st "github.com/markraiter/simple-blog/internal/app/storage"
 	"github.com/markraiter/simple-blog/internal/model"
 	"github.com/stretchr/testify/assert"
@@ -26,6 +28,8 @@ func prepareStorage(t *testing.T) (*Storage, sqlmock.Sqlmock, func()) {
 	return storage, mock, closeFunc
 }
 
+var postRowColumns = []string{"id", "title", "slug", "content", "format", "user_id", "comments_count", "status", "publish_at", "version", "created_at", "updated_at", "tags"}
+
 func TestPostStorage_SavePost(t *testing.T) {
 	const operation = "storage.SavePost"
 	var err = errors.New("error")
@@ -48,29 +52,77 @@ func TestPostStorage_SavePost(t *testing.T) {
 			ctx:  context.Background(),
 			post: &model.Post{
 				Title:   "Test Title",
+				Slug:    "test-title",
 				Content: "Test Content",
+				Format:  model.ContentFormatPlain,
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("INSERT INTO posts").
-					WithArgs("Test Title", "Test Content", 1).
+				mock.ExpectBegin()
+				expectPostSlugs(mock, "test-title", 0)
+				mock.ExpectQuery("INSERT INTO posts \\(title, slug, content, format, user_id, status, publish_at\\)").
+					WithArgs("Test Title", "test-title", "Test Content", "plain", 1, "", nil).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("INSERT INTO post_revisions \\(post_id, number, editor_id, title, content, format\\) SELECT \\$1, COALESCE\\(MAX\\(number\\), 0\\) \\+ 1, \\$2, \\$3, \\$4, \\$5 FROM post_revisions WHERE post_id = \\$1").
+					WithArgs(1, 1, "Test Title", "Test Content", "plain").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
 			},
 			mockReturn: 1,
 			wantID:     1,
 			wantErr:    nil,
 		},
+		{
+			name: "With Tags",
+			ctx:  context.Background(),
+			post: &model.Post{
+				Title:   "Test Title",
+				Slug:    "test-title",
+				Content: "Test Content",
+				Format:  model.ContentFormatPlain,
+				UserID:  1,
+				Tags:    []string{"golang", "web"},
+			},
+			mock: func() {
+				mock.ExpectBegin()
+				expectPostSlugs(mock, "test-title", 0, "test-title")
+				mock.ExpectQuery("INSERT INTO posts \\(title, slug, content, format, user_id, status, publish_at\\)").
+					WithArgs("Test Title", "test-title-2", "Test Content", "plain", 1, "", nil).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				mock.ExpectExec("INSERT INTO post_revisions \\(post_id, number, editor_id, title, content, format\\) SELECT \\$1, COALESCE\\(MAX\\(number\\), 0\\) \\+ 1, \\$2, \\$3, \\$4, \\$5 FROM post_revisions WHERE post_id = \\$1").
+					WithArgs(2, 1, "Test Title", "Test Content", "plain").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM post_tags WHERE post_id = \\$1").
+					WithArgs(2).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO tags \\(slug\\) SELECT UNNEST\\(\\$1::text\\[\\]\\) ON CONFLICT \\(slug\\) DO NOTHING").
+					WithArgs(pq.Array([]string{"golang", "web"})).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO post_tags \\(post_id, tag_id\\) SELECT \\$1, id FROM tags WHERE slug = ANY\\(\\$2\\)").
+					WithArgs(2, pq.Array([]string{"golang", "web"})).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+			mockReturn: 2,
+			wantID:     2,
+			wantErr:    nil,
+		},
 		{
 			name: "Null value for userID",
 			ctx:  context.Background(),
 			post: &model.Post{
 				Title:   "Test Title",
+				Slug:    "test-title",
 				Content: "Test Content",
+				Format:  model.ContentFormatPlain,
 			},
 			mock: func() {
-				mock.ExpectQuery("INSERT INTO posts").
-					WithArgs("Test Title", "Test Content", 0).
+				mock.ExpectBegin()
+				expectPostSlugs(mock, "test-title", 0)
+				mock.ExpectQuery("INSERT INTO posts \\(title, slug, content, format, user_id, status, publish_at\\)").
+					WithArgs("Test Title", "test-title", "Test Content", "plain", 0, "", nil).
 					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
 			},
 			mockReturn: 0,
 			mockError:  sql.ErrNoRows,
@@ -81,13 +133,18 @@ func TestPostStorage_SavePost(t *testing.T) {
 			name: "Null value for title",
 			ctx:  context.Background(),
 			post: &model.Post{
+				Slug:    "post",
 				Content: "Test Content",
+				Format:  model.ContentFormatPlain,
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("INSERT INTO posts").
-					WithArgs("", "Test Content", 1).
+				mock.ExpectBegin()
+				expectPostSlugs(mock, "post", 0)
+				mock.ExpectQuery("INSERT INTO posts \\(title, slug, content, format, user_id, status, publish_at\\)").
+					WithArgs("", "post", "Test Content", "plain", 1, "", nil).
 					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
 			},
 			mockReturn: 0,
 			mockError:  sql.ErrNoRows,
@@ -99,12 +156,17 @@ func TestPostStorage_SavePost(t *testing.T) {
 			ctx:  context.Background(),
 			post: &model.Post{
 				Title:  "Test Title",
+				Slug:   "test-title",
+				Format: model.ContentFormatPlain,
 				UserID: 1,
 			},
 			mock: func() {
-				mock.ExpectQuery("INSERT INTO posts").
-					WithArgs("Test Title", "", 1).
+				mock.ExpectBegin()
+				expectPostSlugs(mock, "test-title", 0)
+				mock.ExpectQuery("INSERT INTO posts \\(title, slug, content, format, user_id, status, publish_at\\)").
+					WithArgs("Test Title", "test-title", "", "plain", 1, "", nil).
 					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
 			},
 			mockReturn: 0,
 			mockError:  sql.ErrNoRows,
@@ -116,13 +178,18 @@ func TestPostStorage_SavePost(t *testing.T) {
 			ctx:  nil,
 			post: &model.Post{
 				Title:   "Test Title",
+				Slug:    "test-title",
 				Content: "Test Content",
+				Format:  model.ContentFormatPlain,
 				UserID:  1,
 			},
 			mock: func() {
-				mock.ExpectQuery("INSERT INTO posts").
-					WithArgs("Test Title", "Test Content", 1).
+				mock.ExpectBegin()
+				expectPostSlugs(mock, "test-title", 0)
+				mock.ExpectQuery("INSERT INTO posts \\(title, slug, content, format, user_id, status, publish_at\\)").
+					WithArgs("Test Title", "test-title", "Test Content", "plain", 1, "", nil).
 					WillReturnError(err)
+				mock.ExpectRollback()
 			},
 			mockReturn: 0,
 			mockError:  err,
@@ -156,6 +223,8 @@ func TestPostStorage_Post(t *testing.T) {
 	const operation = "storage.Post"
 	var err = errors.New("error")
 
+	created := time.Now()
+
 	storage, mock, closeDB := prepareStorage(t)
 	defer closeDB()
 
@@ -172,16 +241,16 @@ func TestPostStorage_Post(t *testing.T) {
 			name:   "Success",
 			postID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts WHERE id = \\$1").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, title, slug, content, format, user_id, comments_count, status, publish_at, version, created_at, updated_at, ARRAY\\(.+\\) FROM posts WHERE id = \\$1").
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "user_id", "comments_count"}).
-						AddRow(1, "Test Title", "Test Content", 1, 0))
+					WillReturnRows(sqlmock.NewRows(postRowColumns).
+						AddRow(1, "Test Title", "test-title", "Test Content", "plain", 1, 0, "published", created, 1, created, created, "{golang}"))
 			},
 			mockReturn: &model.Post{
 				ID:            1,
 				Title:         "Test Title",
 				Content:       "Test Content",
+				Format:        model.ContentFormatPlain,
 				UserID:        1,
 				CommentsCount: 0,
 			},
@@ -190,6 +259,7 @@ func TestPostStorage_Post(t *testing.T) {
 				ID:            1,
 				Title:         "Test Title",
 				Content:       "Test Content",
+				Format:        model.ContentFormatPlain,
 				UserID:        1,
 				CommentsCount: 0,
 			},
@@ -199,8 +269,7 @@ func TestPostStorage_Post(t *testing.T) {
 			name:   "Post not found",
 			postID: 2,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts WHERE id = \\$1").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, title, slug, content, format, user_id, comments_count, status, publish_at, version, created_at, updated_at, ARRAY\\(.+\\) FROM posts WHERE id = \\$1").
 					WithArgs(2).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -213,8 +282,7 @@ func TestPostStorage_Post(t *testing.T) {
 			name:   "Error",
 			postID: 1,
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts WHERE id = \\$1").
-					ExpectQuery().
+				mock.ExpectQuery("SELECT id, title, slug, content, format, user_id, comments_count, status, publish_at, version, created_at, updated_at, ARRAY\\(.+\\) FROM posts WHERE id = \\$1").
 					WithArgs(1).
 					WillReturnError(err)
 			},
@@ -223,18 +291,6 @@ func TestPostStorage_Post(t *testing.T) {
 			wantPost:   nil,
 			wantErr:    fmt.Errorf("%s: %w", operation, err),
 		},
-		{
-			name:   "Prepare error",
-			postID: 1,
-			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts WHERE id = \\$1").
-					WillReturnError(err)
-			},
-			mockReturn: nil,
-			mockErr:    err,
-			wantPost:   nil,
-			wantErr:    fmt.Errorf("%s: %w", operation, err),
-		},
 	}
 
 	for _, tt := range tests {
@@ -259,131 +315,109 @@ func TestPostStorage_Post(t *testing.T) {
 
 func TestPostStorage_Posts(t *testing.T) {
 	const operation = "storage.Posts"
-	var (
-		err     = errors.New("error")
-		scanErr = errors.New("sql: Scan error")
-	)
+	var err = errors.New("error")
 
 	storage, mock, closeDB := prepareStorage(t)
 	defer closeDB()
 
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
 	tests := []struct {
-		name       string
-		mock       func()
-		mockReturn []*model.Post
-		mockErr    error
-		wantPosts  []*model.Post
-		wantErr    error
+		name      string
+		query     *model.PostQuery
+		mock      func()
+		wantPosts []*model.Post
+		wantErr   error
 	}{
 		{
-			name: "Success",
+			name:  "Newest First",
+			query: &model.PostQuery{Limit: 3, Sort: model.PostSortCreated},
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts ORDER BY created_at DESC").
-					ExpectQuery().
-					WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "user_id", "comments_count"}).
-						AddRow(1, "Test Title 1", "Test Content 1", 1, 0).
-						AddRow(2, "Test Title 2", "Test Content 2", 2, 0))
-			},
-			mockReturn: []*model.Post{
-				{
-					ID:            1,
-					Title:         "Test Title 1",
-					Content:       "Test Content 1",
-					UserID:        1,
-					CommentsCount: 0,
-				},
-				{
-					ID:            2,
-					Title:         "Test Title 2",
-					Content:       "Test Content 2",
-					UserID:        2,
-					CommentsCount: 0,
-				},
+				mock.ExpectQuery("SELECT id, .+ FROM posts WHERE status = 'published' ORDER BY created_at DESC, id DESC LIMIT \\$1").
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows(postRowColumns).
+						AddRow(2, "Test Title 2", "test-title-2", "Test Content 2", "plain", 2, 0, "published", to, 1, to, to, "{}").
+						AddRow(1, "Test Title 1", "test-title-1", "Test Content 1", "markdown", 1, 4, "published", from, 3, from, to, "{golang,web}"))
 			},
-			mockErr: nil,
 			wantPosts: []*model.Post{
-				{
-					ID:            1,
-					Title:         "Test Title 1",
-					Content:       "Test Content 1",
-					UserID:        1,
-					CommentsCount: 0,
-				},
-				{
-					ID:            2,
-					Title:         "Test Title 2",
-					Content:       "Test Content 2",
-					UserID:        2,
-					CommentsCount: 0,
-				},
+				{ID: 2, Title: "Test Title 2", Slug: "test-title-2", Content: "Test Content 2", Format: model.ContentFormatPlain, UserID: 2, Tags: []string{}, Status: model.PostStatusPublished, PublishAt: &to, Version: 1, CreatedAt: to, UpdatedAt: to},
+				{ID: 1, Title: "Test Title 1", Slug: "test-title-1", Content: "Test Content 1", Format: model.ContentFormatMarkdown, UserID: 1, CommentsCount: 4, Tags: []string{"golang", "web"}, Status: model.PostStatusPublished, PublishAt: &from, Version: 3, CreatedAt: from, UpdatedAt: to},
 			},
-			wantErr: nil,
 		},
 		{
-			name: "No posts",
+			name: "Filtered After Cursor",
+			query: &model.PostQuery{
+				Limit:    3,
+				Sort:     model.PostSortUpdated,
+				AuthorID: 1,
+				ViewerID: 9,
+				Tag:      "golang",
+				From:     &from,
+				To:       &to,
+				After:    &model.PostCursor{Sort: model.PostSortUpdated, ID: 5, Time: to},
+			},
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts ORDER BY created_at DESC").
-					ExpectQuery().
-					WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "user_id", "comments_count"}))
+				mock.ExpectQuery("FROM posts WHERE \\(status = 'published' OR user_id = \\$1\\) AND user_id = \\$2 AND id IN \\(SELECT pt.post_id FROM post_tags pt JOIN tags t ON t.id = pt.tag_id WHERE t.slug = \\$3\\) AND created_at >= \\$4 AND created_at < \\$5 AND \\(updated_at, id\\) < \\(\\$6, \\$7\\) ORDER BY updated_at DESC, id DESC LIMIT \\$8").
+					WithArgs(9, 1, "golang", from, to, to, 5, 3).
+					WillReturnRows(sqlmock.NewRows(postRowColumns))
 			},
-			mockReturn: []*model.Post{},
-			mockErr:    nil,
-			wantPosts:  []*model.Post{},
-			wantErr:    nil,
+			wantPosts: []*model.Post{},
 		},
 		{
-			name: "Error",
+			name: "Most Commented After Cursor",
+			query: &model.PostQuery{
+				Limit: 3,
+				Sort:  model.PostSortComments,
+				After: &model.PostCursor{Sort: model.PostSortComments, ID: 5, CommentsCount: 2},
+			},
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts ORDER BY created_at DESC").
-					ExpectQuery().
-					WillReturnError(err)
+				mock.ExpectQuery("FROM posts WHERE status = 'published' AND \\(comments_count, id\\) < \\(\\$1, \\$2\\) ORDER BY comments_count DESC, id DESC LIMIT \\$3").
+					WithArgs(2, 5, 3).
+					WillReturnRows(sqlmock.NewRows(postRowColumns))
 			},
-			mockReturn: nil,
-			mockErr:    err,
-			wantPosts:  nil,
-			wantErr:    fmt.Errorf("%s: %w", operation, err),
+			wantPosts: []*model.Post{},
+		},
+		{
+			name:    "Unknown Sort Order",
+			query:   &model.PostQuery{Limit: 3, Sort: "title"},
+			mock:    func() {},
+			wantErr: fmt.Errorf(`%s: unknown sort order "title"`, operation),
 		},
 		{
-			name: "Prepare error",
+			name:  "Error",
+			query: &model.PostQuery{Limit: 3, Sort: model.PostSortCreated},
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts ORDER BY created_at DESC").
+				mock.ExpectQuery("FROM posts WHERE status = 'published' ORDER BY created_at DESC").
 					WillReturnError(err)
 			},
-			mockReturn: nil,
-			mockErr:    err,
-			wantPosts:  nil,
-			wantErr:    fmt.Errorf("%s: %w", operation, err),
+			wantErr: fmt.Errorf("%s: %w", operation, err),
 		},
 		{
-			name: "Scan error",
+			name:  "Scan error",
+			query: &model.PostQuery{Limit: 3, Sort: model.PostSortCreated},
 			mock: func() {
-				mock.ExpectPrepare("SELECT id, title, content, user_id, comments_count FROM posts ORDER BY created_at DESC").
-					ExpectQuery().
-					WillReturnRows(sqlmock.NewRows([]string{"id", "title", "content", "user_id", "comments_count"}).
-						AddRow("invalid_id", "Test Title", "Test Content", 1, 0))
+				mock.ExpectQuery("FROM posts WHERE status = 'published' ORDER BY created_at DESC").
+					WillReturnRows(sqlmock.NewRows(postRowColumns).
+						AddRow("invalid_id", "Test Title", "test-title", "Test Content", "plain", 1, 0, "published", from, 1, from, from, "{}"))
 			},
-			mockReturn: nil,
-			mockErr:    scanErr,
-			wantPosts:  nil,
-			wantErr:    fmt.Errorf("%s: %w", operation, scanErr),
+			wantErr: fmt.Errorf("%s: sql: Scan error", operation),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
-			_, err := storage.Posts(context.Background())
+			posts, err := storage.Posts(context.Background(), tt.query)
 
-			if tt.wantErr != nil && tt.mockErr != scanErr {
-				assert.EqualError(t, err, tt.wantErr.Error())
-			} else if tt.mockErr == scanErr {
+			if tt.wantErr != nil {
 				assert.ErrorContains(t, err, tt.wantErr.Error())
+				assert.Nil(t, posts)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, tt.wantPosts, posts)
 			}
 
-			assert.Equal(t, tt.mockReturn, tt.wantPosts)
-
 			if err := mock.ExpectationsWereMet(); err != nil {
 				t.Errorf("there were unfulfilled expectations: %s", err)
 			}
@@ -399,10 +433,11 @@ func TestPostStorage_UpdatePost(t *testing.T) {
 	defer closeDB()
 
 	tests := []struct {
-		name    string
-		post    *model.Post
-		mock    func()
-		wantErr error
+		name     string
+		post     *model.Post
+		mock     func()
+		wantSlug string
+		wantErr  error
 	}{
 		{
 			name: "Success",
@@ -410,31 +445,101 @@ func TestPostStorage_UpdatePost(t *testing.T) {
 				ID:      1,
 				Title:   "Updated Title",
 				Content: "Updated Content",
+				Format:  model.ContentFormatPlain,
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2 WHERE id = \\$3 AND user_id = \\$4 RETURNING id").
-					WithArgs("Updated Title", "Updated Content", 1, 1).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2, status = \\$5, publish_at = \\$6, format = \\$8, version = version \\+ 1 WHERE id = \\$3 AND user_id = \\$4 AND version = \\$7 RETURNING slug").
+					WithArgs("Updated Title", "Updated Content", 1, 1, "", nil, 2, "plain").
+					WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("updated-title"))
+				mock.ExpectExec("INSERT INTO post_revisions").
+					WithArgs(1, 2, "Updated Title", "Updated Content", "plain").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Clear Tags",
+			post: &model.Post{
+				ID:      1,
+				Title:   "Updated Title",
+				Content: "Updated Content",
+				Format:  model.ContentFormatPlain,
+				UserID:  1,
+				Version: 2,
+				Tags:    []string{},
+			},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE posts SET title").
+					WithArgs("Updated Title", "Updated Content", 1, 1, "", nil, 2, "plain").
+					WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("updated-title"))
+				mock.ExpectExec("INSERT INTO post_revisions").
+					WithArgs(1, 2, "Updated Title", "Updated Content", "plain").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM post_tags WHERE post_id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
 			},
 			wantErr: nil,
 		},
+		{
+			name: "Title Change",
+			post: &model.Post{
+				ID:      1,
+				Title:   "Updated Title",
+				Slug:    "updated-title",
+				Content: "Updated Content",
+				Format:  model.ContentFormatPlain,
+				UserID:  1,
+				Version: 2,
+			},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE posts SET title").
+					WithArgs("Updated Title", "Updated Content", 1, 1, "", nil, 2, "plain").
+					WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("title"))
+				expectPostSlugs(mock, "updated-title", 1, "updated-title")
+				mock.ExpectExec("DELETE FROM post_slug_redirects WHERE slug = \\$1 AND post_id = \\$2").
+					WithArgs("updated-title-2", 1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE posts SET slug = \\$1 WHERE id = \\$2").
+					WithArgs("updated-title-2", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO post_slug_redirects \\(slug, post_id\\) VALUES \\(\\$1, \\$2\\)").
+					WithArgs("title", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO post_revisions").
+					WithArgs(1, 2, "Updated Title", "Updated Content", "plain").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			wantSlug: "updated-title-2",
+		},
 		{
 			name: "Post Not Found",
 			post: &model.Post{
 				ID:      2,
 				Title:   "Title",
 				Content: "Content",
+				Format:  model.ContentFormatPlain,
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2 WHERE id = \\$3 AND user_id = \\$4 RETURNING id").
-					WithArgs("Title", "Content", 2, 1).
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2, status = \\$5, publish_at = \\$6, format = \\$8, version = version \\+ 1 WHERE id = \\$3 AND user_id = \\$4 AND version = \\$7 RETURNING slug").
+					WithArgs("Title", "Content", 2, 1, "", nil, 2, "plain").
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM posts WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM posts WHERE id = \\$1").
 					WithArgs(2).
 					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
 		},
@@ -444,31 +549,62 @@ func TestPostStorage_UpdatePost(t *testing.T) {
 				ID:      3,
 				Title:   "Another Title",
 				Content: "Another Content",
+				Format:  model.ContentFormatPlain,
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2 WHERE id = \\$3 AND user_id = \\$4 RETURNING id").
-					WithArgs("Another Title", "Another Content", 3, 1).
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2, status = \\$5, publish_at = \\$6, format = \\$8, version = version \\+ 1 WHERE id = \\$3 AND user_id = \\$4 AND version = \\$7 RETURNING slug").
+					WithArgs("Another Title", "Another Content", 3, 1, "", nil, 2, "plain").
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM posts WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM posts WHERE id = \\$1").
 					WithArgs(3).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
+				mock.ExpectRollback()
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotAllowed),
 		},
+		{
+			name: "Version Conflict",
+			post: &model.Post{
+				ID:      3,
+				Title:   "Another Title",
+				Content: "Another Content",
+				Format:  model.ContentFormatPlain,
+				UserID:  1,
+				Version: 2,
+			},
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2, status = \\$5, publish_at = \\$6, format = \\$8, version = version \\+ 1 WHERE id = \\$3 AND user_id = \\$4 AND version = \\$7 RETURNING slug").
+					WithArgs("Another Title", "Another Content", 3, 1, "", nil, 2, "plain").
+					WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectQuery("SELECT user_id FROM posts WHERE id = \\$1").
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrConflict),
+		},
 		{
 			name: "Error",
 			post: &model.Post{
 				ID:      1,
 				Title:   "Updated Title",
 				Content: "Updated Content",
+				Format:  model.ContentFormatPlain,
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2 WHERE id = \\$3 AND user_id = \\$4 RETURNING id").
-					WithArgs("Updated Title", "Updated Content", 1, 1).
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2, status = \\$5, publish_at = \\$6, format = \\$8, version = version \\+ 1 WHERE id = \\$3 AND user_id = \\$4 AND version = \\$7 RETURNING slug").
+					WithArgs("Updated Title", "Updated Content", 1, 1, "", nil, 2, "plain").
 					WillReturnError(err)
+				mock.ExpectRollback()
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, err),
 		},
@@ -478,16 +614,20 @@ func TestPostStorage_UpdatePost(t *testing.T) {
 				ID:      1,
 				Title:   "Updated Title",
 				Content: "Updated Content",
+				Format:  model.ContentFormatPlain,
 				UserID:  1,
+				Version: 2,
 			},
 			mock: func() {
-				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2 WHERE id = \\$3 AND user_id = \\$4 RETURNING id").
-					WithArgs("Updated Title", "Updated Content", 1, 1).
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE posts SET title = \\$1, content = \\$2, status = \\$5, publish_at = \\$6, format = \\$8, version = version \\+ 1 WHERE id = \\$3 AND user_id = \\$4 AND version = \\$7 RETURNING slug").
+					WithArgs("Updated Title", "Updated Content", 1, 1, "", nil, 2, "plain").
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM posts WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM posts WHERE id = \\$1").
 					WithArgs(1).
 					WillReturnError(err)
+				mock.ExpectRollback()
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, err),
 		},
@@ -497,7 +637,7 @@ func TestPostStorage_UpdatePost(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 			ctx := context.Background()
-			err := storage.UpdatePost(ctx, tt.post)
+			err := storage.UpdatePost(ctx, tt.post, 2)
 
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
@@ -505,6 +645,10 @@ func TestPostStorage_UpdatePost(t *testing.T) {
 				assert.NoError(t, err)
 			}
 
+			if tt.wantSlug != "" {
+				assert.Equal(t, tt.wantSlug, tt.post.Slug)
+			}
+
 			if err := mock.ExpectationsWereMet(); err != nil {
 				t.Errorf("there were unfulfilled expectations: %s", err)
 			}
@@ -531,8 +675,8 @@ func TestPostStorage_DeletePost(t *testing.T) {
 			postID: 1,
 			userID: 1,
 			mock: func() {
-				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
+				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 AND version = \\$3 RETURNING id").
+					WithArgs(1, 1, 2).
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 			},
 			wantErr: nil,
@@ -542,11 +686,11 @@ func TestPostStorage_DeletePost(t *testing.T) {
 			postID: 2,
 			userID: 1,
 			mock: func() {
-				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(2, 1).
+				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 AND version = \\$3 RETURNING id").
+					WithArgs(2, 1, 2).
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM posts WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM posts WHERE id = \\$1").
 					WithArgs(2).
 					WillReturnError(sql.ErrNoRows)
 			},
@@ -557,23 +701,38 @@ func TestPostStorage_DeletePost(t *testing.T) {
 			postID: 3,
 			userID: 1,
 			mock: func() {
-				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(3, 1).
+				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 AND version = \\$3 RETURNING id").
+					WithArgs(3, 1, 2).
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM posts WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM posts WHERE id = \\$1").
 					WithArgs(3).
-					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(2))
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotAllowed),
 		},
+		{
+			name:   "Version Conflict",
+			postID: 3,
+			userID: 1,
+			mock: func() {
+				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 AND version = \\$3 RETURNING id").
+					WithArgs(3, 1, 2).
+					WillReturnError(sql.ErrNoRows)
+
+				mock.ExpectQuery("SELECT user_id FROM posts WHERE id = \\$1").
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrConflict),
+		},
 		{
 			name:   "Error",
 			postID: 1,
 			userID: 1,
 			mock: func() {
-				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
+				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 AND version = \\$3 RETURNING id").
+					WithArgs(1, 1, 2).
 					WillReturnError(err)
 			},
 			wantErr: fmt.Errorf("%s: %w", operation, err),
@@ -583,11 +742,11 @@ func TestPostStorage_DeletePost(t *testing.T) {
 			postID: 1,
 			userID: 1,
 			mock: func() {
-				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 RETURNING id").
-					WithArgs(1, 1).
+				mock.ExpectQuery("DELETE FROM posts WHERE id = \\$1 AND user_id = \\$2 AND version = \\$3 RETURNING id").
+					WithArgs(1, 1, 2).
 					WillReturnError(sql.ErrNoRows)
 
-				mock.ExpectQuery("SELECT id FROM posts WHERE id = \\$1").
+				mock.ExpectQuery("SELECT user_id FROM posts WHERE id = \\$1").
 					WithArgs(1).
 					WillReturnError(err)
 			},
@@ -599,7 +758,7 @@ func TestPostStorage_DeletePost(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mock()
 			ctx := context.Background()
-			err := storage.DeletePost(ctx, tt.postID, tt.userID)
+			err := storage.DeletePost(ctx, tt.postID, tt.userID, 2)
 
 			if tt.wantErr != nil {
 				assert.EqualError(t, err, tt.wantErr.Error())
@@ -613,3 +772,27 @@ func TestPostStorage_DeletePost(t *testing.T) {
 		})
 	}
 }
+
+func TestPostStorage_PublishDuePosts(t *testing.T) {
+	const operation = "storage.PublishDuePosts"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	mock.ExpectExec("UPDATE posts SET status = 'published', version = version \\+ 1 WHERE status = 'scheduled' AND publish_at <= NOW\\(\\)").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	published, err := storage.PublishDuePosts(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	mock.ExpectExec("UPDATE posts SET status = 'published'").
+		WillReturnError(errors.New("error"))
+
+	_, err = storage.PublishDuePosts(context.Background())
+	assert.EqualError(t, err, fmt.Sprintf("%s: error", operation))
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// revisionColumns is the list of columns scanned by scanRevision.
+const revisionColumns = "number, post_id, editor_id, title, content, format, created_at"
+
+func scanRevision(row rowScanner) (*model.PostRevision, error) {
+	revision := &model.PostRevision{}
+
+	var editorID sql.NullInt64
+
+	err := row.Scan(&revision.Number, &revision.PostID, &editorID, &revision.Title, &revision.Content, &revision.Format, &revision.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.EditorID = int(editorID.Int64)
+
+	return revision, nil
+}
+
+// Revisions returns the revisions of the post, the newest first.
+func (s *Storage) Revisions(ctx context.Context, postID int) ([]*model.PostRevision, error) {
+	const operation = "storage.Revisions"
+
+	query := "SELECT " + revisionColumns + " FROM post_revisions WHERE post_id = $1 ORDER BY number DESC"
+
+	rows, err := s.PostgresDB.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+	defer rows.Close()
+
+	revisions := make([]*model.PostRevision, 0)
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return revisions, nil
+}
+
+// Revision returns the revision of the post by its number.
+//
+// If the revision does not exist it returns storage.ErrNotFound.
+func (s *Storage) Revision(ctx context.Context, postID, number int) (*model.PostRevision, error) {
+	const operation = "storage.Revision"
+
+	query := "SELECT " + revisionColumns + " FROM post_revisions WHERE post_id = $1 AND number = $2"
+
+	revision, err := scanRevision(s.PostgresDB.QueryRowContext(ctx, query, postID, number))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return revision, nil
+}
+
+// saveRevision saves the title, content and format of the post as its next revision.
+//
+// It is called after the post row is written in the same transaction, so
+// the row lock keeps concurrent edits from taking the same number.
+func saveRevision(ctx context.Context, tx *sql.Tx, post *model.Post, editorID int) error {
+	query := `
+        INSERT INTO post_revisions (post_id, number, editor_id, title, content, format)
+        SELECT $1, COALESCE(MAX(number), 0) + 1, $2, $3, $4, $5 FROM post_revisions WHERE post_id = $1
+    `
+
+	_, err := tx.ExecContext(ctx, query, post.ID, editorID, post.Title, post.Content, post.Format)
+
+	return err
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var revisionRowColumns = []string{"number", "post_id", "editor_id", "title", "content", "format", "created_at"}
+
+func TestRevisionStorage_Revisions(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT number, post_id, editor_id, title, content, format, created_at FROM post_revisions WHERE post_id = \\$1 ORDER BY number DESC").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(revisionRowColumns).
+			AddRow(2, 1, nil, "New Title", "New Content", "markdown", created.Add(time.Hour)).
+			AddRow(1, 1, 3, "Title", "Content", "plain", created))
+
+	revisions, err := storage.Revisions(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.PostRevision{
+		{Number: 2, PostID: 1, Title: "New Title", Content: "New Content", Format: model.ContentFormatMarkdown, CreatedAt: created.Add(time.Hour)},
+		{Number: 1, PostID: 1, EditorID: 3, Title: "Title", Content: "Content", Format: model.ContentFormatPlain, CreatedAt: created},
+	}, revisions)
+
+	mock.ExpectQuery("FROM post_revisions").WillReturnError(errors.New("error"))
+
+	_, err = storage.Revisions(context.Background(), 1)
+	assert.EqualError(t, err, "storage.Revisions: error")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevisionStorage_Revision(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectQuery("FROM post_revisions WHERE post_id = \\$1 AND number = \\$2").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows(revisionRowColumns).AddRow(2, 1, 3, "Title", "Content", "plain", created))
+
+	revision, err := storage.Revision(context.Background(), 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &model.PostRevision{Number: 2, PostID: 1, EditorID: 3, Title: "Title", Content: "Content", Format: model.ContentFormatPlain, CreatedAt: created}, revision)
+
+	mock.ExpectQuery("FROM post_revisions WHERE post_id = \\$1 AND number = \\$2").
+		WithArgs(1, 9).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = storage.Revision(context.Background(), 1, 9)
+	assert.ErrorIs(t, err, st.ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+)
+
+// RevokeToken adds the access token ID to the revocation list.
+//
+// The record is kept only until the token expires, after that
+// the token is rejected anyway.
+func (s *Storage) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	const operation = "storage.RevokeToken"
+
+	query := "INSERT INTO revoked_tokens (jti, user_id, expires_at) VALUES ($1, $2, $3) ON CONFLICT (jti) DO NOTHING"
+
+	_, err := s.PostgresDB.ExecContext(ctx, query, jti, userID, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// TokenRevoked reports whether the access token ID is in the revocation list.
+func (s *Storage) TokenRevoked(ctx context.Context, jti string) (bool, error) {
+	const operation = "storage.TokenRevoked"
+
+	query := "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)"
+
+	var revoked bool
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return revoked, nil
+}
+
+// RevokeUserTokens revokes every token issued to the user up to now.
+//
+// Access tokens are rejected by their issue time, refresh tokens,
+// sessions and personal access tokens are revoked directly.
+func (s *Storage) RevokeUserTokens(ctx context.Context, userID int, revokedAt time.Time) error {
+	const operation = "storage.RevokeUserTokens"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	var updatedUserID int
+
+	err = tx.QueryRowContext(ctx, "UPDATE users SET tokens_revoked_at = $1 WHERE id = $2 RETURNING id", revokedAt, userID).Scan(&updatedUserID)
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE personal_access_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// UserTokensRevokedAt returns the moment of the last logout from all devices.
+//
+// If the user has never done it, the zero time is returned.
+func (s *Storage) UserTokensRevokedAt(ctx context.Context, userID int) (time.Time, error) {
+	const operation = "storage.UserTokensRevokedAt"
+
+	query := "SELECT tokens_revoked_at FROM users WHERE id = $1"
+
+	var revokedAt sql.NullTime
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, userID).Scan(&revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return time.Time{}, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return revokedAt.Time, nil
+}
+
+// DeleteExpiredTokens removes the revoked access tokens and the refresh
+// tokens that have expired and returns how many there were.
+//
+// Expired tokens are rejected anyway, so their records are no longer needed.
+func (s *Storage) DeleteExpiredTokens(ctx context.Context) (int, error) {
+	const operation = "storage.DeleteExpiredTokens"
+
+	deleted := 0
+
+	for _, query := range []string{
+		"DELETE FROM revoked_tokens WHERE expires_at < NOW()",
+		"DELETE FROM refresh_tokens WHERE expires_at < NOW()",
+	} {
+		result, err := s.PostgresDB.ExecContext(ctx, query)
+		if err != nil {
+			return deleted, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return deleted, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		deleted += int(rows)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationStorage_RevokeUserTokens(t *testing.T) {
+	const operation = "storage.RevokeUserTokens"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	revokedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE users SET tokens_revoked_at").
+					WithArgs(revokedAt, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("UPDATE refresh_tokens").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE sessions").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE personal_access_tokens SET revoked_at = NOW\\(\\) WHERE user_id = \\$1 AND revoked_at IS NULL").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE users SET tokens_revoked_at").
+					WithArgs(revokedAt, 1).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+		{
+			name: "Personal Tokens Error",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE users SET tokens_revoked_at").
+					WithArgs(revokedAt, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectExec("UPDATE refresh_tokens").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE sessions").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("UPDATE personal_access_tokens").
+					WithArgs(1).
+					WillReturnError(fmt.Errorf("db error"))
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, fmt.Errorf("db error")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := storage.RevokeUserTokens(context.Background(), 1, revokedAt)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRevocationStorage_DeleteExpiredTokens(t *testing.T) {
+	const operation = "storage.DeleteExpiredTokens"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	mock.ExpectExec("DELETE FROM revoked_tokens WHERE expires_at < NOW\\(\\)").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM refresh_tokens WHERE expires_at < NOW\\(\\)").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	deleted, err := storage.DeleteExpiredTokens(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, deleted)
+
+	mock.ExpectExec("DELETE FROM revoked_tokens").
+		WillReturnError(errors.New("error"))
+
+	_, err = storage.DeleteExpiredTokens(context.Background())
+	assert.EqualError(t, err, fmt.Sprintf("%s: error", operation))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
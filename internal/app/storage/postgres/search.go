@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// headlineOptions are the ts_headline options of the result snippets.
+const headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxWords=35, MinWords=15, MaxFragments=2"
+
+const searchPostsQuery = `
+        SELECT '` + model.SearchResultPost + `' AS type, p.id, p.id AS post_id, p.title, p.content, ts_rank(p.search_vector, q.query) AS rank
+        FROM posts p, q
+        WHERE p.search_vector @@ q.query AND p.status = 'published'
+    `
+
+const searchCommentsQuery = `
+        SELECT '` + model.SearchResultComment + `' AS type, c.id, c.post_id, '' AS title, c.content, ts_rank(c.search_vector, q.query) AS rank
+        FROM comments c
+        JOIN posts p ON p.id = c.post_id AND p.status = 'published', q
+        WHERE c.search_vector @@ q.query AND c.deleted_at IS NULL
+    `
+
+// Search returns the published posts and their comments matching the
+// text of the query, best matches first.
+//
+// The text is parsed with websearch_to_tsquery, so it supports quoted
+// phrases, "or" and "-" to exclude words. Snippets are only made for the
+// returned page of results.
+func (s *Storage) Search(ctx context.Context, query *model.SearchQuery) ([]*model.SearchResult, error) {
+	const operation = "storage.Search"
+
+	var sources []string
+
+	if query.Type != model.SearchComments {
+		sources = append(sources, searchPostsQuery)
+	}
+
+	if query.Type != model.SearchPosts {
+		sources = append(sources, searchCommentsQuery)
+	}
+
+	statement := `
+        WITH q AS (SELECT websearch_to_tsquery('english', $1) AS query)
+        SELECT r.type, r.id, r.post_id, r.title, ts_headline('english', r.content, q.query, $2), r.rank
+        FROM (
+            ` + strings.Join(sources, " UNION ALL ") + `
+            ORDER BY rank DESC, type DESC, id DESC
+            LIMIT $3 OFFSET $4
+        ) r, q
+        ORDER BY r.rank DESC, r.type DESC, r.id DESC
+    `
+
+	rows, err := s.PostgresDB.QueryContext(ctx, statement, query.Text, headlineOptions, query.Limit, query.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+	defer rows.Close()
+
+	results := make([]*model.SearchResult, 0, query.Limit)
+	for rows.Next() {
+		result := &model.SearchResult{}
+
+		err := rows.Scan(&result.Type, &result.ID, &result.PostID, &result.Title, &result.Snippet, &result.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		result.Snippet = escapeSnippet(result.Snippet)
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return results, nil
+}
+
+// escapeSnippet escapes the HTML of the content in a snippet but keeps the highlighting.
+func escapeSnippet(snippet string) string {
+	return strings.NewReplacer(
+		html.EscapeString(highlightStart), highlightStart,
+		html.EscapeString(highlightStop), highlightStop,
+	).Replace(html.EscapeString(snippet))
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchStorage_Search(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	columns := []string{"type", "id", "post_id", "title", "snippet", "rank"}
+
+	tests := []struct {
+		name        string
+		query       *model.SearchQuery
+		mock        func()
+		wantResults []*model.SearchResult
+		wantErr     bool
+	}{
+		{
+			name:  "Posts And Comments",
+			query: &model.SearchQuery{Text: "golang", Type: model.SearchAll, Limit: 3},
+			mock: func() {
+				mock.ExpectQuery("FROM posts p, q WHERE p.search_vector @@ q.query AND p.status = 'published' UNION ALL SELECT 'comment' AS type").
+					WithArgs("golang", headlineOptions, 3, 0).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow("post", 1, 1, "Golang", "<mark>Golang</mark> <script>", 0.9).
+						AddRow("comment", 4, 1, "", "about <mark>golang</mark>", 0.5))
+			},
+			wantResults: []*model.SearchResult{
+				{Type: model.SearchResultPost, ID: 1, PostID: 1, Title: "Golang", Snippet: "<mark>Golang</mark> &lt;script&gt;", Rank: 0.9},
+				{Type: model.SearchResultComment, ID: 4, PostID: 1, Snippet: "about <mark>golang</mark>", Rank: 0.5},
+			},
+		},
+		{
+			name:  "Comments Only",
+			query: &model.SearchQuery{Text: "golang", Type: model.SearchComments, Limit: 3, Offset: 3},
+			mock: func() {
+				mock.ExpectQuery(`\(\s*SELECT 'comment' AS type, .* c.deleted_at IS NULL ORDER BY rank DESC`).
+					WithArgs("golang", headlineOptions, 3, 3).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+			wantResults: []*model.SearchResult{},
+		},
+		{
+			name:  "Error",
+			query: &model.SearchQuery{Text: "golang", Type: model.SearchPosts, Limit: 3},
+			mock: func() {
+				mock.ExpectQuery("FROM posts p, q").
+					WillReturnError(errors.New("error"))
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			results, err := storage.Search(context.Background(), tt.query)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, results)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantResults, results)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
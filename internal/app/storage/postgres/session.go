@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// SaveSession records a login, or updates the session when its tokens are refreshed.
+//
+// A refresh moves the expiry and keeps the latest IP and user agent,
+// the creation time stays the time of the login.
+func (s *Storage) SaveSession(ctx context.Context, session *model.Session) error {
+	const operation = "storage.SaveSession"
+
+	query := `
+        INSERT INTO sessions (id, user_id, device, user_agent, ip, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (id) DO UPDATE SET
+            device = EXCLUDED.device,
+            user_agent = EXCLUDED.user_agent,
+            ip = EXCLUDED.ip,
+            expires_at = EXCLUDED.expires_at,
+            last_seen_at = NOW()
+        RETURNING created_at, last_seen_at
+    `
+
+	err := s.PostgresDB.QueryRowContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.Device,
+		session.UserAgent,
+		session.IP,
+		session.ExpiresAt,
+	).Scan(&session.CreatedAt, &session.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// Sessions returns the sessions of the user which are neither revoked nor expired,
+// most recently seen first.
+func (s *Storage) Sessions(ctx context.Context, userID int) ([]*model.Session, error) {
+	const operation = "storage.Sessions"
+
+	query := `
+        SELECT id, user_id, device, user_agent, ip, created_at, last_seen_at, expires_at
+        FROM sessions
+        WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+        ORDER BY last_seen_at DESC
+    `
+
+	rows, err := s.PostgresDB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+	defer rows.Close()
+
+	sessions := make([]*model.Session, 0)
+	for rows.Next() {
+		session := &model.Session{}
+
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.Device,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return sessions, nil
+}
+
+// TouchSession updates the last-seen time of the session and reports
+// whether it has been revoked.
+//
+// An unknown session is not revoked, logins made before sessions were
+// recorded get their session on the next refresh.
+func (s *Storage) TouchSession(ctx context.Context, sessionID string) (bool, error) {
+	const operation = "storage.TouchSession"
+
+	query := "UPDATE sessions SET last_seen_at = NOW() WHERE id = $1 RETURNING revoked_at IS NOT NULL"
+
+	var revoked bool
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, sessionID).Scan(&revoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return revoked, nil
+}
+
+// RevokeSession ends the session of the user and revokes its refresh tokens.
+//
+// If the user has no such active session it returns storage.ErrNotFound.
+func (s *Storage) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	const operation = "storage.RevokeSession"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	var revokedID string
+
+	query := "UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL RETURNING id"
+
+	err = tx.QueryRowContext(ctx, query, sessionID, userID).Scan(&revokedID)
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", sessionID)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions ends every session of the user except the kept one
+// and revokes their refresh tokens and personal access tokens.
+// It returns the IDs of the ended sessions.
+func (s *Storage) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) ([]string, error) {
+	const operation = "storage.RevokeOtherSessions"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	query := "UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL RETURNING id"
+
+	rows, err := tx.QueryContext(ctx, query, userID, keepSessionID)
+	if err != nil {
+		tx.Rollback()
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	sessionIDs := make([]string, 0)
+	for rows.Next() {
+		var sessionID string
+
+		if err := rows.Scan(&sessionID); err != nil {
+			rows.Close()
+			tx.Rollback()
+
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL", userID, keepSessionID)
+	if err != nil {
+		tx.Rollback()
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE personal_access_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		tx.Rollback()
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return sessionIDs, nil
+}
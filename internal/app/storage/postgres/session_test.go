@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStorage_RevokeSession(t *testing.T) {
+	const operation = "storage.RevokeSession"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE sessions").
+					WithArgs("session", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("session"))
+				mock.ExpectExec("UPDATE refresh_tokens").
+					WithArgs("session").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery("UPDATE sessions").
+					WithArgs("session", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := storage.RevokeSession(context.Background(), 1, "session")
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSessionStorage_TouchSession(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	mock.ExpectQuery("UPDATE sessions SET last_seen_at").
+		WithArgs("revoked").
+		WillReturnRows(sqlmock.NewRows([]string{"revoked"}).AddRow(true))
+	mock.ExpectQuery("UPDATE sessions SET last_seen_at").
+		WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"revoked"}))
+
+	revoked, err := storage.TouchSession(context.Background(), "revoked")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// Sessions of logins made before they were recorded are not revoked.
+	revoked, err = storage.TouchSession(context.Background(), "unknown")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionStorage_RevokeOtherSessions(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE sessions SET revoked_at").
+		WithArgs(1, "current").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("laptop").AddRow("phone"))
+	mock.ExpectExec("UPDATE refresh_tokens").
+		WithArgs(1, "current").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE personal_access_tokens SET revoked_at = NOW\\(\\) WHERE user_id = \\$1 AND revoked_at IS NULL").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	sessionIDs, err := storage.RevokeOtherSessions(context.Background(), 1, "current")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"laptop", "phone"}, sessionIDs)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionStorage_RevokeOtherSessions_PersonalTokensError(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE sessions SET revoked_at").
+		WithArgs(1, "current").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("laptop"))
+	mock.ExpectExec("UPDATE refresh_tokens").
+		WithArgs(1, "current").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE personal_access_tokens").
+		WithArgs(1).
+		WillReturnError(fmt.Errorf("db error"))
+	mock.ExpectRollback()
+
+	_, err := storage.RevokeOtherSessions(context.Background(), 1, "current")
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
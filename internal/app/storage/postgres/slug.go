@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// PostBySlug returns the post by its current slug.
+//
+// If no post has the slug it returns storage.ErrNotFound.
+func (s *Storage) PostBySlug(ctx context.Context, slug string) (*model.Post, error) {
+	const operation = "storage.PostBySlug"
+
+	query := "SELECT " + postColumns + " FROM posts WHERE slug = $1"
+
+	post, err := scanPost(s.PostgresDB.QueryRowContext(ctx, query, slug))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return post, nil
+}
+
+// RedirectedPost returns the post a former slug redirects to.
+//
+// If the slug is no former slug of a post it returns storage.ErrNotFound.
+func (s *Storage) RedirectedPost(ctx context.Context, slug string) (*model.Post, error) {
+	const operation = "storage.RedirectedPost"
+
+	query := "SELECT " + postColumns + " FROM posts WHERE id = (SELECT post_id FROM post_slug_redirects WHERE slug = $1)"
+
+	post, err := scanPost(s.PostgresDB.QueryRowContext(ctx, query, slug))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return post, nil
+}
+
+// uniquePostSlug returns the base slug, or the base with the lowest number
+// suffix from 2 on, that neither another post uses nor redirects from.
+// A new post has no ID yet and is passed as 0.
+//
+// The base is locked until the transaction ends, so concurrent saves of
+// posts with the same base wait for each other instead of picking the
+// same slug.
+func uniquePostSlug(ctx context.Context, tx *sql.Tx, base string, postID int) (string, error) {
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", base); err != nil {
+		return "", err
+	}
+
+	query := `
+        SELECT slug FROM posts WHERE (slug = $1 OR slug LIKE $2) AND id <> $3
+        UNION
+        SELECT slug FROM post_slug_redirects WHERE (slug = $1 OR slug LIKE $2) AND post_id <> $3
+    `
+
+	rows, err := tx.QueryContext(ctx, query, base, base+"-%", postID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", err
+		}
+
+		taken[slug] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = base + "-" + strconv.Itoa(n)
+	}
+
+	return slug, nil
+}
+
+// changePostSlug moves the post to a slug of post.Slug if the current slug
+// is not made of it, and keeps the current slug as a redirect. An empty
+// post.Slug keeps the current slug. post.Slug is set to the slug the post
+// ends up with.
+func changePostSlug(ctx context.Context, tx *sql.Tx, post *model.Post, current string) error {
+	if post.Slug == "" || hasSlugBase(current, post.Slug) {
+		post.Slug = current
+
+		return nil
+	}
+
+	slug, err := uniquePostSlug(ctx, tx, post.Slug, post.ID)
+	if err != nil {
+		return err
+	}
+
+	// The post may take back one of its former slugs.
+	query := "DELETE FROM post_slug_redirects WHERE slug = $1 AND post_id = $2"
+	if _, err := tx.ExecContext(ctx, query, slug, post.ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE posts SET slug = $1 WHERE id = $2", slug, post.ID); err != nil {
+		return err
+	}
+
+	query = "INSERT INTO post_slug_redirects (slug, post_id) VALUES ($1, $2)"
+	if _, err := tx.ExecContext(ctx, query, current, post.ID); err != nil {
+		return err
+	}
+
+	post.Slug = slug
+
+	return nil
+}
+
+// hasSlugBase reports whether the slug is the base, or the base with a
+// number suffix given by uniquePostSlug.
+func hasSlugBase(slug, base string) bool {
+	if slug == base {
+		return true
+	}
+
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	if !ok {
+		return false
+	}
+
+	n, err := strconv.Atoi(suffix)
+
+	return err == nil && n >= 2 && strconv.Itoa(n) == suffix
+}
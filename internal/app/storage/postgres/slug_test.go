@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectPostSlugs expects the lock of base and the lookup of the slugs of
+// base that posts other than postID use or redirect from, and returns the
+// taken ones.
+func expectPostSlugs(mock sqlmock.Sqlmock, base string, postID int, taken ...string) {
+	rows := sqlmock.NewRows([]string{"slug"})
+	for _, slug := range taken {
+		rows.AddRow(slug)
+	}
+
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(hashtext\\(\\$1\\)\\)").
+		WithArgs(base).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery("SELECT slug FROM posts WHERE \\(slug = \\$1 OR slug LIKE \\$2\\) AND id <> \\$3 UNION SELECT slug FROM post_slug_redirects").
+		WithArgs(base, base+"-%", postID).
+		WillReturnRows(rows)
+}
+
+func TestPostStorage_PostBySlug(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT id, title, slug, .+ FROM posts WHERE slug = \\$1").
+		WithArgs("hello-world").
+		WillReturnRows(sqlmock.NewRows(postRowColumns).
+			AddRow(1, "Hello, World", "hello-world", "Content", "plain", 1, 0, "published", nil, 2, created, created, "{}"))
+
+	post, err := storage.PostBySlug(context.Background(), "hello-world")
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Post{
+		ID:        1,
+		Title:     "Hello, World",
+		Slug:      "hello-world",
+		Content:   "Content",
+		Format:    model.ContentFormatPlain,
+		UserID:    1,
+		Tags:      []string{},
+		Status:    model.PostStatusPublished,
+		Version:   2,
+		CreatedAt: created,
+		UpdatedAt: created,
+	}, post)
+
+	mock.ExpectQuery("FROM posts WHERE slug = \\$1").
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = storage.PostBySlug(context.Background(), "missing")
+	assert.ErrorIs(t, err, st.ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostStorage_RedirectedPost(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	query := "FROM posts WHERE id = \\(SELECT post_id FROM post_slug_redirects WHERE slug = \\$1\\)"
+
+	mock.ExpectQuery(query).
+		WithArgs("hello").
+		WillReturnRows(sqlmock.NewRows(postRowColumns).
+			AddRow(1, "Hello, World", "hello-world", "Content", "plain", 1, 0, "published", nil, 2, created, created, "{}"))
+
+	post, err := storage.RedirectedPost(context.Background(), "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello-world", post.Slug)
+
+	mock.ExpectQuery(query).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = storage.RedirectedPost(context.Background(), "missing")
+	assert.ErrorIs(t, err, st.ErrNotFound)
+
+	mock.ExpectQuery(query).
+		WithArgs("hello").
+		WillReturnError(errors.New("error"))
+
+	_, err = storage.RedirectedPost(context.Background(), "hello")
+	assert.EqualError(t, err, "storage.RedirectedPost: error")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestHasSlugBase(t *testing.T) {
+	tests := []struct {
+		slug string
+		base string
+		want bool
+	}{
+		{slug: "hello", base: "hello", want: true},
+		{slug: "hello-2", base: "hello", want: true},
+		{slug: "hello-12", base: "hello", want: true},
+		{slug: "hello-1", base: "hello", want: false},
+		{slug: "hello-02", base: "hello", want: false},
+		{slug: "hello-world", base: "hello", want: false},
+		{slug: "hello", base: "hello-world", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slug+" "+tt.base, func(t *testing.T) {
+			assert.Equal(t, tt.want, hasSlugBase(tt.slug, tt.base))
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// Tags returns the tags of published posts, the most used first.
+func (s *Storage) Tags(ctx context.Context) ([]*model.Tag, error) {
+	const operation = "storage.Tags"
+
+	query := `
+        SELECT t.slug, COUNT(*) AS posts_count
+        FROM tags t
+        JOIN post_tags pt ON pt.tag_id = t.id
+        JOIN posts p ON p.id = pt.post_id AND p.status = 'published'
+        GROUP BY t.slug
+        ORDER BY posts_count DESC, t.slug
+    `
+
+	rows, err := s.PostgresDB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+	defer rows.Close()
+
+	tags := make([]*model.Tag, 0)
+	for rows.Next() {
+		tag := &model.Tag{}
+
+		if err := rows.Scan(&tag.Slug, &tag.PostsCount); err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return tags, nil
+}
+
+// Tag returns the tag by its slug.
+//
+// If no published post uses the tag it returns storage.ErrNotFound.
+func (s *Storage) Tag(ctx context.Context, slug string) (*model.Tag, error) {
+	const operation = "storage.Tag"
+
+	query := `
+        SELECT t.slug, COUNT(*)
+        FROM tags t
+        JOIN post_tags pt ON pt.tag_id = t.id
+        JOIN posts p ON p.id = pt.post_id AND p.status = 'published'
+        WHERE t.slug = $1
+        GROUP BY t.slug
+    `
+
+	tag := &model.Tag{}
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, slug).Scan(&tag.Slug, &tag.PostsCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return tag, nil
+}
+
+// setPostTags replaces the tags of the post, missing tags are created.
+func setPostTags(ctx context.Context, tx *sql.Tx, postID int, slugs []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = $1", postID); err != nil {
+		return err
+	}
+
+	if len(slugs) == 0 {
+		return nil
+	}
+
+	query := "INSERT INTO tags (slug) SELECT UNNEST($1::text[]) ON CONFLICT (slug) DO NOTHING"
+	if _, err := tx.ExecContext(ctx, query, pq.Array(slugs)); err != nil {
+		return err
+	}
+
+	query = "INSERT INTO post_tags (post_id, tag_id) SELECT $1, id FROM tags WHERE slug = ANY($2)"
+	if _, err := tx.ExecContext(ctx, query, postID, pq.Array(slugs)); err != nil {
+		return err
+	}
+
+	return nil
+}
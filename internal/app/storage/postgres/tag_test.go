@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagStorage_Tags(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	mock.ExpectQuery("SELECT t.slug, COUNT\\(\\*\\) AS posts_count FROM tags t JOIN post_tags pt ON pt.tag_id = t.id JOIN posts p ON p.id = pt.post_id AND p.status = 'published' GROUP BY t.slug ORDER BY posts_count DESC, t.slug").
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "posts_count"}).
+			AddRow("golang", 3).
+			AddRow("web", 1))
+
+	tags, err := storage.Tags(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Tag{{Slug: "golang", PostsCount: 3}, {Slug: "web", PostsCount: 1}}, tags)
+
+	mock.ExpectQuery("FROM tags t").WillReturnError(errors.New("error"))
+
+	_, err = storage.Tags(context.Background())
+	assert.EqualError(t, err, "storage.Tags: error")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTagStorage_Tag(t *testing.T) {
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	mock.ExpectQuery("FROM tags t JOIN post_tags pt ON pt.tag_id = t.id JOIN posts p ON p.id = pt.post_id AND p.status = 'published' WHERE t.slug = \\$1").
+		WithArgs("golang").
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "count"}).AddRow("golang", 3))
+
+	tag, err := storage.Tag(context.Background(), "golang")
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Tag{Slug: "golang", PostsCount: 3}, tag)
+
+	// Tags without posts are not listed either.
+	mock.ExpectQuery("FROM tags t JOIN post_tags pt ON pt.tag_id = t.id JOIN posts p ON p.id = pt.post_id AND p.status = 'published' WHERE t.slug = \\$1").
+		WithArgs("unused").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = storage.Tag(context.Background(), "unused")
+	assert.ErrorIs(t, err, st.ErrNotFound)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// SaveRefreshToken saves the hash of a newly issued refresh token.
+func (s *Storage) SaveRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	const operation = "storage.SaveRefreshToken"
+
+	query := `
+        INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt).Scan(&token.ID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken marks the refresh token as used and returns it.
+//
+// The token can be rotated only once, so two concurrent refreshes with
+// the same token can not both succeed.
+//
+// If the token does not exist it returns storage.ErrNotFound.
+// If the token has already been rotated it returns storage.ErrTokenReused.
+// If the token has been revoked it returns storage.ErrTokenRevoked.
+func (s *Storage) RotateRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	const operation = "storage.RotateRefreshToken"
+
+	query := `
+        UPDATE refresh_tokens
+        SET rotated_at = NOW()
+        WHERE token_hash = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+        RETURNING id, user_id, family_id, token_hash, expires_at
+    `
+
+	token := &model.RefreshToken{}
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, tokenHash).
+		Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			stateQuery := "SELECT rotated_at IS NOT NULL FROM refresh_tokens WHERE token_hash = $1"
+
+			var rotated bool
+
+			err := s.PostgresDB.QueryRowContext(ctx, stateQuery, tokenHash).Scan(&rotated)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+				}
+				return nil, fmt.Errorf("%s: %w", operation, err)
+			}
+
+			if rotated {
+				return nil, fmt.Errorf("%s: %w", operation, storage.ErrTokenReused)
+			}
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrTokenRevoked)
+		}
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return token, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token of the family
+// the provided token belongs to.
+func (s *Storage) RevokeRefreshTokenFamily(ctx context.Context, tokenHash string) error {
+	const operation = "storage.RevokeRefreshTokenFamily"
+
+	query := `
+        UPDATE refresh_tokens
+        SET revoked_at = NOW()
+        WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1) AND revoked_at IS NULL
+    `
+
+	_, err := s.PostgresDB.ExecContext(ctx, query, tokenHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
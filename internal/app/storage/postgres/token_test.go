@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenStorage_RotateRefreshToken(t *testing.T) {
+	const operation = "storage.RotateRefreshToken"
+	var err = errors.New("error")
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		tokenHash string
+		mock      func()
+		wantToken *model.RefreshToken
+		wantErr   error
+	}{
+		{
+			name:      "Success",
+			tokenHash: "hash",
+			mock: func() {
+				mock.ExpectQuery("UPDATE refresh_tokens SET rotated_at = NOW\\(\\)").
+					WithArgs("hash").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "token_hash", "expires_at"}).
+						AddRow(1, 1, "family", "hash", expiresAt))
+			},
+			wantToken: &model.RefreshToken{ID: 1, UserID: 1, FamilyID: "family", TokenHash: "hash", ExpiresAt: expiresAt},
+			wantErr:   nil,
+		},
+		{
+			name:      "Token not found",
+			tokenHash: "hash",
+			mock: func() {
+				mock.ExpectQuery("UPDATE refresh_tokens SET rotated_at = NOW\\(\\)").
+					WithArgs("hash").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("SELECT rotated_at IS NOT NULL FROM refresh_tokens WHERE token_hash = \\$1").
+					WithArgs("hash").
+					WillReturnError(sql.ErrNoRows)
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+		{
+			name:      "Token reused",
+			tokenHash: "hash",
+			mock: func() {
+				mock.ExpectQuery("UPDATE refresh_tokens SET rotated_at = NOW\\(\\)").
+					WithArgs("hash").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("SELECT rotated_at IS NOT NULL FROM refresh_tokens WHERE token_hash = \\$1").
+					WithArgs("hash").
+					WillReturnRows(sqlmock.NewRows([]string{"rotated"}).AddRow(true))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrTokenReused),
+		},
+		{
+			name:      "Token revoked",
+			tokenHash: "hash",
+			mock: func() {
+				mock.ExpectQuery("UPDATE refresh_tokens SET rotated_at = NOW\\(\\)").
+					WithArgs("hash").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("SELECT rotated_at IS NOT NULL FROM refresh_tokens WHERE token_hash = \\$1").
+					WithArgs("hash").
+					WillReturnRows(sqlmock.NewRows([]string{"rotated"}).AddRow(false))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrTokenRevoked),
+		},
+		{
+			name:      "Error",
+			tokenHash: "hash",
+			mock: func() {
+				mock.ExpectQuery("UPDATE refresh_tokens SET rotated_at = NOW\\(\\)").
+					WithArgs("hash").
+					WillReturnError(err)
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, err),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			token, err := storage.RotateRefreshToken(context.Background(), tt.tokenHash)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantToken, token)
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
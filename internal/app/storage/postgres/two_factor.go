@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// TwoFactor returns the TOTP state of the user.
+func (s *Storage) TwoFactor(ctx context.Context, userID int) (*model.TwoFactor, error) {
+	const operation = "storage.TwoFactor"
+
+	query := "SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = $1"
+
+	var (
+		secret    sql.NullString
+		enabledAt sql.NullTime
+		lastStep  int64
+	)
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, userID).Scan(&secret, &enabledAt, &lastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tf := &model.TwoFactor{Secret: secret.String, LastStep: lastStep}
+	if enabledAt.Valid {
+		tf.EnabledAt = &enabledAt.Time
+	}
+
+	return tf, nil
+}
+
+// SaveTOTPSecret stores a new, not yet confirmed, TOTP secret.
+//
+// If the second factor is already enabled it returns storage.ErrAlreadyExists.
+func (s *Storage) SaveTOTPSecret(ctx context.Context, userID int, secret string) error {
+	const operation = "storage.SaveTOTPSecret"
+
+	query := "UPDATE users SET totp_secret = $1 WHERE id = $2 AND totp_enabled_at IS NULL"
+
+	result, err := s.PostgresDB.ExecContext(ctx, query, secret, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("%s: %w", operation, storage.ErrAlreadyExists)
+	}
+
+	return nil
+}
+
+// EnableTOTP requires the second factor on login and stores the hashes of
+// the initial recovery codes.
+func (s *Storage) EnableTOTP(ctx context.Context, userID int, codeHashes []string) error {
+	const operation = "storage.EnableTOTP"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	query := "UPDATE users SET totp_enabled_at = NOW() WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL"
+
+	result, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if rows == 0 {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// DisableTOTP removes the TOTP secret and all recovery codes of the user.
+func (s *Storage) DisableTOTP(ctx context.Context, userID int) error {
+	const operation = "storage.DisableTOTP"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL WHERE id = $1", userID)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes invalidates all recovery codes of the user and stores the new ones.
+func (s *Storage) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	const operation = "storage.ReplaceRecoveryCodes"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep remembers the time step of an accepted TOTP code.
+//
+// If a code of this step or a later one has already been accepted it
+// returns storage.ErrNotFound.
+func (s *Storage) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	const operation = "storage.UseTOTPStep"
+
+	query := "UPDATE users SET totp_last_step = $2 WHERE id = $1 AND totp_last_step < $2"
+
+	result, err := s.PostgresDB.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks the recovery code as used.
+//
+// If the code does not exist or has already been used it returns storage.ErrNotFound.
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	const operation = "storage.UseRecoveryCode"
+
+	query := "UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL"
+
+	result, err := s.PostgresDB.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.ExecContext(ctx, "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactorStorage_EnableTOTP(t *testing.T) {
+	const operation = "storage.EnableTOTP"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE users SET totp_enabled_at = NOW\\(\\)").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("DELETE FROM recovery_codes WHERE user_id = \\$1").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("INSERT INTO recovery_codes").
+					WithArgs(1, "first").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("INSERT INTO recovery_codes").
+					WithArgs(1, "second").
+					WillReturnResult(sqlmock.NewResult(2, 1))
+				mock.ExpectCommit()
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Not Enrolled",
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE users SET totp_enabled_at = NOW\\(\\)").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := storage.EnableTOTP(context.Background(), 1, []string{"first", "second"})
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestTwoFactorStorage_UseRecoveryCode(t *testing.T) {
+	const operation = "storage.UseRecoveryCode"
+	var err = errors.New("error")
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectExec("UPDATE recovery_codes SET used_at = NOW\\(\\)").
+					WithArgs(1, "hash").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Already Used",
+			mock: func() {
+				mock.ExpectExec("UPDATE recovery_codes SET used_at = NOW\\(\\)").
+					WithArgs(1, "hash").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+		{
+			name: "Error",
+			mock: func() {
+				mock.ExpectExec("UPDATE recovery_codes SET used_at = NOW\\(\\)").
+					WithArgs(1, "hash").
+					WillReturnError(err)
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, err),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := storage.UseRecoveryCode(context.Background(), 1, "hash")
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestTwoFactorStorage_UseTOTPStep(t *testing.T) {
+	const operation = "storage.UseTOTPStep"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectExec("UPDATE users SET totp_last_step = \\$2 WHERE id = \\$1 AND totp_last_step < \\$2").
+					WithArgs(1, int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: nil,
+		},
+		{
+			name: "Replayed",
+			mock: func() {
+				mock.ExpectExec("UPDATE users SET totp_last_step = \\$2 WHERE id = \\$1 AND totp_last_step < \\$2").
+					WithArgs(1, int64(100)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := storage.UseTOTPStep(context.Background(), 1, 100)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// deletedUserEmail identifies the placeholder user which keeps the
+// anonymised content of deleted accounts.
+const deletedUserEmail = "deleted-user@invalid"
+
+// Account returns the profile of the user with the number of its posts
+// and the private details of the account.
+//
+// If the user does not exist it returns storage.ErrNotFound.
+func (s *Storage) Account(ctx context.Context, userID int) (*model.Account, error) {
+	const operation = "storage.Account"
+
+	query := `
+        SELECT u.id, u.username, u.display_name, u.bio, u.website, u.avatar_url, u.created_at,
+            u.email, u.role, u.email_verified_at,
+            (SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id AND p.status = 'published')
+        FROM users u
+        WHERE u.id = $1
+    `
+
+	account := &model.Account{}
+
+	var createdAt, emailVerifiedAt sql.NullTime
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, userID).Scan(
+		&account.ID,
+		&account.Username,
+		&account.DisplayName,
+		&account.Bio,
+		&account.Website,
+		&account.AvatarURL,
+		&createdAt,
+		&account.Email,
+		&account.Role,
+		&emailVerifiedAt,
+		&account.PostsCount,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	account.CreatedAt = createdAt.Time
+
+	if emailVerifiedAt.Valid {
+		account.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+
+	return account, nil
+}
+
+// UpdateProfile sets the profile fields present in the request,
+// the others keep their values.
+//
+// If the user does not exist it returns storage.ErrNotFound.
+func (s *Storage) UpdateProfile(ctx context.Context, userID int, profile *model.ProfileRequest) error {
+	const operation = "storage.UpdateProfile"
+
+	query := `
+        UPDATE users SET
+            username = COALESCE($1, username),
+            display_name = COALESCE($2, display_name),
+            bio = COALESCE($3, bio),
+            website = COALESCE($4, website),
+            avatar_url = COALESCE($5, avatar_url),
+            updated_at = NOW()
+        WHERE id = $6
+        RETURNING id
+    `
+
+	var updatedUserID int
+
+	err := s.PostgresDB.QueryRowContext(ctx, query,
+		profile.Username,
+		profile.DisplayName,
+		profile.Bio,
+		profile.Website,
+		profile.AvatarURL,
+		userID,
+	).Scan(&updatedUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// UserPosts returns the posts of the user, newest first.
+// Unless all is set, only the published ones.
+func (s *Storage) UserPosts(ctx context.Context, userID int, all bool) ([]*model.Post, error) {
+	const operation = "storage.UserPosts"
+
+	query := "SELECT " + postColumns + " FROM posts WHERE user_id = $1 AND ($2 OR status = 'published') ORDER BY created_at DESC, id DESC"
+
+	rows, err := s.PostgresDB.QueryContext(ctx, query, userID, all)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+	defer rows.Close()
+
+	posts := make([]*model.Post, 0)
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return posts, nil
+}
+
+// UserComments returns the comments written by the user.
+func (s *Storage) UserComments(ctx context.Context, userID int) ([]*model.Comment, error) {
+	const operation = "storage.UserComments"
+
+	query := "SELECT " + commentColumns + " FROM comments WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at"
+
+	rows, err := s.PostgresDB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+	defer rows.Close()
+
+	comments := make([]*model.Comment, 0)
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return comments, nil
+}
+
+// DeleteUser deletes the account of the user together with its tokens,
+// sessions and linked identities.
+//
+// With model.DeletionPolicyAnonymize the posts and comments of the user
+// are moved to the deleted user placeholder, otherwise they are deleted
+// and the comment counters of other posts are corrected. Comments that
+// other users replied to are kept as placeholders, like deleteComment
+// does, so the threads stay intact.
+// If the user does not exist it returns storage.ErrNotFound.
+func (s *Storage) DeleteUser(ctx context.Context, userID int, policy model.DeletionPolicy) error {
+	const operation = "storage.DeleteUser"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if policy == model.DeletionPolicyAnonymize {
+		err = anonymizeUserContent(ctx, tx, userID)
+	} else {
+		err = deleteUserComments(ctx, tx, userID)
+	}
+
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	var deletedUserID int
+
+	err = tx.QueryRowContext(ctx, "DELETE FROM users WHERE id = $1 AND email <> $2 RETURNING id", userID, deletedUserEmail).Scan(&deletedUserID)
+	if err != nil {
+		tx.Rollback()
+
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// anonymizeUserContent moves the posts and comments of the user to the deleted user placeholder.
+func anonymizeUserContent(ctx context.Context, tx *sql.Tx, userID int) error {
+	for _, table := range []string{"posts", "comments"} {
+		query := "UPDATE " + table + " SET user_id = (SELECT id FROM users WHERE email = $1) WHERE user_id = $2"
+
+		if _, err := tx.ExecContext(ctx, query, deletedUserEmail, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteUserComments deletes the comments of the user the same way
+// deleteComment does.
+//
+// Comments with replies of other users are turned into placeholders of
+// the deleted user, the rest is deleted and the placeholders left without
+// replies are pruned.
+func deleteUserComments(ctx context.Context, tx *sql.Tx, userID int) error {
+	if err := uncountUserComments(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	query := `
+        WITH RECURSIVE thread AS (
+            SELECT id AS root_id, id FROM comments WHERE user_id = $1
+            UNION ALL
+            SELECT t.root_id, c.id FROM comments c JOIN thread t ON c.parent_id = t.id
+        )
+        UPDATE comments
+        SET content = $2, format = 'plain', deleted_at = COALESCE(deleted_at, NOW()),
+            user_id = (SELECT id FROM users WHERE email = $3)
+        WHERE id IN (
+            SELECT t.root_id FROM thread t JOIN comments c ON c.id = t.id
+            WHERE c.user_id <> $1 AND c.deleted_at IS NULL
+        )
+    `
+
+	if _, err := tx.ExecContext(ctx, query, userID, model.DeletedCommentContent, deletedUserEmail); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, "DELETE FROM comments WHERE user_id = $1 RETURNING parent_id", userID)
+	if err != nil {
+		return err
+	}
+
+	parentIDs := make([]sql.NullInt64, 0)
+	for rows.Next() {
+		var parentID sql.NullInt64
+		if err := rows.Scan(&parentID); err != nil {
+			rows.Close()
+
+			return err
+		}
+
+		parentIDs = append(parentIDs, parentID)
+	}
+
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, parentID := range parentIDs {
+		if err := pruneDeletedComments(ctx, tx, parentID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uncountUserComments decrements the comment counters of the posts of
+// other users by the comments of the user, which are about to be deleted.
+func uncountUserComments(ctx context.Context, tx *sql.Tx, userID int) error {
+	query := `
+        UPDATE posts p SET comments_count = p.comments_count - c.count
+        FROM (SELECT post_id, COUNT(*) AS count FROM comments WHERE user_id = $1 AND deleted_at IS NULL GROUP BY post_id) c
+        WHERE p.id = c.post_id AND p.user_id <> $1
+    `
+
+	_, err := tx.ExecContext(ctx, query, userID)
+
+	return err
+}
@@ -0,0 +1,227 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	st "github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserStorage_Account(t *testing.T) {
+	const operation = "storage.Account"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	columns := []string{"id", "username", "display_name", "bio", "website", "avatar_url", "created_at", "email", "role", "email_verified_at", "count"}
+
+	tests := []struct {
+		name        string
+		mock        func()
+		wantAccount *model.Account
+		wantErr     error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectQuery("SELECT (.+) FROM users u WHERE u.id = \\$1").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow(1, "user", "Jane", "bio", "https://example.com", "", createdAt, "user@example.com", "author", nil, 2))
+			},
+			wantAccount: &model.Account{
+				Profile: model.Profile{
+					ID:          1,
+					Username:    "user",
+					DisplayName: "Jane",
+					Bio:         "bio",
+					Website:     "https://example.com",
+					PostsCount:  2,
+					CreatedAt:   createdAt,
+				},
+				Email: "user@example.com",
+				Role:  model.RoleAuthor,
+			},
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectQuery("SELECT (.+) FROM users u WHERE u.id = \\$1").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows(columns))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			account, err := storage.Account(context.Background(), 1)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantAccount, account)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserStorage_UpdateProfile(t *testing.T) {
+	const operation = "storage.UpdateProfile"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	bio := "bio"
+
+	tests := []struct {
+		name    string
+		mock    func()
+		wantErr error
+	}{
+		{
+			name: "Success",
+			mock: func() {
+				mock.ExpectQuery("UPDATE users SET").
+					WithArgs(nil, nil, bio, nil, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+			},
+		},
+		{
+			name: "Not Found",
+			mock: func() {
+				mock.ExpectQuery("UPDATE users SET").
+					WithArgs(nil, nil, bio, nil, nil, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := storage.UpdateProfile(context.Background(), 1, &model.ProfileRequest{Bio: &bio})
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserStorage_DeleteUser(t *testing.T) {
+	const operation = "storage.DeleteUser"
+
+	storage, mock, closeDB := prepareStorage(t)
+	defer closeDB()
+
+	tests := []struct {
+		name    string
+		policy  model.DeletionPolicy
+		mock    func()
+		wantErr error
+	}{
+		{
+			name:   "Anonymize",
+			policy: model.DeletionPolicyAnonymize,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE posts SET user_id").
+					WithArgs(deletedUserEmail, 1).
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("UPDATE comments SET user_id").
+					WithArgs(deletedUserEmail, 1).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectQuery("DELETE FROM users").
+					WithArgs(1, deletedUserEmail).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "Delete",
+			policy: model.DeletionPolicyDelete,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE posts p SET comments_count").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				// Comment 5 has replies of other users and stays as a placeholder,
+				// its reply 6 of the user is deleted. The placeholder 4 of another
+				// user only had the reply 7 of the user and is pruned.
+				mock.ExpectExec("WITH RECURSIVE thread AS .+ UPDATE comments SET content = \\$2, format = 'plain', deleted_at = COALESCE\\(deleted_at, NOW\\(\\)\\), user_id = \\(SELECT id FROM users WHERE email = \\$3\\)").
+					WithArgs(1, model.DeletedCommentContent, deletedUserEmail).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery("DELETE FROM comments WHERE user_id = \\$1 RETURNING parent_id").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(5).AddRow(4).AddRow(nil))
+				mock.ExpectQuery("DELETE FROM comments c WHERE id = \\$1 AND deleted_at IS NOT NULL").
+					WithArgs(int64(5)).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectQuery("DELETE FROM comments c WHERE id = \\$1 AND deleted_at IS NOT NULL").
+					WithArgs(int64(4)).
+					WillReturnRows(sqlmock.NewRows([]string{"parent_id"}).AddRow(nil))
+				mock.ExpectQuery("DELETE FROM users").
+					WithArgs(1, deletedUserEmail).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "Not Found",
+			policy: model.DeletionPolicyDelete,
+			mock: func() {
+				mock.ExpectBegin()
+				mock.ExpectExec("UPDATE posts p SET comments_count").
+					WithArgs(1).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("WITH RECURSIVE thread AS").
+					WithArgs(1, model.DeletedCommentContent, deletedUserEmail).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery("DELETE FROM comments WHERE user_id").
+					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"parent_id"}))
+				mock.ExpectQuery("DELETE FROM users").
+					WithArgs(1, deletedUserEmail).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+			},
+			wantErr: fmt.Errorf("%s: %w", operation, st.ErrNotFound),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mock()
+
+			err := storage.DeleteUser(context.Background(), 1, tt.policy)
+
+			if tt.wantErr != nil {
+				assert.EqualError(t, err, tt.wantErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/markraiter/simple-blog/internal/app/storage"
+	"github.com/markraiter/simple-blog/internal/model"
+)
+
+// SaveUserToken saves the hash of a new single-use token.
+//
+// Unused tokens of the same purpose issued to the user earlier are
+// invalidated, so only the latest emailed link works.
+func (s *Storage) SaveUserToken(ctx context.Context, token *model.UserToken) error {
+	const operation = "storage.SaveUserToken"
+
+	tx, err := s.PostgresDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	invalidateQuery := "UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL"
+	_, err = tx.ExecContext(ctx, invalidateQuery, token.UserID, token.Purpose)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	query := `
+        INSERT INTO user_tokens (user_id, purpose, token_hash, new_email, expires_at)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+        RETURNING id
+    `
+
+	err = tx.QueryRowContext(ctx, query, token.UserID, token.Purpose, token.TokenHash, token.NewEmail, token.ExpiresAt).Scan(&token.ID)
+	if err != nil {
+		tx.Rollback()
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// ConsumeUserToken marks the token as used and returns it.
+//
+// If the token does not exist, has expired, has already been used or was
+// issued for another purpose it returns storage.ErrNotFound.
+func (s *Storage) ConsumeUserToken(ctx context.Context, purpose model.TokenPurpose, tokenHash string) (*model.UserToken, error) {
+	const operation = "storage.ConsumeUserToken"
+
+	query := `
+        UPDATE user_tokens
+        SET used_at = NOW()
+        WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+        RETURNING id, user_id, purpose, token_hash, COALESCE(new_email, ''), expires_at
+    `
+
+	token := &model.UserToken{}
+
+	err := s.PostgresDB.QueryRowContext(ctx, query, tokenHash, purpose).
+		Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.NewEmail, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", operation, storage.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return token, nil
+}
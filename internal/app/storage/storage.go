@@ -7,4 +7,7 @@ var (
 	ErrNotFound      = errors.New("not found")
 	ErrNotAllowed    = errors.New("not allowed")
 	ErrPostNotExists = errors.New("post with such ID does not exist")
+	ErrTokenReused   = errors.New("token has already been used")
+	ErrTokenRevoked  = errors.New("token has been revoked")
+	ErrConflict      = errors.New("version conflict")
 )
@@ -0,0 +1,173 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around the changes.
+const context = 3
+
+type edit struct {
+	kind byte // ' ' keeps, '-' removes and '+' adds the line.
+	line string
+	// a and b are the indexes of the line in the old and the new text.
+	a, b int
+}
+
+// Unified returns the unified diff of the lines of a and b labelled
+// with fromName and toName, or an empty string if they are equal.
+//
+// Lines are compared without their line endings.
+func Unified(fromName, toName, a, b string) string {
+	edits := diffLines(split(a), split(b))
+
+	var out strings.Builder
+
+	for i := 0; i < len(edits); {
+		if edits[i].kind == ' ' {
+			i++
+
+			continue
+		}
+
+		start := max(i-context, 0)
+
+		// Changes closer than twice the context share a hunk.
+		end := i
+		for end < len(edits) {
+			if edits[end].kind != ' ' {
+				end++
+
+				continue
+			}
+
+			next := end
+			for next < len(edits) && edits[next].kind == ' ' {
+				next++
+			}
+
+			if next == len(edits) || next-end > 2*context {
+				end = min(end+context, len(edits))
+
+				break
+			}
+
+			end = next
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+
+		writeHunk(&out, edits[start:end])
+
+		i = end
+	}
+
+	return out.String()
+}
+
+func writeHunk(out *strings.Builder, edits []edit) {
+	var aCount, bCount int
+
+	for _, e := range edits {
+		if e.kind != '+' {
+			aCount++
+		}
+
+		if e.kind != '-' {
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(edits[0].a, aCount), hunkRange(edits[0].b, bCount))
+
+	for _, e := range edits {
+		out.WriteByte(e.kind)
+		out.WriteString(e.line)
+		out.WriteByte('\n')
+	}
+}
+
+// hunkRange formats the lines of a hunk the way diff does: an empty
+// range starts at the line before it and a single line has no count.
+func hunkRange(index, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", index)
+	case 1:
+		return fmt.Sprintf("%d", index+1)
+	default:
+		return fmt.Sprintf("%d,%d", index+1, count)
+	}
+}
+
+func split(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script turning a into b, found
+// through the longest common subsequence of their lines.
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the longest common subsequence of midA[i:] and midB[j:].
+	lcs := make([][]int, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(midB)+1)
+	}
+
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	edits := make([]edit, 0, len(a)+len(b)-prefix-suffix)
+
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{kind: ' ', line: a[i], a: i, b: i})
+	}
+
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			edits = append(edits, edit{kind: ' ', line: midA[i], a: prefix + i, b: prefix + j})
+			i++
+			j++
+		case j == len(midB) || (i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{kind: '-', line: midA[i], a: prefix + i, b: prefix + j})
+			i++
+		default:
+			edits = append(edits, edit{kind: '+', line: midB[j], a: prefix + i, b: prefix + j})
+			j++
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		ai, bi := len(a)-suffix+k, len(b)-suffix+k
+		edits = append(edits, edit{kind: ' ', line: a[ai], a: ai, b: bi})
+	}
+
+	return edits
+}
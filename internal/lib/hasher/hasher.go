@@ -0,0 +1,208 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/markraiter/simple-blog/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	argon2Prefix     = "$" + AlgorithmArgon2id + "$"
+)
+
+var (
+	ErrMismatch         = errors.New("password does not match")
+	ErrMalformedHash    = errors.New("malformed password hash")
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrInvalidParams    = errors.New("invalid password hash parameters")
+)
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	keyLen  uint32
+}
+
+// Hasher hashes passwords with the configured algorithm.
+//
+// Hashes are self-describing: bcrypt hashes carry their cost and argon2id
+// hashes use the PHC string format
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>, so hashes
+// made with other algorithms or parameters can still be verified.
+type Hasher struct {
+	algorithm  string
+	bcryptCost int
+	argon2     argon2Params
+}
+
+// New creates a hasher from the config.
+//
+// PASSWORD_HASHER chooses the algorithm new hashes are made with, either
+// bcrypt with BCRYPT_COST or argon2id with ARGON2_TIME, ARGON2_MEMORY (KiB)
+// and ARGON2_THREADS.
+func New(cfg config.Auth) (*Hasher, error) {
+	const operation = "hasher.New"
+
+	h := &Hasher{
+		algorithm:  cfg.PasswordHasher,
+		bcryptCost: cfg.BcryptCost,
+		argon2: argon2Params{
+			time:    cfg.Argon2Time,
+			memory:  cfg.Argon2Memory,
+			threads: cfg.Argon2Threads,
+			keyLen:  argon2KeyLength,
+		},
+	}
+
+	switch h.algorithm {
+	case AlgorithmBcrypt:
+		if h.bcryptCost < bcrypt.MinCost || h.bcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("%s: %w: bcrypt cost %d", operation, ErrInvalidParams, h.bcryptCost)
+		}
+	case AlgorithmArgon2id:
+		if h.argon2.time == 0 || h.argon2.threads == 0 || h.argon2.memory < 8*uint32(h.argon2.threads) {
+			return nil, fmt.Errorf("%s: %w: argon2id t=%d m=%d p=%d", operation, ErrInvalidParams, h.argon2.time, h.argon2.memory, h.argon2.threads)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %w: %q", operation, ErrUnknownAlgorithm, h.algorithm)
+	}
+
+	return h, nil
+}
+
+// Hash returns the hash of the password made with the configured algorithm.
+func (h *Hasher) Hash(password string) (string, error) {
+	const operation = "hasher.Hash"
+
+	if h.algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", operation, err)
+		}
+
+		return string(hash), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	p := h.argon2
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, p.keyLen)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix,
+		argon2.Version,
+		p.memory,
+		p.time,
+		p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Compare checks the password against a hash made with any supported
+// algorithm. It returns ErrMismatch if the password is wrong.
+func (h *Hasher) Compare(hash, password string) error {
+	const operation = "hasher.Compare"
+
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return fmt.Errorf("%s: %w", operation, ErrMismatch)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %w: %w", operation, ErrMalformedHash, err)
+		}
+
+		return nil
+	}
+
+	p, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, p.keyLen)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return fmt.Errorf("%s: %w", operation, ErrMismatch)
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether the hash was made with another algorithm or
+// other parameters than the configured ones.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		if h.algorithm != AlgorithmBcrypt {
+			return true
+		}
+
+		cost, err := bcrypt.Cost([]byte(hash))
+
+		return err != nil || cost != h.bcryptCost
+	}
+
+	if h.algorithm != AlgorithmArgon2id {
+		return true
+	}
+
+	p, _, _, err := decodeArgon2(hash)
+
+	return err != nil || p != h.argon2
+}
+
+// decodeArgon2 splits an argon2id hash in the PHC string format into
+// its parameters, salt and key.
+func decodeArgon2(hash string) (argon2Params, []byte, []byte, error) {
+	var (
+		p       argon2Params
+		version int
+	)
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads)
+	if err != nil || p.time == 0 || p.threads == 0 {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	p.keyLen = uint32(len(key))
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,88 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newHasher(t *testing.T, cfg config.Auth) *Hasher {
+	h, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(config.Auth{PasswordHasher: "md5"})
+	assert.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	_, err = New(config.Auth{PasswordHasher: AlgorithmBcrypt, BcryptCost: 100})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+
+	_, err = New(config.Auth{PasswordHasher: AlgorithmArgon2id, Argon2Time: 1, Argon2Memory: 64})
+	assert.ErrorIs(t, err, ErrInvalidParams)
+}
+
+func TestHasher_HashAndCompare(t *testing.T) {
+	hashers := map[string]*Hasher{
+		AlgorithmBcrypt:   newHasher(t, config.Auth{PasswordHasher: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}),
+		AlgorithmArgon2id: newHasher(t, config.Auth{PasswordHasher: AlgorithmArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1}),
+	}
+
+	for name, h := range hashers {
+		t.Run(name, func(t *testing.T) {
+			hash, err := h.Hash("Password12345!")
+			assert.NoError(t, err)
+
+			other, err := h.Hash("Password12345!")
+			assert.NoError(t, err)
+			assert.NotEqual(t, hash, other)
+
+			assert.NoError(t, h.Compare(hash, "Password12345!"))
+			assert.ErrorIs(t, h.Compare(hash, "Wrong12345!"), ErrMismatch)
+			assert.False(t, h.NeedsRehash(hash))
+
+			// Hashes of the other algorithm are still verified.
+			for otherName, otherHasher := range hashers {
+				if otherName != name {
+					assert.NoError(t, otherHasher.Compare(hash, "Password12345!"))
+					assert.True(t, otherHasher.NeedsRehash(hash))
+				}
+			}
+		})
+	}
+}
+
+func TestHasher_Argon2Format(t *testing.T) {
+	h := newHasher(t, config.Auth{PasswordHasher: AlgorithmArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1})
+
+	hash, err := h.Hash("Password12345!")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"))
+
+	assert.ErrorIs(t, h.Compare("$argon2id$v=19$m=64,t=0,p=1$c2FsdA$a2V5", "Password12345!"), ErrMalformedHash)
+	assert.ErrorIs(t, h.Compare("$argon2id$broken", "Password12345!"), ErrMalformedHash)
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	weak := newHasher(t, config.Auth{PasswordHasher: AlgorithmArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1})
+	strong := newHasher(t, config.Auth{PasswordHasher: AlgorithmArgon2id, Argon2Time: 2, Argon2Memory: 128, Argon2Threads: 1})
+
+	hash, err := weak.Hash("Password12345!")
+	assert.NoError(t, err)
+	assert.True(t, strong.NeedsRehash(hash))
+
+	cheap := newHasher(t, config.Auth{PasswordHasher: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	costly := newHasher(t, config.Auth{PasswordHasher: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+
+	hash, err = cheap.Hash("Password12345!")
+	assert.NoError(t, err)
+	assert.True(t, costly.NeedsRehash(hash))
+	assert.NoError(t, costly.Compare(hash, "Password12345!"))
+}
@@ -0,0 +1,125 @@
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// PurposeTwoFactor marks tokens which only allow to finish a login with a second factor.
+const PurposeTwoFactor = "2fa"
+
+// NewChallengeToken generates a short-lived token which proves that the
+// user has passed the password check.
+//
+// It carries the purpose claim, so ParseToken never accepts it as an access token.
+func NewChallengeToken(keys *KeySet, userID int, duration time.Duration) (string, error) {
+	const operation = "jwt.NewChallengeToken"
+
+	now := time.Now()
+
+	tokenString, err := keys.sign(jwt.MapClaims{
+		"uid":     userID,
+		"purpose": PurposeTwoFactor,
+		"iat":     now.Unix(),
+		"exp":     now.Add(duration).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return tokenString, nil
+}
+
+// ParseChallengeToken parses a token created by NewChallengeToken and returns the user ID.
+func ParseChallengeToken(tokenString string, keys *KeySet) (int, error) {
+	token, err := jwt.Parse(tokenString, keys.keyFunc)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return 0, ErrTokenExpired
+		}
+
+		return 0, fmt.Errorf("challenge token throws an error during parsing: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, ErrInvalidToken
+	}
+
+	if purpose, _ := claims["purpose"].(string); purpose != PurposeTwoFactor {
+		return 0, ErrInvalidToken
+	}
+
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		return 0, ErrNotFoundInTokenClaims
+	}
+
+	return int(uid), nil
+}
+
+// PurposeOIDC marks tokens which carry the state of an external login.
+const PurposeOIDC = "oidc"
+
+// OIDCState is what has to survive the redirect to the identity provider.
+type OIDCState struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewOIDCStateToken signs the state of an external login, so it can be
+// kept by the browser in a cookie instead of on the server.
+func NewOIDCStateToken(keys *KeySet, state OIDCState, duration time.Duration) (string, error) {
+	const operation = "jwt.NewOIDCStateToken"
+
+	now := time.Now()
+
+	tokenString, err := keys.sign(jwt.MapClaims{
+		"purpose":  PurposeOIDC,
+		"state":    state.State,
+		"nonce":    state.Nonce,
+		"verifier": state.Verifier,
+		"iat":      now.Unix(),
+		"exp":      now.Add(duration).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return tokenString, nil
+}
+
+// ParseOIDCStateToken parses a token created by NewOIDCStateToken.
+func ParseOIDCStateToken(tokenString string, keys *KeySet) (*OIDCState, error) {
+	token, err := jwt.Parse(tokenString, keys.keyFunc)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+
+		return nil, fmt.Errorf("oidc state token throws an error during parsing: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if purpose, _ := claims["purpose"].(string); purpose != PurposeOIDC {
+		return nil, ErrInvalidToken
+	}
+
+	state, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+
+	if state == "" || nonce == "" || verifier == "" {
+		return nil, ErrNotFoundInTokenClaims
+	}
+
+	return &OIDCState{State: state, Nonce: nonce, Verifier: verifier}, nil
+}
@@ -3,10 +3,11 @@ package jwt
 import (
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/lib/secret"
 	"github.com/markraiter/simple-blog/internal/model"
 )
 
@@ -19,31 +20,44 @@ var (
 )
 
 type TokenClaims struct {
-	UID      string
-	Username string
-	Email    string
-	Exp      int64
+	ID        string
+	SessionID string
+	UID       string
+	Username  string
+	Email     string
+	Role      model.Role
+	IssuedAt  time.Time
+	Exp       int64
 }
 
-// NewToken generates new JWT token and returns signedString.
+// NewToken generates new JWT token signed with the current key of the set and returns signedString.
 //
+// The token carries the ID of the session it was issued for in the sid claim.
 // In case of error occurs it throws an error.
-func NewToken(cfg config.Auth, user *model.User, duration time.Duration) (string, error) {
+func NewToken(keys *KeySet, user *model.User, sessionID string, duration time.Duration) (string, error) {
 	const operation = "jwt.NewToken"
 
-	token := jwt.New(jwt.SigningMethodHS256)
+	claims := jwt.MapClaims{}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", ErrInvalidClaims
+	jti, err := secret.Generate(16)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
 	}
 
+	now := time.Now()
+
+	claims["jti"] = jti
+	claims["sid"] = sessionID
 	claims["uid"] = user.ID
 	claims["username"] = user.Username
 	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
+	claims["role"] = user.Role
+	// iat carries microseconds, so a token issued right after a logout
+	// from all devices is told apart from the tokens it revoked.
+	claims["iat"] = float64(now.UnixMicro()) / 1e6
+	claims["exp"] = now.Add(duration).Unix()
 
-	tokenString, err := token.SignedString([]byte(cfg.SigningKey))
+	tokenString, err := keys.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", operation, err)
 	}
@@ -53,16 +67,11 @@ func NewToken(cfg config.Auth, user *model.User, duration time.Duration) (string
 
 // ParseToken parses the JWT token and returns the user ID.
 //
+// The verification key is picked from the set by the kid header.
 // If the token is invalid, returns an error.
 // If the token is valid, returns the user ID.
-func ParseToken(tokenString, signingKey string) (*TokenClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, ErrInvalidSigningMethod
-		}
-
-		return []byte(signingKey), nil
-	})
+func ParseToken(tokenString string, keys *KeySet) (*TokenClaims, error) {
+	token, err := jwt.Parse(tokenString, keys.keyFunc)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrTokenExpired
@@ -76,6 +85,12 @@ func ParseToken(tokenString, signingKey string) (*TokenClaims, error) {
 		return nil, ErrInvalidToken
 	}
 
+	// Tokens issued for a special purpose, like a two-factor challenge,
+	// must never be accepted as access tokens.
+	if _, ok := claims["purpose"]; ok {
+		return nil, ErrInvalidToken
+	}
+
 	var userID string
 	if uid, ok := claims["uid"]; ok {
 		switch v := uid.(type) {
@@ -122,11 +137,38 @@ func ParseToken(tokenString, signingKey string) (*TokenClaims, error) {
 		return nil, ErrNotFoundInTokenClaims
 	}
 
+	// jti, sid and iat are optional, tokens issued before they were
+	// introduced do not have them.
+	var jti string
+	if jtiClaim, ok := claims["jti"].(string); ok {
+		jti = jtiClaim
+	}
+
+	var sid string
+	if sidClaim, ok := claims["sid"].(string); ok {
+		sid = sidClaim
+	}
+
+	var iat time.Time
+	if iatClaim, ok := claims["iat"].(float64); ok {
+		iat = time.UnixMicro(int64(math.Round(iatClaim * 1e6)))
+	}
+
+	// Tokens without a role get no permissions until they are refreshed.
+	var role model.Role
+	if roleClaim, ok := claims["role"].(string); ok {
+		role = model.Role(roleClaim)
+	}
+
 	tc := TokenClaims{
-		UID:      userID,
-		Username: username,
-		Email:    email,
-		Exp:      exp,
+		ID:        jti,
+		SessionID: sid,
+		UID:       userID,
+		Username:  username,
+		Email:     email,
+		Role:      role,
+		IssuedAt:  iat,
+		Exp:       exp,
 	}
 
 	return &tc, nil
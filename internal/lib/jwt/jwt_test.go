@@ -21,11 +21,34 @@ func TestNewToken(t *testing.T) {
 	}
 	duration := time.Minute
 
-	token, err := NewToken(cfg, &user, duration)
+	keys, err := NewKeySet(cfg)
+	assert.NoError(t, err)
+
+	token, err := NewToken(keys, &user, "session", duration)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 }
 
+func TestNewToken_IssuedAt(t *testing.T) {
+	keys, err := NewKeySet(config.Auth{SigningKey: "testKey"})
+	assert.NoError(t, err)
+
+	before := time.Now().Truncate(time.Microsecond)
+
+	token, err := NewToken(keys, &model.User{ID: 1, Username: "user", Email: "email@example.com"}, "session", time.Minute)
+	assert.NoError(t, err)
+
+	after := time.Now()
+
+	claims, err := ParseToken(token, keys)
+	assert.NoError(t, err)
+
+	// iat keeps the microseconds.
+	assert.False(t, claims.IssuedAt.Before(before))
+	assert.False(t, claims.IssuedAt.After(after))
+	assert.Equal(t, claims.IssuedAt, claims.IssuedAt.Truncate(time.Microsecond))
+}
+
 func TestParseToken(t *testing.T) {
 	cfg := config.Auth{
 		SigningKey: "testKey",
@@ -35,10 +58,14 @@ func TestParseToken(t *testing.T) {
 		ID:       111,
 		Username: "testUser",
 		Email:    "test@test.com",
+		Role:     model.RoleAuthor,
 	}
 
 	duration := time.Minute
 
+	keys, err := NewKeySet(cfg)
+	assert.NoError(t, err)
+
 	tests := []struct {
 		name       string
 		user       *model.User
@@ -50,7 +77,7 @@ func TestParseToken(t *testing.T) {
 			name:       "valid token",
 			user:       &user,
 			duration:   duration,
-			wantClaims: &TokenClaims{UID: "111", Username: "testUser", Email: "test@test.com", Exp: time.Now().Add(duration).Unix()},
+			wantClaims: &TokenClaims{UID: "111", Username: "testUser", Email: "test@test.com", Role: model.RoleAuthor, Exp: time.Now().Add(duration).Unix()},
 			wantErr:    nil,
 		},
 		{
@@ -64,10 +91,10 @@ func TestParseToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, err := NewToken(cfg, tt.user, tt.duration)
+			token, err := NewToken(keys, tt.user, "session", tt.duration)
 			assert.NoError(t, err)
 
-			claims, err := ParseToken(token, cfg.SigningKey)
+			claims, err := ParseToken(token, keys)
 
 			if tt.wantErr != nil {
 				assert.Error(t, err)
@@ -80,7 +107,62 @@ func TestParseToken(t *testing.T) {
 				assert.Equal(t, tt.wantClaims.UID, claims.UID)
 				assert.Equal(t, tt.wantClaims.Username, claims.Username)
 				assert.Equal(t, tt.wantClaims.Email, claims.Email)
+				assert.Equal(t, tt.wantClaims.Role, claims.Role)
+				assert.Equal(t, "session", claims.SessionID)
+				assert.NotEmpty(t, claims.ID)
+				assert.NotZero(t, claims.IssuedAt)
 			}
 		})
 	}
 }
+
+func TestChallengeToken(t *testing.T) {
+	cfg := config.Auth{
+		SigningKey: "testKey",
+	}
+
+	keys, err := NewKeySet(cfg)
+	assert.NoError(t, err)
+
+	token, err := NewChallengeToken(keys, 111, time.Minute)
+	assert.NoError(t, err)
+
+	userID, err := ParseChallengeToken(token, keys)
+	assert.NoError(t, err)
+	assert.Equal(t, 111, userID)
+
+	_, err = ParseToken(token, keys)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	accessToken, err := NewToken(keys, &model.User{ID: 111, Username: "testUser", Email: "test@test.com"}, "session", time.Minute)
+	assert.NoError(t, err)
+
+	_, err = ParseChallengeToken(accessToken, keys)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestOIDCStateToken(t *testing.T) {
+	keys, err := NewKeySet(config.Auth{SigningKey: "testKey"})
+	assert.NoError(t, err)
+
+	want := OIDCState{State: "state", Nonce: "nonce", Verifier: "verifier"}
+
+	token, err := NewOIDCStateToken(keys, want, time.Minute)
+	assert.NoError(t, err)
+
+	got, err := ParseOIDCStateToken(token, keys)
+	assert.NoError(t, err)
+	assert.Equal(t, &want, got)
+
+	_, err = ParseToken(token, keys)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = ParseChallengeToken(token, keys)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	challenge, err := NewChallengeToken(keys, 111, time.Minute)
+	assert.NoError(t, err)
+
+	_, err = ParseOIDCStateToken(challenge, keys)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,250 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/markraiter/simple-blog/config"
+)
+
+// DefaultKeyID identifies the HMAC key from SIGNING_KEY.
+//
+// Tokens issued before key IDs were introduced have no kid header and are
+// verified with this key.
+const DefaultKeyID = "default"
+
+var (
+	ErrNoSigningKey       = errors.New("no signing key configured")
+	ErrUnknownKey         = errors.New("unknown key id")
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
+
+// Key is a single signing or verification key.
+//
+// Keys without a private part can only verify tokens, this is how a
+// retired key is kept until the tokens signed with it expire.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	sign   any
+	verify any
+}
+
+// CanSign reports whether the key has a private part.
+func (k *Key) CanSign() bool {
+	return k.sign != nil
+}
+
+// KeySet holds all keys tokens are verified with and the one new tokens are signed with.
+type KeySet struct {
+	current *Key
+	keys    map[string]*Key
+}
+
+// NewKeySet loads the keys from the config.
+//
+// SIGNING_KEY is registered as the HMAC key DefaultKeyID. SIGNING_KEYS is a
+// comma separated list of kid=path pairs pointing to PEM files with RSA or
+// Ed25519 keys. SIGNING_KEY_ID chooses the key new tokens are signed with,
+// by default the HMAC key is used.
+func NewKeySet(cfg config.Auth) (*KeySet, error) {
+	const operation = "jwt.NewKeySet"
+
+	ks := &KeySet{keys: make(map[string]*Key)}
+
+	if cfg.SigningKey != "" {
+		ks.keys[DefaultKeyID] = &Key{
+			ID:     DefaultKeyID,
+			Method: jwt.SigningMethodHS256,
+			sign:   []byte(cfg.SigningKey),
+			verify: []byte(cfg.SigningKey),
+		}
+	}
+
+	for _, entry := range cfg.SigningKeys {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || kid == "" || path == "" {
+			return nil, fmt.Errorf("%s: invalid key entry %q, expected kid=path", operation, entry)
+		}
+
+		if _, exists := ks.keys[kid]; exists {
+			return nil, fmt.Errorf("%s: duplicate key id %q", operation, kid)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		key, err := parsePEMKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", operation, kid, err)
+		}
+
+		ks.keys[kid] = key
+	}
+
+	currentID := cfg.SigningKeyID
+	if currentID == "" {
+		currentID = DefaultKeyID
+	}
+
+	current, ok := ks.keys[currentID]
+	if !ok || !current.CanSign() {
+		return nil, fmt.Errorf("%s: %w: %q", operation, ErrNoSigningKey, currentID)
+	}
+
+	ks.current = current
+
+	return ks, nil
+}
+
+// Current returns the key new tokens are signed with.
+func (ks *KeySet) Current() *Key {
+	return ks.current
+}
+
+// sign signs the claims with the current key and sets the kid header.
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.current.Method, claims)
+	token.Header["kid"] = ks.current.ID
+
+	return token.SignedString(ks.current.sign)
+}
+
+// keyFunc picks the verification key by the kid header.
+//
+// The algorithm of the token must match the key, otherwise a public key
+// could be abused as an HMAC secret.
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = DefaultKeyID
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrInvalidSigningMethod
+	}
+
+	return key.verify, nil
+}
+
+// JWK is a public key in the JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set. HMAC keys are secret and never published.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+
+	ids := make([]string, 0, len(ks.keys))
+	for kid := range ks.keys {
+		ids = append(ids, kid)
+	}
+
+	slices.Sort(ids)
+
+	for _, kid := range ids {
+		key := ks.keys[kid]
+
+		switch pub := key.verify.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return set
+}
+
+func parsePEMKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var (
+		parsed any
+		err    error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedKeyType, block.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newKey(kid, parsed)
+}
+
+func newKey(kid string, parsed any) (*Key, error) {
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, sign: k, verify: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, verify: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, sign: k, verify: k.Public().(ed25519.PublicKey)}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodEdDSA, verify: k}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKeyType, parsed)
+	}
+}
@@ -0,0 +1,184 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestNewKeySet(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	edDER, err := x509.MarshalPKCS8PrivateKey(edPriv)
+	assert.NoError(t, err)
+
+	edPubDER, err := x509.MarshalPKIXPublicKey(edPub)
+	assert.NoError(t, err)
+
+	rsaPath := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	edPath := writePEM(t, "PRIVATE KEY", edDER)
+	edPubPath := writePEM(t, "PUBLIC KEY", edPubDER)
+
+	tests := []struct {
+		name       string
+		cfg        config.Auth
+		wantMethod string
+		wantErr    error
+	}{
+		{name: "HMAC Only", cfg: config.Auth{SigningKey: "testKey"}, wantMethod: "HS256"},
+		{name: "RSA Current", cfg: config.Auth{SigningKey: "testKey", SigningKeys: []string{"rsa-1=" + rsaPath}, SigningKeyID: "rsa-1"}, wantMethod: "RS256"},
+		{name: "Ed25519 Current", cfg: config.Auth{SigningKeys: []string{"ed-1=" + edPath}, SigningKeyID: "ed-1"}, wantMethod: "EdDSA"},
+		{name: "No Keys", cfg: config.Auth{}, wantErr: ErrNoSigningKey},
+		{name: "Unknown Current", cfg: config.Auth{SigningKey: "testKey", SigningKeyID: "missing"}, wantErr: ErrNoSigningKey},
+		{name: "Public Key Can Not Sign", cfg: config.Auth{SigningKeys: []string{"ed-1=" + edPubPath}, SigningKeyID: "ed-1"}, wantErr: ErrNoSigningKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := NewKeySet(tt.cfg)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantMethod, keys.Current().Method.Alg())
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	user := &model.User{ID: 111, Username: "testUser", Email: "test@test.com", Role: model.RoleAuthor}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	rsaPath := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	before, err := NewKeySet(config.Auth{SigningKey: "testKey"})
+	assert.NoError(t, err)
+
+	oldToken, err := NewToken(before, user, "session", time.Minute)
+	assert.NoError(t, err)
+
+	after, err := NewKeySet(config.Auth{SigningKey: "testKey", SigningKeys: []string{"rsa-1=" + rsaPath}, SigningKeyID: "rsa-1"})
+	assert.NoError(t, err)
+
+	newToken, err := NewToken(after, user, "session", time.Minute)
+	assert.NoError(t, err)
+
+	// Tokens signed before the rotation stay valid.
+	claims, err := ParseToken(oldToken, after)
+	assert.NoError(t, err)
+	assert.Equal(t, "111", claims.UID)
+
+	claims, err = ParseToken(newToken, after)
+	assert.NoError(t, err)
+	assert.Equal(t, "111", claims.UID)
+
+	// The old set does not know the new key.
+	_, err = ParseToken(newToken, before)
+	assert.Error(t, err)
+}
+
+func TestKeySet_KeyFunc(t *testing.T) {
+	keys, err := NewKeySet(config.Auth{SigningKey: "testKey"})
+	assert.NoError(t, err)
+
+	claims := jwt.MapClaims{"uid": 111, "exp": time.Now().Add(time.Minute).Unix()}
+
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("testKey"))
+	assert.NoError(t, err)
+
+	unknown := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	unknown.Header["kid"] = "missing"
+	unknownString, err := unknown.SignedString([]byte("testKey"))
+	assert.NoError(t, err)
+
+	mismatch := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
+	mismatch.Header["kid"] = DefaultKeyID
+	mismatchString, err := mismatch.SignedString([]byte("testKey"))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "Legacy Token Without Kid", token: legacy},
+		{name: "Unknown Kid", token: unknownString, wantErr: ErrUnknownKey},
+		{name: "Algorithm Mismatch", token: mismatchString, wantErr: ErrInvalidSigningMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.Parse(tt.token, keys.keyFunc)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	edDER, err := x509.MarshalPKCS8PrivateKey(edPriv)
+	assert.NoError(t, err)
+
+	keys, err := NewKeySet(config.Auth{
+		SigningKey: "testKey",
+		SigningKeys: []string{
+			"rsa-1=" + writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)),
+			"ed-1=" + writePEM(t, "PRIVATE KEY", edDER),
+		},
+		SigningKeyID: "rsa-1",
+	})
+	assert.NoError(t, err)
+
+	set := keys.JWKS()
+
+	assert.Len(t, set.Keys, 2)
+	assert.Equal(t, "ed-1", set.Keys[0].KeyID)
+	assert.Equal(t, "OKP", set.Keys[0].KeyType)
+	assert.Equal(t, "EdDSA", set.Keys[0].Algorithm)
+	assert.NotEmpty(t, set.Keys[0].X)
+	assert.Equal(t, "rsa-1", set.Keys[1].KeyID)
+	assert.Equal(t, "RSA", set.Keys[1].KeyType)
+	assert.Equal(t, "RS256", set.Keys[1].Algorithm)
+	assert.Equal(t, "AQAB", set.Keys[1].E)
+}
@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/markraiter/simple-blog/config"
+)
+
+// Log does not deliver emails. It writes them to the log and, if the
+// directory is configured, to .eml files there.
+//
+// It is meant for local development and tests.
+type Log struct {
+	from string
+	dir  string
+	log  *slog.Logger
+}
+
+func NewLog(cfg config.Mailer, log *slog.Logger) *Log {
+	return &Log{
+		from: cfg.From,
+		dir:  cfg.Dir,
+		log:  log,
+	}
+}
+
+func (l *Log) Send(ctx context.Context, msg Message) error {
+	const operation = "mailer.Log.Send"
+
+	l.log.Info("email",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+
+	if l.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(l.dir, 0o755); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), sanitize(msg.To))
+
+	if err := os.WriteFile(filepath.Join(l.dir, name), compose(l.from, msg), 0o644); err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// sanitize makes the address safe to be used in a file name.
+func sanitize(address string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+
+		return r
+	}, address)
+}
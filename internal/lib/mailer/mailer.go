@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/markraiter/simple-blog/config"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails to users.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by cfg.Driver.
+func New(cfg config.Mailer, log *slog.Logger) (Mailer, error) {
+	const operation = "mailer.New"
+
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTP(cfg), nil
+	case DriverLog:
+		return NewLog(cfg, log), nil
+	}
+
+	return nil, fmt.Errorf("%s: unknown mailer driver %q", operation, cfg.Driver)
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/markraiter/simple-blog/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var log = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  string
+		want    Mailer
+		wantErr bool
+	}{
+		{name: "SMTP", driver: DriverSMTP, want: &SMTP{}},
+		{name: "Log", driver: DriverLog, want: &Log{}},
+		{name: "Unknown", driver: "pigeon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(config.Mailer{Driver: tt.driver}, log)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.IsType(t, tt.want, m)
+		})
+	}
+}
+
+func TestLog_Send(t *testing.T) {
+	dir := t.TempDir()
+
+	m := NewLog(config.Mailer{From: "blog@example.com", Dir: dir}, log)
+
+	err := m.Send(context.Background(), Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "first line\nsecond line",
+	})
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	content, err := os.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: user@example.com\r\n")
+	assert.Contains(t, string(content), "Subject: Hello\r\n")
+	assert.Contains(t, string(content), "first line\r\nsecond line")
+}
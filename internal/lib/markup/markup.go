@@ -0,0 +1,64 @@
+package markup
+
+import (
+	"bytes"
+	"html"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	// markdown renders GitHub flavoured markdown. Raw HTML is passed
+	// through so that policy decides what is kept of it.
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+
+	// policy is the allowlist of user generated HTML: formatting, links
+	// and images are kept, scripts, styles, event handler attributes and
+	// URLs of other schemes than http, https and mailto are removed.
+	policy = bluemonday.UGCPolicy().AllowURLSchemes("mailto", "http", "https")
+)
+
+// Markdown renders the markdown source to sanitised HTML.
+func Markdown(source string) (string, error) {
+	var b bytes.Buffer
+
+	if err := markdown.Convert([]byte(source), &b); err != nil {
+		return "", err
+	}
+
+	return Sanitize(b.String()), nil
+}
+
+// Plain renders plain text to HTML: the text is escaped, blank lines
+// separate paragraphs and other line breaks are kept.
+func Plain(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+
+	var b strings.Builder
+
+	for _, paragraph := range strings.Split(source, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+
+	return b.String()
+}
+
+// Sanitize returns the HTML with everything removed that is not in the
+// allowlist of user generated HTML.
+func Sanitize(value string) string {
+	return policy.Sanitize(value)
+}
@@ -0,0 +1,85 @@
+package markup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "Formatting",
+			source: "# Title\n\nSome **bold** and `code`.",
+			want:   "<h1>Title</h1>\n<p>Some <strong>bold</strong> and <code>code</code>.</p>\n",
+		},
+		{
+			name:   "Link",
+			source: "[site](https://example.com)",
+			want:   "<p><a href=\"https://example.com\" rel=\"nofollow\">site</a></p>\n",
+		},
+		{
+			name:   "Script",
+			source: "before\n\n<script>alert(1)</script>\n\nafter",
+			want:   "<p>before</p>\n\n<p>after</p>\n",
+		},
+		{
+			name:   "Event Handler",
+			source: "<img src=\"https://example.com/a.png\" onerror=\"alert(1)\">",
+			want:   "<img src=\"https://example.com/a.png\">",
+		},
+		{
+			name:   "JavaScript URL",
+			source: "[click](javascript:alert(1))",
+			want:   "<p>click</p>\n",
+		},
+		{
+			name:   "JavaScript URL in HTML",
+			source: "<a href=\"javascript:alert(1)\">click</a>",
+			want:   "<p>click</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Markdown(tt.source)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "Paragraphs",
+			source: "one\ntwo\r\n\r\nthree",
+			want:   "<p>one<br>\ntwo</p>\n<p>three</p>\n",
+		},
+		{
+			name:   "Escaped",
+			source: "<script>alert(1)</script> & *not markdown*",
+			want:   "<p>&lt;script&gt;alert(1)&lt;/script&gt; &amp; *not markdown*</p>\n",
+		},
+		{
+			name:   "Empty",
+			source: "\n\n\n",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Plain(tt.source))
+		})
+	}
+}
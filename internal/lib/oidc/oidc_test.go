@@ -0,0 +1,156 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/markraiter/simple-blog/config"
+	"github.com/markraiter/simple-blog/internal/lib/oidc"
+	"github.com/markraiter/simple-blog/internal/lib/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(t *testing.T) (*oidc.Client, *oidctest.Server) {
+	t.Helper()
+
+	server := oidctest.NewServer(t, "blog", "secret")
+
+	client, err := oidc.New(context.Background(), config.OIDC{
+		Issuer:       server.URL,
+		ClientID:     "blog",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/api/auth/oidc/callback",
+		Scopes:       []string{"openid", "email"},
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, server
+}
+
+func TestNew_IssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer(t, "blog", "secret")
+
+	_, err := oidc.New(context.Background(), config.OIDC{Issuer: server.URL + "/other"}, server.Client())
+	assert.ErrorIs(t, err, oidc.ErrDiscovery)
+}
+
+func TestClient_AuthCodeURL(t *testing.T) {
+	client, server := newTestClient(t)
+
+	authURL, err := url.Parse(client.AuthCodeURL("state", "nonce", "verifier"))
+	assert.NoError(t, err)
+
+	q := authURL.Query()
+
+	assert.Equal(t, server.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, "code", q.Get("response_type"))
+	assert.Equal(t, "blog", q.Get("client_id"))
+	assert.Equal(t, "openid email", q.Get("scope"))
+	assert.Equal(t, "state", q.Get("state"))
+	assert.Equal(t, "nonce", q.Get("nonce"))
+	assert.Equal(t, oidc.Challenge("verifier"), q.Get("code_challenge"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+}
+
+func TestClient_Exchange(t *testing.T) {
+	identity := oidc.Claims{Subject: "42", Email: "user@example.com", EmailVerified: true, PreferredUsername: "user"}
+
+	tests := []struct {
+		name          string
+		exchangeNonce string
+		wrongVerifier bool
+		wantErr       error
+	}{
+		{name: "Success", exchangeNonce: "nonce"},
+		{name: "Wrong Verifier", exchangeNonce: "nonce", wrongVerifier: true, wantErr: oidc.ErrExchange},
+		{name: "Wrong Nonce", exchangeNonce: "other", wantErr: oidc.ErrInvalidIDToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClient(t)
+
+			verifier, err := oidc.NewVerifier()
+			assert.NoError(t, err)
+
+			code, state, err := server.Authorize(client.AuthCodeURL("state", "nonce", verifier), identity)
+			assert.NoError(t, err)
+			assert.Equal(t, "state", state)
+
+			if tt.wrongVerifier {
+				verifier += "x"
+			}
+
+			claims, err := client.Exchange(context.Background(), code, verifier, tt.exchangeNonce)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, server.URL, claims.Issuer)
+			assert.Equal(t, identity.Subject, claims.Subject)
+			assert.Equal(t, identity.Email, claims.Email)
+			assert.True(t, claims.EmailVerified)
+			assert.Equal(t, identity.PreferredUsername, claims.PreferredUsername)
+
+			// The code can be redeemed only once.
+			_, err = client.Exchange(context.Background(), code, verifier, tt.exchangeNonce)
+			assert.ErrorIs(t, err, oidc.ErrExchange)
+		})
+	}
+}
+
+func TestClient_Verify(t *testing.T) {
+	client, server := newTestClient(t)
+
+	now := time.Now()
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   server.URL,
+			"sub":   "42",
+			"aud":   "blog",
+			"exp":   now.Add(time.Minute).Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(jwt.MapClaims)
+		wantErr bool
+	}{
+		{name: "Valid", modify: func(jwt.MapClaims) {}},
+		{name: "Wrong Issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "Wrong Audience", modify: func(c jwt.MapClaims) { c["aud"] = "other" }, wantErr: true},
+		{name: "Expired", modify: func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Hour).Unix() }, wantErr: true},
+		{name: "No Expiry", modify: func(c jwt.MapClaims) { delete(c, "exp") }, wantErr: true},
+		{name: "No Subject", modify: func(c jwt.MapClaims) { delete(c, "sub") }, wantErr: true},
+		{name: "Other Authorized Party", modify: func(c jwt.MapClaims) { c["aud"] = []string{"blog", "other"}; c["azp"] = "other" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(claims)
+
+			idToken, err := server.SignIDToken(claims)
+			assert.NoError(t, err)
+
+			_, err = client.Verify(context.Background(), idToken, "nonce")
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package secret
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Generate returns a URL-safe random string built from size random bytes.
+//
+// It is used for opaque tokens (refresh tokens, reset links etc.) which are
+// handed to the client once and are only stored as a hash.
+func Generate(size int) (string, error) {
+	const operation = "secret.Generate"
+
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns hex encoded SHA-256 of the provided value.
+//
+// Opaque tokens have enough entropy, so a fast hash is sufficient to keep
+// them useless in case the database leaks.
+func Hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	first, err := Generate(32)
+	assert.NoError(t, err)
+	assert.Len(t, first, 43)
+
+	second, err := Generate(32)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, Hash("token"), Hash("token"))
+	assert.NotEqual(t, Hash("token"), Hash("other"))
+	assert.Len(t, Hash("token"), 64)
+}
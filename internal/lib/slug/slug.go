@@ -0,0 +1,33 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Make returns the slug of the value: lower case letters and digits with
+// every other run of characters replaced by a single dash, so "Go & Web
+// Dev" and "go-web-dev" have the same slug.
+//
+// The slug is empty if the value has no letters or digits.
+func Make(value string) string {
+	var b strings.Builder
+
+	dash := false
+	for _, r := range strings.ToLower(value) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+
+			b.WriteRune(r)
+			dash = false
+
+			continue
+		}
+
+		dash = true
+	}
+
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package slug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "golang", want: "golang"},
+		{value: "Go & Web  Dev", want: "go-web-dev"},
+		{value: "  --Go 1.22--  ", want: "go-1-22"},
+		{value: "Ünïcode Тег", want: "ünïcode-тег"},
+		{value: "#!?", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, Make(tt.value))
+		})
+	}
+}
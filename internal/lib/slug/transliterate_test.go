@@ -0,0 +1,29 @@
+package slug
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeASCII(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "Go & Web  Dev", want: "go-web-dev"},
+		{value: "Ünïcode Тег", want: "unicode-teg"},
+		{value: "Straße über Łódź", want: "strasse-uber-lodz"},
+		{value: "Щука и ёж", want: "shchuka-i-ezh"},
+		{value: "Ελληνικά", want: "ellinika"},
+		{value: "Объявление: Ъ", want: "obyavlenie"},
+		{value: "日本語 post", want: "日本語-post"},
+		{value: "#!?", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, MakeASCII(tt.value))
+		})
+	}
+}
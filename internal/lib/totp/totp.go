@@ -0,0 +1,138 @@
+// Package totp implements time-based one-time passwords (RFC 6238) as used
+// by common authenticator apps: HMAC-SHA1, 6 digits, 30 second period.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Period is the number of seconds a code is valid for.
+	Period = 30
+	// Digits is the length of a code.
+	Digits = 6
+
+	secretSize = 20
+	// skew is the number of periods before and after the current one
+	// in which a code is still accepted, to tolerate clock drift.
+	skew = 1
+)
+
+var ErrInvalidSecret = errors.New("invalid secret")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random base32 encoded secret.
+func GenerateSecret() (string, error) {
+	const operation = "totp.GenerateSecret"
+
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return encoding.EncodeToString(b), nil
+}
+
+// URI returns the otpauth:// URI which authenticator apps accept, usually as a QR code.
+func URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(Period))
+
+	label := url.PathEscape(issuer + ":" + account)
+
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// Code returns the code for the provided secret at time t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decode(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return code(key, uint64(t.Unix()/Period)), nil
+}
+
+// Validate reports whether code is valid for the secret at time t.
+func Validate(secret, code string, t time.Time) bool {
+	_, ok := Step(secret, code, t)
+
+	return ok
+}
+
+// Step returns the time step the code was generated for, if the code is
+// valid for the secret at time t.
+//
+// A code stays valid for a few periods, callers which must not accept
+// the same code twice remember the last accepted step and reject codes
+// of that step or earlier ones.
+func Step(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	key, err := decode(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	counter := t.Unix() / Period
+
+	for i := -skew; i <= skew; i++ {
+		expected := codeAt(key, counter+int64(i))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter + int64(i), true
+		}
+	}
+
+	return 0, false
+}
+
+func codeAt(key []byte, counter int64) string {
+	if counter < 0 {
+		return ""
+	}
+
+	return code(key, uint64(counter))
+}
+
+func code(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1000000)
+}
+
+func decode(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.TrimSpace(secret), "="))
+
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+
+	return key, nil
+}
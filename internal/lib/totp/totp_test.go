@@ -0,0 +1,93 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the SHA1 key from the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "59", unix: 59, want: "287082"},
+		{name: "1111111109", unix: 1111111109, want: "081804"},
+		{name: "1111111111", unix: 1111111111, want: "050471"},
+		{name: "1234567890", unix: 1234567890, want: "005924"},
+		{name: "2000000000", unix: 2000000000, want: "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	tests := []struct {
+		name string
+		code string
+		at   time.Time
+		want bool
+	}{
+		{name: "Current Period", code: "081804", at: now, want: true},
+		{name: "Previous Period", code: "081804", at: now.Add(Period * time.Second), want: true},
+		{name: "Next Period", code: "081804", at: now.Add(-Period * time.Second), want: true},
+		{name: "Too Old", code: "081804", at: now.Add(2 * Period * time.Second), want: false},
+		{name: "Wrong Code", code: "123456", at: now, want: false},
+		{name: "Wrong Length", code: "81804", at: now, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Validate(rfcSecret, tt.code, tt.at))
+		})
+	}
+}
+
+func TestStep(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	step, ok := Step(rfcSecret, "081804", now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/Period, step)
+
+	step, ok = Step(rfcSecret, "081804", now.Add(Period*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/Period, step)
+
+	_, ok = Step(rfcSecret, "123456", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	code, err := Code(secret, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, Validate(secret, code, time.Now()))
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("simple-blog", "email@example.com", "SECRET")
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/simple-blog:email@example.com?"))
+	assert.Contains(t, uri, "secret=SECRET")
+	assert.Contains(t, uri, "issuer=simple-blog")
+}
@@ -0,0 +1,64 @@
+// Package useragent turns a User-Agent header into a short description
+// of the device, good enough to tell the sessions of a user apart.
+package useragent
+
+import "strings"
+
+// Unknown is returned when nothing is recognized in the header.
+const Unknown = "Unknown device"
+
+type rule struct {
+	token string
+	name  string
+}
+
+// Order matters, many browsers mention the engines of the others,
+// e.g. Edge sends "Chrome" and "Safari" as well.
+var browsers = []rule{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"Go-http-client/", "Go HTTP client"},
+	{"PostmanRuntime/", "Postman"},
+}
+
+var systems = []rule{
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// Describe returns a description like "Firefox on Linux".
+func Describe(userAgent string) string {
+	browser := match(browsers, userAgent)
+	system := match(systems, userAgent)
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+
+	return Unknown
+}
+
+func match(rules []rule, userAgent string) string {
+	for _, r := range rules {
+		if strings.Contains(userAgent, r.token) {
+			return r.name
+		}
+	}
+
+	return ""
+}
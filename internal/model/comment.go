@@ -1,13 +1,41 @@
 package model
 
+// DeletedCommentContent replaces the content of a deleted comment which
+// is kept as a placeholder because it has replies.
+const DeletedCommentContent = "[deleted]"
+
+// Comment is a comment on a post. Version is increased by every update
+// and is the ETag of the comment.
+//
+// Content is the source in Format, ContentHTML is the sanitised HTML it
+// renders to.
 type Comment struct {
-	ID      int    `json:"id"`
-	Content string `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
-	PostID  int    `json:"post_id"`
-	UserID  int    `json:"user_id"`
+	ID          int           `json:"id"`
+	Content     string        `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
+	Format      ContentFormat `json:"format" example:"markdown"`
+	ContentHTML string        `json:"content_html" example:"<p>lorem ipsum dolor sit amet ...</p>"`
+	PostID      int           `json:"post_id"`
+	UserID      int           `json:"user_id"`
+	ParentID    *int          `json:"parent_id,omitempty" example:"1"`
+	Depth       int           `json:"depth"`
+	Deleted     bool          `json:"deleted,omitempty"`
+	Version     int           `json:"version" example:"1"`
+	Replies     []*Comment    `json:"replies,omitempty"`
 }
 
+// CommentRequest creates a comment. ParentID makes the comment a reply.
+//
+// A comment without a format is plain text.
 type CommentRequest struct {
-	Content string `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
-	PostID  int    `json:"post_id" validate:"required" example:"1"`
+	Content  string        `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
+	Format   ContentFormat `json:"format,omitempty" validate:"omitempty,oneof=plain markdown" example:"markdown"`
+	PostID   int           `json:"post_id" validate:"required" example:"1"`
+	ParentID *int          `json:"parent_id,omitempty" example:"1"`
+}
+
+// CommentUpdateRequest updates the content of a comment, the post and the
+// parent of a comment can not be changed. An omitted format is kept.
+type CommentUpdateRequest struct {
+	Content string        `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
+	Format  ContentFormat `json:"format,omitempty" validate:"omitempty,oneof=plain markdown" example:"markdown"`
 }
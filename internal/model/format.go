@@ -0,0 +1,10 @@
+package model
+
+// ContentFormat is the format the content of a post or comment is written
+// in. Either is rendered to HTML for display.
+type ContentFormat string
+
+const (
+	ContentFormatPlain    ContentFormat = "plain"
+	ContentFormatMarkdown ContentFormat = "markdown"
+)
@@ -0,0 +1,13 @@
+package model
+
+// UserIdentity links an account at an external OpenID Connect provider to a user.
+//
+// The account is identified by the issuer and subject, the email is only
+// kept for information, because the provider may let users change it.
+type UserIdentity struct {
+	ID      int
+	UserID  int
+	Issuer  string
+	Subject string
+	Email   string
+}
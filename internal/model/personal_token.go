@@ -0,0 +1,51 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// PersonalTokenPrefix starts every personal access token, so they can be told apart from JWTs.
+const PersonalTokenPrefix = "sbp_"
+
+// Scope limits what a personal access token can be used for.
+type Scope string
+
+const (
+	ScopePostsWrite    Scope = "posts:write"
+	ScopeCommentsWrite Scope = "comments:write"
+)
+
+// PersonalAccessToken is a long-lived token for scripts and CI.
+//
+// Only the hash of the token is stored, Prefix is kept to help the user
+// recognise the token in the list.
+type PersonalAccessToken struct {
+	ID         int        `json:"id" example:"1"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name" example:"release script"`
+	Prefix     string     `json:"prefix" example:"sbp_q2V0aGlz"`
+	TokenHash  string     `json:"-"`
+	Scopes     []Scope    `json:"scopes" example:"posts:write"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the token was granted the scope.
+func (t *PersonalAccessToken) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+type PersonalTokenRequest struct {
+	Name      string     `json:"name" validate:"required,max=100" example:"release script"`
+	Scopes    []Scope    `json:"scopes" validate:"required,min=1,dive,oneof=posts:write comments:write" example:"posts:write"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2030-01-01T00:00:00Z"`
+}
+
+// PersonalTokenCreated is returned once after creating the token, it is
+// the only time the token itself is shown.
+type PersonalTokenCreated struct {
+	PersonalAccessToken
+	Token string `json:"token" example:"sbp_q2V0aGlzLWlzLWEtcGVyc29uYWwtdG9rZW4"`
+}
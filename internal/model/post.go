@@ -1,14 +1,103 @@
 package model
 
+import "time"
+
+// PostSort is the order in which posts are listed, always descending.
+type PostSort string
+
+const (
+	PostSortCreated  PostSort = "created"
+	PostSortUpdated  PostSort = "updated"
+	PostSortComments PostSort = "comments"
+)
+
+// PostStatus is the stage of a post's lifecycle. Only published posts are
+// visible to others than the author.
+type PostStatus string
+
+const (
+	PostStatusDraft     PostStatus = "draft"
+	PostStatusScheduled PostStatus = "scheduled"
+	PostStatusPublished PostStatus = "published"
+	PostStatusArchived  PostStatus = "archived"
+)
+
+// Post is a blog post. Version is increased by every update and is the
+// ETag of the post.
+//
+// Slug is the unique name of the post in URLs, made of its title. When the
+// title changes so does the slug, the former one redirects to it.
+//
+// Content is the source in Format, ContentHTML is the sanitised HTML it
+// renders to.
 type Post struct {
-	ID            int    `json:"id"`
-	Title         string `json:"title" validate:"required,min=3,max=50" example:"title"`
-	Content       string `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
-	UserID        int    `json:"user_id"`
-	CommentsCount int    `json:"comments_count" example:"0"`
+	ID            int           `json:"id"`
+	Title         string        `json:"title" validate:"required,min=3,max=50" example:"title"`
+	Slug          string        `json:"slug" example:"title"`
+	Content       string        `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
+	Format        ContentFormat `json:"format" example:"markdown"`
+	ContentHTML   string        `json:"content_html" example:"<p>lorem ipsum dolor sit amet ...</p>"`
+	UserID        int           `json:"user_id"`
+	CommentsCount int           `json:"comments_count" example:"0"`
+	Tags          []string      `json:"tags" example:"golang,web"`
+	Status        PostStatus    `json:"status" example:"published"`
+	PublishAt     *time.Time    `json:"publish_at,omitempty"`
+	Version       int           `json:"version" example:"1"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
 }
 
+// PostRequest creates or updates a post.
+//
+// Tags are stored as slugs, a post has at most 10 of them. On update
+// omitted tags are kept and an empty list removes them.
+//
+// A new post without a status is published, or scheduled if PublishAt is
+// set. PublishAt is the future time a scheduled post is published at.
+// On update an omitted status is kept.
+//
+// A new post without a format is plain text, on update an omitted format
+// is kept.
 type PostRequest struct {
-	Title   string `json:"title" validate:"required,min=3,max=50" example:"title"`
-	Content string `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
+	Title     string        `json:"title" validate:"required,min=3,max=50" example:"title"`
+	Content   string        `json:"content" validate:"required,min=3" example:"lorem ipsum dolor sit amet ..."`
+	Format    ContentFormat `json:"format,omitempty" validate:"omitempty,oneof=plain markdown" example:"markdown"`
+	Tags      []string      `json:"tags,omitempty" validate:"max=10,dive,required,max=30" example:"golang,web"`
+	Status    PostStatus    `json:"status,omitempty" validate:"omitempty,oneof=draft scheduled published archived" example:"draft"`
+	PublishAt *time.Time    `json:"publish_at,omitempty"`
+}
+
+// Tag is a tag with the number of posts it is used in.
+type Tag struct {
+	Slug       string `json:"slug" example:"golang"`
+	PostsCount int    `json:"posts_count" example:"3"`
+}
+
+// PostQuery selects a page of posts.
+//
+// From and To limit the creation time of the posts to [From, To).
+// After is the decoded cursor of the previous page. Only published posts
+// are selected, plus the posts of ViewerID if it is set.
+type PostQuery struct {
+	Limit    int      `validate:"min=0,max=100"`
+	Sort     PostSort `validate:"omitempty,oneof=created updated comments"`
+	AuthorID int      `validate:"min=0"`
+	Tag      string
+	From     *time.Time
+	To       *time.Time
+	After    *PostCursor
+	ViewerID int
+}
+
+// PostCursor is the position of the last post of a page in the listing order.
+type PostCursor struct {
+	Sort          PostSort  `json:"s"`
+	ID            int       `json:"i"`
+	Time          time.Time `json:"t,omitempty"`
+	CommentsCount int       `json:"c,omitempty"`
+}
+
+type PostPage struct {
+	Posts      []*Post `json:"posts"`
+	NextCursor string  `json:"next_cursor,omitempty" example:"eyJzIjoiY3JlYXRlZCIsImkiOjF9"`
 }
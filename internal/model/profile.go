@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// Profile is the public part of a user account.
+type Profile struct {
+	ID          int       `json:"id" example:"1"`
+	Username    string    `json:"username" example:"username"`
+	DisplayName string    `json:"display_name" example:"Jane Doe"`
+	Bio         string    `json:"bio" example:"Writes about Go and databases"`
+	Website     string    `json:"website" example:"https://example.com"`
+	AvatarURL   string    `json:"avatar_url" example:"https://example.com/avatar.png"`
+	PostsCount  int       `json:"posts_count" example:"0"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Account is the profile of the current user together with its private details.
+type Account struct {
+	Profile
+	Email           string     `json:"email" example:"email@example.com"`
+	Role            Role       `json:"role" example:"author"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+}
+
+// ProfileRequest changes only the fields which are present,
+// an empty string clears an optional field.
+type ProfileRequest struct {
+	Username    *string `json:"username,omitempty" validate:"omitempty,min=3,max=50" example:"username"`
+	DisplayName *string `json:"display_name,omitempty" validate:"omitempty,max=100" example:"Jane Doe"`
+	Bio         *string `json:"bio,omitempty" validate:"omitempty,max=500" example:"Writes about Go and databases"`
+	Website     *string `json:"website,omitempty" validate:"omitempty,max=255,weblink" example:"https://example.com"`
+	AvatarURL   *string `json:"avatar_url,omitempty" validate:"omitempty,max=255,weblink" example:"https://example.com/avatar.png"`
+}
+
+// DeletionPolicy tells what happens to the posts and comments of a deleted account.
+type DeletionPolicy string
+
+const (
+	DeletionPolicyAnonymize DeletionPolicy = "anonymize"
+	DeletionPolicyDelete    DeletionPolicy = "delete"
+)
+
+// Valid reports whether the policy is one of the known policies.
+func (p DeletionPolicy) Valid() bool {
+	return p == DeletionPolicyAnonymize || p == DeletionPolicyDelete
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required" example:"Password12345!"`
+}
+
+// AccountExport is the personal data of a user handed out on request.
+type AccountExport struct {
+	Account  *Account
+	Posts    []*Post
+	Comments []*Comment
+}
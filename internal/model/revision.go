@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// PostRevision is the title, content and format of a post after an edit. The
+// first revision is the post as it was created.
+//
+// EditorID is 0 once the editor deleted their account.
+type PostRevision struct {
+	Number    int           `json:"number" example:"2"`
+	PostID    int           `json:"post_id" example:"1"`
+	EditorID  int           `json:"editor_id" example:"1"`
+	Title     string        `json:"title" example:"title"`
+	Content   string        `json:"content" example:"lorem ipsum dolor sit amet ..."`
+	Format    ContentFormat `json:"format" example:"markdown"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// RevisionDiff is the unified diff turning revision From into revision To.
+// Diff is empty if they are the same.
+type RevisionDiff struct {
+	From int    `json:"from" example:"1"`
+	To   int    `json:"to" example:"2"`
+	Diff string `json:"diff" example:"--- revision 1\n+++ revision 2\n@@ -1 +1 @@\n-title\n+new title\n"`
+}
@@ -0,0 +1,49 @@
+package model
+
+// Role defines what the user is allowed to do.
+//
+// Roles are ordered, every role has all permissions of the roles below it:
+// reader < author < moderator < admin.
+type Role string
+
+const (
+	RoleReader    Role = "reader"
+	RoleAuthor    Role = "author"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleReader:    1,
+	RoleAuthor:    2,
+	RoleModerator: 3,
+	RoleAdmin:     4,
+}
+
+// Valid reports whether the role is one of the known roles.
+func (r Role) Valid() bool {
+	_, ok := roleLevels[r]
+
+	return ok
+}
+
+// AtLeast reports whether the role has all permissions of the min role.
+//
+// Unknown roles have no permissions at all.
+func (r Role) AtLeast(min Role) bool {
+	level, ok := roleLevels[r]
+	if !ok {
+		return false
+	}
+
+	return level >= roleLevels[min]
+}
+
+// CanModerate reports whether the role may edit and delete content of other users.
+func (r Role) CanModerate() bool {
+	return r.AtLeast(RoleModerator)
+}
+
+type RoleRequest struct {
+	Role Role `json:"role" validate:"required,oneof=reader author moderator admin" example:"moderator"`
+}
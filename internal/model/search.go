@@ -0,0 +1,43 @@
+package model
+
+// SearchType limits the kind of results of a search.
+type SearchType string
+
+const (
+	SearchAll      SearchType = "all"
+	SearchPosts    SearchType = "posts"
+	SearchComments SearchType = "comments"
+)
+
+const (
+	SearchResultPost    = "post"
+	SearchResultComment = "comment"
+)
+
+// SearchQuery selects a page of search results.
+//
+// Offset is the number of results of the previous pages.
+type SearchQuery struct {
+	Text   string     `validate:"required,max=200"`
+	Type   SearchType `validate:"omitempty,oneof=all posts comments"`
+	Limit  int        `validate:"min=0,max=100"`
+	Offset int
+}
+
+// SearchResult is a post or a comment matching a search.
+//
+// Snippet is an HTML-escaped excerpt of the content with the matched
+// words wrapped in <mark> tags.
+type SearchResult struct {
+	Type    string  `json:"type" example:"post"`
+	ID      int     `json:"id"`
+	PostID  int     `json:"post_id"`
+	Title   string  `json:"title,omitempty"`
+	Snippet string  `json:"snippet" example:"lorem <mark>ipsum</mark> dolor"`
+	Rank    float64 `json:"rank"`
+}
+
+type SearchPage struct {
+	Results    []*SearchResult `json:"results"`
+	NextCursor string          `json:"next_cursor,omitempty" example:"eyJvIjoyMH0"`
+}
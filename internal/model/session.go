@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// ClientInfo describes where a request came from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Session is a single login of a user.
+//
+// Its ID is the family ID of the refresh tokens of the login and the sid
+// claim of the access tokens issued for it.
+type Session struct {
+	ID         string    `json:"id" example:"q2V0aGlzLWlzLWEtc2Vzc2lvbg"`
+	UserID     int       `json:"-"`
+	Device     string    `json:"device" example:"Firefox on Linux"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"`
+	IP         string    `json:"ip" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// TokenPair is returned to the client after successful login or refresh.
+type TokenPair struct {
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"q2V0aGlzLWlzLWEtcmVmcmVzaC10b2tlbg"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required" example:"q2V0aGlzLWlzLWEtcmVmcmVzaC10b2tlbg"`
+}
+
+// RefreshToken is a refresh token stored in the database.
+//
+// Only the hash of the token is stored. All tokens produced by rotating
+// the same login share the FamilyID.
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" example:"q2V0aGlzLWlzLWEtcmVmcmVzaC10b2tlbg"`
+}
+
+// TokenPurpose tells what a UserToken can be used for.
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposeEmailChange       TokenPurpose = "email_change"
+)
+
+// UserToken is a single-use token sent to the user by email.
+//
+// Only the hash of the token is stored. Tokens confirming an email change
+// carry the new address.
+type UserToken struct {
+	ID        int
+	UserID    int
+	Purpose   TokenPurpose
+	TokenHash string
+	NewEmail  string
+	ExpiresAt time.Time
+}
+
+// LoginResponse is returned after checking the user credentials.
+//
+// If the account has two-factor authentication enabled, no tokens are issued.
+// Instead ChallengeToken is returned and has to be exchanged together with
+// a code at /api/auth/login/2fa.
+type LoginResponse struct {
+	*TokenPair
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// TwoFactor is the TOTP state of an account.
+//
+// Secret is set on enrollment, but the second factor is required only
+// after the enrollment is confirmed with a valid code.
+type TwoFactor struct {
+	Secret    string
+	EnabledAt *time.Time
+	// LastStep is the time step of the last accepted TOTP code, codes of
+	// this step and earlier ones are not accepted again.
+	LastStep int64
+}
+
+// Enabled reports whether the second factor is required on login.
+func (tf *TwoFactor) Enabled() bool {
+	return tf.EnabledAt != nil
+}
+
+// TwoFactorEnrollment is returned when a new TOTP secret is generated.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" example:"otpauth://totp/simple-blog:email@example.com?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=simple-blog"`
+}
+
+// TwoFactorCodeRequest carries either a TOTP code or a recovery code.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required" example:"123456"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	Code           string `json:"code" validate:"required" example:"123456"`
+}
+
+// RecoveryCodes are shown to the user once. Every code can be used
+// instead of a TOTP code a single time.
+type RecoveryCodes struct {
+	Codes []string `json:"codes" example:"k3j5x-9pq2m,7hw4r-2nd8c"`
+}
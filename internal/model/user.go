@@ -1,7 +1,9 @@
 package model
 
 import (
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator"
 )
@@ -11,6 +13,9 @@ type User struct {
 	Username string `json:"username" validate:"min=3,max=50" example:"username"`
 	Password string `json:"password" validate:"min=8,max=50,number,upper,lower,special" example:"Password12345!"`
 	Email    string `json:"email" validate:"email" example:"email@example.com"`
+	Role     Role   `json:"role" example:"author"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
 }
 
 type UserRequest struct {
@@ -24,6 +29,29 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required,min=8,max=50,number,upper,lower,special" example:"Password12345!"`
 }
 
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email" example:"email@example.com"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required" example:"q2V0aGlzLWlzLWEtcmVzZXQtdG9rZW4"`
+	Password string `json:"password" validate:"required,min=8,max=50,number,upper,lower,special" example:"Password12345!"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required" example:"Password12345!"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=50,number,upper,lower,special" example:"NewPassword12345!"`
+}
+
+type ChangeEmailRequest struct {
+	Password string `json:"password" validate:"required" example:"Password12345!"`
+	NewEmail string `json:"new_email" validate:"required,email" example:"new@example.com"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required" example:"q2V0aGlzLWlzLWEtY29uZmlybWF0aW9uLXRva2Vu"`
+}
+
 // ValidateContainsNumber checks if password contains at least one number
 //
 // Example:
@@ -96,3 +124,23 @@ func ValidateContainsSpecial(fl validator.FieldLevel) bool {
 
 	return false
 }
+
+// ValidateWebLink checks if the field is empty or an absolute http(s) link
+//
+// Example:
+//
+//	ValidateWebLink("https://example.com") // true
+//	ValidateWebLink("javascript:alert(1)") // false
+func ValidateWebLink(fl validator.FieldLevel) bool {
+	link := fl.Field().String()
+	if link == "" {
+		return true
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}